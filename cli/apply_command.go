@@ -23,16 +23,28 @@ const (
 )
 
 type applyOptions struct {
-	yes    bool
-	dryRun bool
-	to     string
-	force  bool
+	yes      bool
+	dryRun   bool
+	to       string
+	force    bool
+	includes []string
+	excludes []string
+	patch    bool
+	threeWay bool
+	resume   bool
+	abort    bool
+	commits  bool
+	output   string
 }
 
 type handoffOptions struct {
-	yes    bool
-	dryRun bool
-	to     string
+	yes       bool
+	dryRun    bool
+	to        string
+	selection transferSelection
+	threeWay  bool
+	commits   bool
+	output    string
 }
 
 type transferPlan struct {
@@ -41,13 +53,24 @@ type transferPlan struct {
 	sourceName      string
 	destinationRoot string
 	destinationName string
+	selection       transferSelection
+	threeWay        bool
+	commits         bool
+	backup          bool
 }
 
 type transferPreflight struct {
 	destinationDirty bool
 	overlappingFiles int
+	trackedFiles     int
 	trackedPatch     bool
 	untrackedFiles   []string
+	commits          int
+	commitFiles      int
+	commitHashes     []string
+	emptyDirs        []string
+	gitlinks         []gitlinkChange
+	skipped          []manifestEntry
 }
 
 type applyConflictError struct {
@@ -65,20 +88,52 @@ func (e *applyConflictError) Error() string {
 func (e *applyConflictError) Unwrap() error { return e.err }
 
 func newApplyCommand() *cobra.Command {
-	opts := &applyOptions{}
+	opts := &applyOptions{output: handoffOutputText}
 	cmd := &cobra.Command{
-		Use:   "apply <task>",
+		Use:   "apply <task> [-- <path>...]",
 		Short: "Apply non-destructive changes between a Codex worktree and local checkout",
-		Args:  cobra.ExactArgs(1),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if opts.resume || opts.abort {
+				return cobra.NoArgs(cmd, args)
+			}
+			return taskWithPathsArgs(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.resume || opts.abort {
+				if opts.resume && opts.abort {
+					return fmt.Errorf("choose only one of --continue or --abort")
+				}
+				if flagChangedAny(cmd, "3way", "force", "dry-run", "include", "exclude", "patch", "to", "commits", "output") {
+					return fmt.Errorf("--continue and --abort cannot be combined with other apply flags")
+				}
+				return runThreeWayResume(cmd, opts.abort)
+			}
+			if opts.threeWay && opts.force {
+				return fmt.Errorf("--3way cannot be combined with --force")
+			}
+			if opts.threeWay && opts.commits {
+				return fmt.Errorf("--3way cannot be combined with --commits")
+			}
 			mode := handoffApply
 			if opts.force {
 				mode = handoffOverwrite
 			}
+			var paths []string
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				paths = args[dash:]
+			}
+			selection, err := newTransferSelection(opts.includes, opts.excludes, paths, opts.patch)
+			if err != nil {
+				return err
+			}
 			return runCodexHandoff(cmd, strings.TrimSpace(args[0]), handoffOptions{
-				yes:    opts.yes,
-				dryRun: opts.dryRun,
-				to:     opts.to,
+				yes:       opts.yes,
+				dryRun:    opts.dryRun,
+				to:        opts.to,
+				selection: selection,
+				threeWay:  opts.threeWay,
+				commits:   opts.commits,
+				output:    opts.output,
 			}, mode)
 		},
 	}
@@ -87,11 +142,32 @@ func newApplyCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
 	cmd.Flags().StringVar(&opts.to, "to", transferToLocal, "transfer destination: local or worktree")
 	cmd.Flags().BoolVar(&opts.force, "force", false, "compatibility alias for overwrite behavior")
+	cmd.Flags().StringArrayVar(&opts.includes, "include", nil, "only transfer paths matching this glob (repeatable)")
+	cmd.Flags().StringArrayVar(&opts.excludes, "exclude", nil, "skip paths matching this glob (repeatable)")
+	cmd.Flags().BoolVar(&opts.patch, "patch", false, "interactively choose files and hunks to transfer")
+	cmd.Flags().BoolVar(&opts.threeWay, "3way", false, "merge with git apply --3way and leave conflict markers instead of blocking")
+	cmd.Flags().BoolVar(&opts.commits, "commits", false, "also replay source commits missing from the destination, keeping authorship")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "result output format: text or json")
+	cmd.Flags().BoolVar(&opts.resume, "continue", false, "finish an apply --3way after resolving conflicts")
+	cmd.Flags().BoolVar(&opts.abort, "abort", false, "restore the destination from before an apply --3way")
 	return cmd
 }
 
+// taskWithPathsArgs accepts exactly one <task>, optionally followed by
+// repo-relative paths after "--".
+func taskWithPathsArgs(cmd *cobra.Command, args []string) error {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		return cobra.ExactArgs(1)(cmd, args)
+	}
+	if dash != 1 {
+		return fmt.Errorf("accepts exactly one <task> before --, received %d", dash)
+	}
+	return nil
+}
+
 func newOverwriteCommand() *cobra.Command {
-	opts := &handoffOptions{}
+	opts := &handoffOptions{output: handoffOutputText}
 	cmd := &cobra.Command{
 		Use:   "overwrite <task>",
 		Short: "Overwrite destination with source changes in codex mode",
@@ -104,6 +180,7 @@ func newOverwriteCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&opts.yes, "yes", false, "skip confirmation prompts")
 	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
 	cmd.Flags().StringVar(&opts.to, "to", transferToLocal, "transfer destination: local or worktree")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "result output format: text or json")
 	return cmd
 }
 
@@ -121,16 +198,50 @@ func runCodexHandoff(cmd *cobra.Command, opaqueID string, opts handoffOptions, m
 	if !cmd.Flags().Changed("yes") {
 		opts.yes = !cfg.Cleanup.Confirm
 	}
+	if err := validateHandoffOutput(opts.output); err != nil {
+		return err
+	}
+	jsonOutput := opts.output == handoffOutputJSON
+	if jsonOutput && (opts.dryRun || opts.threeWay) {
+		return fmt.Errorf("--output json cannot be combined with --dry-run or --3way")
+	}
 
-	runner := defaultRunner()
+	runner := defaultRunner(ctx)
 	plan, err := resolveCodexHandoffPlan(ctx, runner, modeCtx, opaqueID, opts.to)
 	if err != nil {
 		return err
 	}
+	plan.selection = opts.selection
+	plan.threeWay = opts.threeWay
+	plan.commits = opts.commits
+	plan.backup = mode == handoffOverwrite && cfg.Backup.Enabled
+
+	set, err := collectTransferSet(ctx, runner, plan.sourceRoot, plan.selection)
+	if err != nil {
+		return err
+	}
+	if plan.selection.interactive {
+		set, err = selectTransferSetInteractively(cmd.InOrStdin(), cmd.OutOrStdout(), set, plan.destinationName)
+		if err != nil {
+			return err
+		}
+	}
+
+	if plan.commits {
+		set.commits, err = collectTransferCommits(ctx, runner, plan.sourceRoot, plan.destinationRoot)
+		if err != nil {
+			return err
+		}
+	}
+
+	files := append(append(append([]string{}, set.tracked...), set.untracked...), set.commits.files...)
+	if err := warnOutsideSparse(ctx, cmd, runner, plan.destinationRoot, plan.destinationName, files); err != nil {
+		return err
+	}
 
 	preflight := transferPreflight{}
 	if opts.dryRun || mode == handoffApply {
-		preflight, err = collectTransferPreflight(ctx, runner, plan.sourceRoot, plan.destinationRoot, opts.dryRun)
+		preflight, err = collectTransferPreflight(ctx, runner, set, plan.destinationRoot)
 		if err != nil {
 			return err
 		}
@@ -142,6 +253,24 @@ func runCodexHandoff(cmd *cobra.Command, opaqueID string, opts handoffOptions, m
 		}
 	}
 
+	if plan.threeWay {
+		if opts.dryRun {
+			return nil
+		}
+		conflicts, err := transferChangesThreeWay(ctx, cmd, runner, opaqueID, plan, set)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			if err := printThreeWayConflicts(cmd.OutOrStdout(), plan.destinationName, conflicts); err != nil {
+				return err
+			}
+			return errApplyConflicted
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), ui.SuccessStyle.Render(fmt.Sprintf("%s complete", mode)))
+		return err
+	}
+
 	if mode == handoffApply {
 		reasons := conflictReasonsForApply(preflight, plan.destinationName)
 		if len(reasons) > 0 {
@@ -155,13 +284,30 @@ func runCodexHandoff(cmd *cobra.Command, opaqueID string, opts handoffOptions, m
 		}
 	}
 
+	var manifest *transferManifest
+	if !opts.dryRun {
+		manifest = newTransferManifest(mode, plan)
+	}
+
 	if mode == handoffOverwrite && !opts.dryRun {
 		if err := confirmOverwrite(cmd, opts.yes, plan); err != nil {
 			return err
 		}
+		if plan.backup {
+			b, err := createSafetyBackup(ctx, cmd, runner, plan.destinationRoot, string(handoffOverwrite), opaqueID)
+			if err != nil {
+				return err
+			}
+			manifest.Backup = b.ID
+			if !jsonOutput {
+				if err := printBackupSaved(cmd, b); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
-	err = transferChanges(ctx, cmd, runner, plan.sourceRoot, plan.destinationRoot, opts.dryRun, mode == handoffOverwrite)
+	err = transferChanges(ctx, cmd, runner, plan.sourceRoot, plan.destinationRoot, set, opts.dryRun, mode == handoffOverwrite, manifest)
 	if err != nil {
 		if mode == handoffApply {
 			var conflictErr *applyConflictError
@@ -178,6 +324,19 @@ func runCodexHandoff(cmd *cobra.Command, opaqueID string, opts handoffOptions, m
 		return err
 	}
 
+	if jsonOutput {
+		return writeTransferManifest(cmd.OutOrStdout(), handoffOutputJSON, manifest)
+	}
+	if manifest != nil {
+		if err := writeTransferManifest(cmd.OutOrStdout(), handoffOutputText, manifest); err != nil {
+			return err
+		}
+	}
+	if n := len(set.commits.commits); n > 0 && !opts.dryRun {
+		if _, err := fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render(fmt.Sprintf("replayed %d commit(s) onto the %s", n, plan.destinationName))); err != nil {
+			return err
+		}
+	}
 	if _, err := fmt.Fprintln(cmd.OutOrStdout(), ui.SuccessStyle.Render(fmt.Sprintf("%s complete", mode))); err != nil {
 		return err
 	}
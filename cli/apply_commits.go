@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// transferCommit is one source commit that apply --commits replays.
+type transferCommit struct {
+	hash    string
+	author  string
+	subject string
+}
+
+// commitRange lists the source commits missing from the destination, oldest
+// first, together with the files they touch.
+type commitRange struct {
+	base    string
+	commits []transferCommit
+	files   []string
+}
+
+// collectTransferCommits lists the commits reachable from the source HEAD but
+// not the destination HEAD. Commits whose patch is already present in the
+// destination are skipped, as with `git cherry-pick` of an upstream range.
+func collectTransferCommits(ctx context.Context, runner git.Runner, sourceRoot, destinationRoot string) (commitRange, error) {
+	base, err := git.RevParse(ctx, runner, destinationRoot, "HEAD")
+	if err != nil {
+		return commitRange{}, err
+	}
+	tip, err := git.RevParse(ctx, runner, sourceRoot, "HEAD")
+	if err != nil {
+		return commitRange{}, err
+	}
+	rng := commitRange{base: base}
+	if base == tip {
+		return rng, nil
+	}
+
+	symmetric := base + "..." + tip
+	stdout, stderr, err := runner.Run(ctx, "-C", sourceRoot, "log", "--reverse", "--right-only", "--cherry-pick", "--format=%H%x1f%P%x1f%an <%ae>%x1f%s", symmetric)
+	if err != nil {
+		return commitRange{}, fmt.Errorf("git log: %w: %s", err, stderr)
+	}
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			return commitRange{}, fmt.Errorf("unexpected git log line: %q", line)
+		}
+		if len(strings.Fields(fields[1])) > 1 {
+			return commitRange{}, fmt.Errorf("commit %s is a merge; --commits only replays linear history", fields[0])
+		}
+		rng.commits = append(rng.commits, transferCommit{hash: fields[0], author: fields[2], subject: fields[3]})
+	}
+	if len(rng.commits) == 0 {
+		return rng, nil
+	}
+
+	names, stderr, err := runner.Run(ctx, "-C", sourceRoot, "diff", "--name-only", "--no-renames", symmetric)
+	if err != nil {
+		return commitRange{}, fmt.Errorf("git diff --name-only: %w: %s", err, stderr)
+	}
+	for _, line := range strings.Split(names, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			rng.files = append(rng.files, trimmed)
+		}
+	}
+	return rng, nil
+}
+
+func (r commitRange) hashes() []string {
+	out := make([]string, 0, len(r.commits))
+	for _, commit := range r.commits {
+		out = append(out, commit.hash)
+	}
+	return out
+}
+
+// replayCommits cherry-picks the range onto the destination, keeping the
+// original authorship. A failed pick is aborted so the destination is left
+// as it was.
+func replayCommits(ctx context.Context, cmd *cobra.Command, runner git.Runner, destinationRoot string, rng commitRange, dryRun bool) error {
+	if len(rng.commits) == 0 {
+		return nil
+	}
+	args := append([]string{"-C", destinationRoot, "cherry-pick"}, rng.hashes()...)
+	if err := runGit(ctx, cmd, dryRun, runner, args...); err != nil {
+		if _, stderr, abortErr := runner.Run(ctx, "-C", destinationRoot, "cherry-pick", "--abort"); abortErr != nil {
+			return fmt.Errorf("%w (cherry-pick --abort failed: %v: %s)", err, abortErr, stderr)
+		}
+		return &applyConflictError{reason: "replaying commits failed", err: err}
+	}
+	return nil
+}
@@ -9,7 +9,11 @@ import (
 )
 
 func detectApplyConflicts(ctx context.Context, runner git.Runner, destinationRoot, destinationName, sourceRoot string) ([]string, error) {
-	preflight, err := collectTransferPreflight(ctx, runner, sourceRoot, destinationRoot, false)
+	set, err := collectTransferSet(ctx, runner, sourceRoot, transferSelection{})
+	if err != nil {
+		return nil, err
+	}
+	preflight, err := collectTransferPreflight(ctx, runner, set, destinationRoot)
 	if err != nil {
 		return nil, err
 	}
@@ -79,41 +83,32 @@ func intersectCount(left, right map[string]struct{}) int {
 	return count
 }
 
-func collectTransferPreflight(ctx context.Context, runner git.Runner, sourceRoot, destinationRoot string, includeTransferState bool) (transferPreflight, error) {
+// collectTransferPreflight inspects the destination against the selected
+// transfer set, so overlap only counts files that will actually move.
+func collectTransferPreflight(ctx context.Context, runner git.Runner, set transferSet, destinationRoot string) (transferPreflight, error) {
 	destinationDirty, err := isDirty(ctx, runner, destinationRoot)
 	if err != nil {
 		return transferPreflight{}, err
 	}
 
-	sourceModified, err := modifiedFiles(ctx, runner, sourceRoot)
-	if err != nil {
-		return transferPreflight{}, err
-	}
 	destinationModified, err := modifiedFiles(ctx, runner, destinationRoot)
 	if err != nil {
 		return transferPreflight{}, err
 	}
 
-	preflight := transferPreflight{
+	return transferPreflight{
 		destinationDirty: destinationDirty,
-		overlappingFiles: intersectCount(sourceModified, destinationModified),
-	}
-
-	if includeTransferState {
-		patch, err := gitDiff(ctx, runner, sourceRoot)
-		if err != nil {
-			return transferPreflight{}, err
-		}
-		preflight.trackedPatch = patch != ""
-
-		untracked, err := listUntracked(ctx, runner, sourceRoot)
-		if err != nil {
-			return transferPreflight{}, err
-		}
-		preflight.untrackedFiles = untracked
-	}
-
-	return preflight, nil
+		overlappingFiles: intersectCount(set.files(), destinationModified),
+		trackedFiles:     len(set.tracked),
+		trackedPatch:     set.patch != "",
+		untrackedFiles:   set.untracked,
+		commits:          len(set.commits.commits),
+		commitFiles:      len(set.commits.files),
+		commitHashes:     set.commits.hashes(),
+		emptyDirs:        set.emptyDirs,
+		gitlinks:         set.gitlinks,
+		skipped:          set.skipped,
+	}, nil
 }
 
 func conflictReasonsForApply(preflight transferPreflight, destinationName string) []string {
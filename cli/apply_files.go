@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -26,48 +28,80 @@ func writeTempPatch(contents string) (string, error) {
 	return tmp.Name(), nil
 }
 
-func copyFile(srcRoot, dstRoot, rel string, dryRun bool, out io.Writer, maskPaths bool) (err error) {
+// errUnsupportedFileType marks paths that are neither regular files nor
+// symlinks; transfers skip them instead of failing.
+var errUnsupportedFileType = errors.New("unsupported file type")
+
+func copyFile(srcRoot, dstRoot, rel string, dryRun bool, out io.Writer, maskPaths bool) error {
+	_, err := copyFileVerified(srcRoot, dstRoot, rel, dryRun, out, maskPaths)
+	return err
+}
+
+// copyFileVerified streams rel from srcRoot to dstRoot, mirrors its
+// permission bits and returns the SHA-256 of the copied content after
+// re-reading the destination to verify it.
+func copyFileVerified(srcRoot, dstRoot, rel string, dryRun bool, out io.Writer, maskPaths bool) (string, error) {
 	srcPath := filepath.Join(srcRoot, rel)
 	dstPath := filepath.Join(dstRoot, rel)
 
 	info, err := os.Lstat(srcPath)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if info.Mode()&os.ModeSymlink != 0 {
 		target, err := os.Readlink(srcPath)
 		if err != nil {
-			return err
+			return "", err
 		}
 		if dryRun {
 			_, err := fmt.Fprintf(out, "symlink %s -> %s (%s)\n", maskPathForDryRun(srcPath, maskPaths), maskPathForDryRun(dstPath, maskPaths), maskPathForDryRun(target, maskPaths))
-			return err
+			return "", err
 		}
 		if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
-			return err
+			return "", err
 		}
 		if err := os.Remove(dstPath); err != nil && !errors.Is(err, os.ErrNotExist) {
-			return err
+			return "", err
 		}
-		return os.Symlink(target, dstPath)
+		return "", os.Symlink(target, dstPath)
 	}
 
 	if !info.Mode().IsRegular() {
-		return fmt.Errorf("unsupported file type for copy: %s", srcPath)
+		return "", fmt.Errorf("%w for copy: %s", errUnsupportedFileType, srcPath)
 	}
 
 	if dryRun {
 		_, err := fmt.Fprintf(out, "copy %s -> %s\n", maskPathForDryRun(srcPath, maskPaths), maskPathForDryRun(dstPath, maskPaths))
-		return err
+		return "", err
 	}
 
 	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
-		return err
+		return "", err
+	}
+	sum, err := streamFile(srcPath, dstPath, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+	// OpenFile only applies the mode on create; keep executable bits in sync
+	// when the destination already existed.
+	if err := os.Chmod(dstPath, info.Mode().Perm()); err != nil {
+		return "", err
+	}
+	written, err := fileChecksum(dstPath)
+	if err != nil {
+		return "", err
+	}
+	if written != sum {
+		return "", fmt.Errorf("checksum mismatch after copying %s", rel)
 	}
+	return sum, nil
+}
+
+func streamFile(srcPath, dstPath string, mode os.FileMode) (sum string, err error) {
 	in, err := os.Open(srcPath)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer func() {
 		if closeErr := in.Close(); closeErr != nil && err == nil {
@@ -75,9 +109,9 @@ func copyFile(srcRoot, dstRoot, rel string, dryRun bool, out io.Writer, maskPath
 		}
 	}()
 
-	outFile, err := os.OpenFile(dstPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	outFile, err := os.OpenFile(dstPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer func() {
 		if closeErr := outFile.Close(); closeErr != nil && err == nil {
@@ -85,10 +119,28 @@ func copyFile(srcRoot, dstRoot, rel string, dryRun bool, out io.Writer, maskPath
 		}
 	}()
 
-	if _, err := io.Copy(outFile, in); err != nil {
-		return err
+	hash := sha256.New()
+	if _, err := io.Copy(outFile, io.TeeReader(in, hash)); err != nil {
+		return "", err
 	}
-	return nil
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileChecksum(path string) (sum string, err error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if closeErr := in.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, in); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 func removeTempPatch(path string) error {
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+const gitlinkMode = "160000"
+
+// gitlinkChange is a submodule pointer change carried by the source diff.
+// from or to is empty when the submodule was added or removed.
+type gitlinkChange struct {
+	path string
+	from string
+	to   string
+}
+
+func isGitlinkSection(file patchFile) bool {
+	for _, line := range file.header {
+		if strings.HasPrefix(line, "index ") && strings.HasSuffix(line, " "+gitlinkMode) {
+			return true
+		}
+		if line == "new file mode "+gitlinkMode || line == "deleted file mode "+gitlinkMode {
+			return true
+		}
+	}
+	return false
+}
+
+// gitlinkChanges extracts submodule pointer changes from a git diff. git apply
+// leaves gitlinks alone outside --index, so they are applied separately.
+func gitlinkChanges(patch string) []gitlinkChange {
+	var out []gitlinkChange
+	for _, file := range parsePatch(patch) {
+		if !isGitlinkSection(file) {
+			continue
+		}
+		change := gitlinkChange{path: file.path}
+		for _, hunk := range file.hunks {
+			for _, line := range hunk {
+				switch {
+				case strings.HasPrefix(line, "-Subproject commit "):
+					change.from = subprojectCommit(strings.TrimPrefix(line, "-Subproject commit "))
+				case strings.HasPrefix(line, "+Subproject commit "):
+					change.to = subprojectCommit(strings.TrimPrefix(line, "+Subproject commit "))
+				}
+			}
+		}
+		out = append(out, change)
+	}
+	return out
+}
+
+// subprojectCommit returns the commit of a "Subproject commit" diff line,
+// without the -dirty marker git adds for a modified submodule.
+func subprojectCommit(rest string) string {
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(fields[0], "-dirty")
+}
+
+// applyGitlinkChanges checks out the source's submodule commit in each
+// initialized destination submodule, fetching it from the source checkout
+// when the destination does not have it yet.
+func applyGitlinkChanges(ctx context.Context, cmd *cobra.Command, runner git.Runner, sourceRoot, destinationRoot string, changes []gitlinkChange, dryRun bool, manifest *transferManifest) error {
+	for _, change := range changes {
+		switch {
+		case change.to == "":
+			manifest.skipped(manifestEntry{Path: change.path, Kind: "gitlink", Reason: "submodule removed in source; commit the removal and use --commits"})
+			continue
+		case change.from == "":
+			manifest.skipped(manifestEntry{Path: change.path, Kind: "gitlink", Reason: "submodule added in source; commit it and use --commits"})
+			continue
+		}
+		destinationSub := filepath.Join(destinationRoot, change.path)
+		if dryRun {
+			if err := runGit(ctx, cmd, dryRun, runner, "-C", destinationSub, "checkout", "-q", "--detach", change.to); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(destinationSub, ".git")); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			manifest.skipped(manifestEntry{Path: change.path, Kind: "gitlink", Reason: "submodule not initialized in destination"})
+			continue
+		}
+		if _, _, err := runner.Run(ctx, "-C", destinationSub, "cat-file", "-e", change.to+"^{commit}"); err != nil {
+			if _, stderr, err := runner.Run(ctx, "-C", destinationSub, "fetch", "-q", filepath.Join(sourceRoot, change.path), change.to); err != nil {
+				manifest.skipped(manifestEntry{Path: change.path, Kind: "gitlink", Reason: fmt.Sprintf("commit %s not available: %s", worktree.ShortHash(change.to, 0), strings.TrimSpace(stderr))})
+				continue
+			}
+		}
+		if _, stderr, err := runner.Run(ctx, "-C", destinationSub, "checkout", "-q", "--detach", change.to); err != nil {
+			manifest.skipped(manifestEntry{Path: change.path, Kind: "gitlink", Reason: fmt.Sprintf("checkout %s failed: %s", worktree.ShortHash(change.to, 0), strings.TrimSpace(stderr))})
+			continue
+		}
+		manifest.copied(manifestEntry{Path: change.path, Kind: "gitlink", Reason: fmt.Sprintf("%s -> %s", worktree.ShortHash(change.from, 0), worktree.ShortHash(change.to, 0))})
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/ui"
+)
+
+const (
+	handoffOutputText = "text"
+	handoffOutputJSON = "json"
+)
+
+// manifestEntry records what happened to one path during a transfer.
+type manifestEntry struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Reason string `json:"reason,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// transferManifest is the final report of a handoff: every path that was
+// copied, removed or skipped, with the reason when it is not obvious.
+type transferManifest struct {
+	Mode        string          `json:"mode"`
+	To          string          `json:"to"`
+	Source      string          `json:"source"`
+	Destination string          `json:"destination"`
+	Backup      string          `json:"backup,omitempty"`
+	Commits     []string        `json:"commits"`
+	Copied      []manifestEntry `json:"copied"`
+	Removed     []manifestEntry `json:"removed"`
+	Skipped     []manifestEntry `json:"skipped"`
+}
+
+func newTransferManifest(mode handoffMode, plan transferPlan) *transferManifest {
+	return &transferManifest{
+		Mode:        string(mode),
+		To:          plan.to,
+		Source:      plan.sourceRoot,
+		Destination: plan.destinationRoot,
+		Commits:     []string{},
+		Copied:      []manifestEntry{},
+		Removed:     []manifestEntry{},
+		Skipped:     []manifestEntry{},
+	}
+}
+
+func (m *transferManifest) copied(entry manifestEntry) {
+	if m != nil {
+		m.Copied = append(m.Copied, entry)
+	}
+}
+
+func (m *transferManifest) removed(entry manifestEntry) {
+	if m != nil {
+		m.Removed = append(m.Removed, entry)
+	}
+}
+
+func (m *transferManifest) skipped(entry manifestEntry) {
+	if m != nil {
+		m.Skipped = append(m.Skipped, entry)
+	}
+}
+
+func (m *transferManifest) recordCommits(hashes []string) {
+	if m != nil {
+		m.Commits = append(m.Commits, hashes...)
+	}
+}
+
+// recordPatch adds the tracked paths carried by patch to the manifest.
+// Gitlink sections are left to applyGitlinkChanges, which knows the outcome.
+func (m *transferManifest) recordPatch(patch string, partial bool) {
+	if m == nil {
+		return
+	}
+	for _, file := range parsePatch(patch) {
+		if isGitlinkSection(file) {
+			continue
+		}
+		if patchSectionHas(file, "deleted file mode") {
+			m.removed(manifestEntry{Path: file.path, Kind: "file", Reason: "deleted in source"})
+			continue
+		}
+		entry := manifestEntry{Path: file.path, Kind: "patch"}
+		switch {
+		case partial:
+			entry.Reason = "selected hunks"
+		case len(file.hunks) == 0 && patchSectionHas(file, "new mode"):
+			entry.Reason = "mode change"
+		}
+		m.copied(entry)
+	}
+}
+
+func writeTransferManifest(out io.Writer, format string, m *transferManifest) error {
+	if format == handoffOutputJSON {
+		payload, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(payload))
+		return err
+	}
+	sections := []struct {
+		label   string
+		entries []manifestEntry
+	}{
+		{label: "copied", entries: m.Copied},
+		{label: "removed", entries: m.Removed},
+		{label: "skipped", entries: m.Skipped},
+	}
+	for _, section := range sections {
+		for _, entry := range section.entries {
+			detail := entry.Kind
+			if entry.Reason != "" {
+				detail += ": " + entry.Reason
+			}
+			line := fmt.Sprintf("%-8s %s %s", section.label, entry.Path, ui.MutedStyle.Render("("+detail+")"))
+			if section.label == "skipped" {
+				line = ui.WarningStyle.Render(fmt.Sprintf("%-8s %s", section.label, entry.Path)) + " " + ui.MutedStyle.Render("("+detail+")")
+			}
+			if _, err := fmt.Fprintln(out, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateHandoffOutput(format string) error {
+	switch format {
+	case handoffOutputText, handoffOutputJSON:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+func patchSectionHas(file patchFile, prefix string) bool {
+	for _, line := range file.header {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/ui"
+)
+
+type patchFile struct {
+	path   string
+	header []string
+	hunks  [][]string
+}
+
+type hunkChoice int
+
+const (
+	hunkTake hunkChoice = iota
+	hunkSkip
+	hunkTakeRest
+	hunkSkipRest
+	hunkQuit
+)
+
+// parsePatch splits a unified git diff into per-file sections and hunks.
+// Binary and mode-only sections carry no hunks and are selected as a whole.
+func parsePatch(patch string) []patchFile {
+	var files []patchFile
+	var current *patchFile
+	var hunk []string
+	flushHunk := func() {
+		if current != nil && hunk != nil {
+			current.hunks = append(current.hunks, hunk)
+		}
+		hunk = nil
+	}
+	for _, line := range strings.Split(strings.TrimRight(patch, "\n"), "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flushHunk()
+			if current != nil {
+				files = append(files, *current)
+			}
+			current = &patchFile{path: patchFilePath(line), header: []string{line}}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			flushHunk()
+			hunk = []string{line}
+			continue
+		}
+		if hunk != nil {
+			hunk = append(hunk, line)
+			continue
+		}
+		current.header = append(current.header, line)
+	}
+	flushHunk()
+	if current != nil {
+		files = append(files, *current)
+	}
+	return files
+}
+
+func patchFilePath(header string) string {
+	rest := strings.TrimPrefix(header, "diff --git ")
+	if idx := strings.LastIndex(rest, " b/"); idx >= 0 {
+		return rest[idx+len(" b/"):]
+	}
+	return strings.TrimPrefix(rest, "a/")
+}
+
+// whole reports whether the section can only be transferred as a unit:
+// binary and mode-only sections have no hunks, and git apply rejects a
+// deleted or new file with some of its hunks missing.
+func (f patchFile) whole() bool {
+	if len(f.hunks) == 0 {
+		return true
+	}
+	for _, line := range f.header {
+		if strings.HasPrefix(line, "deleted file mode ") || strings.HasPrefix(line, "new file mode ") {
+			return true
+		}
+	}
+	return false
+}
+
+// deletion reports whether the section deletes its file.
+func (f patchFile) deletion() bool {
+	for _, line := range f.header {
+		if strings.HasPrefix(line, "deleted file mode ") {
+			return true
+		}
+	}
+	return false
+}
+
+func (f patchFile) render(hunks [][]string) string {
+	var b strings.Builder
+	for _, line := range f.header {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	for _, hunk := range hunks {
+		for _, line := range hunk {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// selectTransferSetInteractively walks every file and hunk of set and keeps
+// only what the user accepts, in the spirit of `git add --patch`.
+func selectTransferSetInteractively(in io.Reader, out io.Writer, set transferSet, destinationName string) (transferSet, error) {
+	reader := bufio.NewReader(in)
+	selected := transferSet{filtered: true, partial: true}
+	var patch strings.Builder
+	quit := false
+
+	for _, file := range parsePatch(set.patch) {
+		if quit {
+			break
+		}
+		if file.whole() {
+			if _, err := fmt.Fprint(out, file.render(file.hunks)); err != nil {
+				return transferSet{}, err
+			}
+			message := fmt.Sprintf("Transfer %s to the %s?", file.path, destinationName)
+			if file.deletion() {
+				message = fmt.Sprintf("Delete %s in the %s?", file.path, destinationName)
+			}
+			choice, err := promptHunkChoice(reader, out, message)
+			if err != nil {
+				return transferSet{}, err
+			}
+			switch choice {
+			case hunkTake, hunkTakeRest:
+				patch.WriteString(file.render(file.hunks))
+				selected.tracked = append(selected.tracked, file.path)
+			case hunkQuit:
+				quit = true
+			}
+			continue
+		}
+
+		var kept [][]string
+		takeRest, skipRest := false, false
+		for idx, hunk := range file.hunks {
+			if quit || skipRest {
+				break
+			}
+			if takeRest {
+				kept = append(kept, hunk)
+				continue
+			}
+			if _, err := fmt.Fprint(out, file.render([][]string{hunk})); err != nil {
+				return transferSet{}, err
+			}
+			choice, err := promptHunkChoice(reader, out, fmt.Sprintf("Transfer hunk %d/%d of %s to the %s?", idx+1, len(file.hunks), file.path, destinationName))
+			if err != nil {
+				return transferSet{}, err
+			}
+			switch choice {
+			case hunkTake:
+				kept = append(kept, hunk)
+			case hunkTakeRest:
+				kept = append(kept, hunk)
+				takeRest = true
+			case hunkSkipRest:
+				skipRest = true
+			case hunkQuit:
+				quit = true
+			}
+		}
+		if len(kept) > 0 {
+			patch.WriteString(file.render(kept))
+			selected.tracked = append(selected.tracked, file.path)
+		}
+	}
+
+	for _, rel := range set.untracked {
+		if quit {
+			break
+		}
+		choice, err := promptHunkChoice(reader, out, fmt.Sprintf("Copy untracked file %s to the %s?", rel, destinationName))
+		if err != nil {
+			return transferSet{}, err
+		}
+		switch choice {
+		case hunkTake, hunkTakeRest:
+			selected.untracked = append(selected.untracked, rel)
+		case hunkQuit:
+			quit = true
+		}
+	}
+
+	for _, rel := range set.emptyDirs {
+		if quit {
+			break
+		}
+		choice, err := promptHunkChoice(reader, out, fmt.Sprintf("Create empty directory %s in the %s?", rel, destinationName))
+		if err != nil {
+			return transferSet{}, err
+		}
+		switch choice {
+		case hunkTake, hunkTakeRest:
+			selected.emptyDirs = append(selected.emptyDirs, rel)
+		case hunkQuit:
+			quit = true
+		}
+	}
+
+	selected.patch = patch.String()
+	selected.gitlinks = gitlinkChanges(selected.patch)
+	selected.skipped = set.skipped
+	return selected, nil
+}
+
+func promptHunkChoice(reader *bufio.Reader, out io.Writer, message string) (hunkChoice, error) {
+	for {
+		if _, err := fmt.Fprintf(out, "%s %s ", ui.PromptStyle.Render(message), ui.MutedStyle.Render("[y,n,a,d,q]")); err != nil {
+			return hunkQuit, err
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return hunkQuit, err
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		switch answer {
+		case "y", "yes":
+			return hunkTake, nil
+		case "n", "no":
+			return hunkSkip, nil
+		case "a":
+			return hunkTakeRest, nil
+		case "d":
+			return hunkSkipRest, nil
+		case "q":
+			return hunkQuit, nil
+		}
+		if err == io.EOF {
+			return hunkQuit, nil
+		}
+		if _, err := fmt.Fprintln(out, ui.MutedStyle.Render("y: transfer, n: skip, a: transfer this and the rest of the file, d: skip the rest of the file, q: stop selecting")); err != nil {
+			return hunkQuit, err
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+)
+
+// transferSelection narrows a handoff to a subset of the source changes.
+// The zero value selects everything.
+type transferSelection struct {
+	includes    []string
+	excludes    []string
+	paths       []string
+	interactive bool
+}
+
+// transferSet is the resolved list of changes a handoff will move. It is
+// collected once so preflight, dry-run output and the transfer agree.
+type transferSet struct {
+	patch     string
+	tracked   []string
+	untracked []string
+	filtered  bool
+	partial   bool
+	commits   commitRange
+	emptyDirs []string
+	gitlinks  []gitlinkChange
+	skipped   []manifestEntry
+}
+
+func newTransferSelection(includes, excludes, paths []string, interactive bool) (transferSelection, error) {
+	selection := transferSelection{interactive: interactive}
+	for _, pattern := range includes {
+		normalized, err := normalizeSelectionGlob(pattern)
+		if err != nil {
+			return transferSelection{}, err
+		}
+		selection.includes = append(selection.includes, normalized)
+	}
+	for _, pattern := range excludes {
+		normalized, err := normalizeSelectionGlob(pattern)
+		if err != nil {
+			return transferSelection{}, err
+		}
+		selection.excludes = append(selection.excludes, normalized)
+	}
+	for _, raw := range paths {
+		normalized, err := normalizeSelectionPath(raw)
+		if err != nil {
+			return transferSelection{}, err
+		}
+		selection.paths = append(selection.paths, normalized)
+	}
+	return selection, nil
+}
+
+func normalizeSelectionGlob(raw string) (string, error) {
+	pattern := strings.Trim(filepath.ToSlash(strings.TrimSpace(raw)), "/")
+	if pattern == "" {
+		return "", fmt.Errorf("selection glob cannot be empty")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", fmt.Errorf("invalid selection glob %q: %w", raw, err)
+	}
+	return pattern, nil
+}
+
+func normalizeSelectionPath(raw string) (string, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return "", fmt.Errorf("selection path cannot be empty")
+	}
+	if filepath.IsAbs(trimmed) {
+		return "", fmt.Errorf("selection path %q must be relative to the repository root", raw)
+	}
+	clean := path.Clean(filepath.ToSlash(trimmed))
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("selection path %q must stay inside the repository", raw)
+	}
+	return clean, nil
+}
+
+// filters reports whether the selection restricts files by path or glob.
+func (s transferSelection) filters() bool {
+	return len(s.includes) > 0 || len(s.excludes) > 0 || len(s.paths) > 0
+}
+
+func (s transferSelection) isAll() bool {
+	return !s.filters() && !s.interactive
+}
+
+func (s transferSelection) matches(rel string) bool {
+	rel = strings.TrimPrefix(filepath.ToSlash(rel), "./")
+	if len(s.paths) > 0 {
+		under := false
+		for _, prefix := range s.paths {
+			if prefix == "." || rel == prefix || strings.HasPrefix(rel, prefix+"/") {
+				under = true
+				break
+			}
+		}
+		if !under {
+			return false
+		}
+	}
+	if len(s.includes) > 0 {
+		included := false
+		for _, pattern := range s.includes {
+			if matchPathGlob(pattern, rel) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, pattern := range s.excludes {
+		if matchPathGlob(pattern, rel) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s transferSelection) String() string {
+	if s.isAll() {
+		return "all"
+	}
+	var parts []string
+	if len(s.includes) > 0 {
+		parts = append(parts, "include="+strings.Join(s.includes, ","))
+	}
+	if len(s.excludes) > 0 {
+		parts = append(parts, "exclude="+strings.Join(s.excludes, ","))
+	}
+	if len(s.paths) > 0 {
+		parts = append(parts, "paths="+strings.Join(s.paths, ","))
+	}
+	if s.interactive {
+		parts = append(parts, "patch")
+	}
+	return strings.Join(parts, " ")
+}
+
+// matchPathGlob matches a repo-relative slash path against a glob. Patterns
+// without a slash match any single path segment (so "*.md" or "vendor" work
+// at any depth); patterns with a slash are anchored at the repository root,
+// support "**" for any number of segments, and also match everything below
+// a matching directory.
+func matchPathGlob(pattern, rel string) bool {
+	parts := strings.Split(rel, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, part := range parts {
+			if ok, _ := path.Match(pattern, part); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchGlobSegments(strings.Split(pattern, "/"), parts)
+}
+
+func matchGlobSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchGlobSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return true
+}
+
+func collectTransferSet(ctx context.Context, runner git.Runner, sourceRoot string, selection transferSelection) (transferSet, error) {
+	listed, err := listUntracked(ctx, runner, sourceRoot)
+	if err != nil {
+		return transferSet{}, err
+	}
+	emptyDirs, err := listEmptyUntrackedDirs(ctx, runner, sourceRoot)
+	if err != nil {
+		return transferSet{}, err
+	}
+	// ls-files reports untracked nested repositories as "dir/"; their
+	// contents belong to another repository and are never copied.
+	var untracked []string
+	var skipped []manifestEntry
+	for _, rel := range listed {
+		if strings.HasSuffix(rel, "/") {
+			if selection.matches(strings.TrimSuffix(rel, "/")) {
+				skipped = append(skipped, manifestEntry{Path: strings.TrimSuffix(rel, "/"), Kind: "repository", Reason: "untracked nested repository"})
+			}
+			continue
+		}
+		untracked = append(untracked, rel)
+	}
+
+	if !selection.filters() {
+		// --patch picks by path, so a rename is offered as its deletion
+		// and its addition.
+		var diffArgs, listArgs []string
+		if selection.interactive {
+			diffArgs = []string{"--no-renames", "HEAD"}
+			listArgs = []string{"--no-renames"}
+		}
+		patch, err := gitDiff(ctx, runner, sourceRoot, diffArgs...)
+		if err != nil {
+			return transferSet{}, err
+		}
+		tracked, err := listChangedPaths(ctx, runner, sourceRoot, listArgs...)
+		if err != nil {
+			return transferSet{}, err
+		}
+		return transferSet{
+			patch:     patch,
+			tracked:   tracked,
+			untracked: untracked,
+			emptyDirs: emptyDirs,
+			gitlinks:  gitlinkChanges(patch),
+			skipped:   skipped,
+		}, nil
+	}
+
+	set := transferSet{filtered: true, skipped: skipped}
+	changed, err := listChangedPaths(ctx, runner, sourceRoot, "--no-renames")
+	if err != nil {
+		return transferSet{}, err
+	}
+	for _, rel := range changed {
+		if selection.matches(rel) {
+			set.tracked = append(set.tracked, rel)
+		}
+	}
+	for _, rel := range untracked {
+		if selection.matches(rel) {
+			set.untracked = append(set.untracked, rel)
+		}
+	}
+	for _, rel := range emptyDirs {
+		if selection.matches(rel) {
+			set.emptyDirs = append(set.emptyDirs, rel)
+		}
+	}
+	if len(set.tracked) > 0 {
+		set.patch, err = gitDiff(ctx, runner, sourceRoot, append([]string{"--no-renames", "HEAD", "--"}, set.tracked...)...)
+		if err != nil {
+			return transferSet{}, err
+		}
+		set.gitlinks = gitlinkChanges(set.patch)
+	}
+	return set, nil
+}
+
+// listEmptyUntrackedDirs returns the empty directories inside untracked,
+// non-ignored directories; plain ls-files never lists them.
+func listEmptyUntrackedDirs(ctx context.Context, runner git.Runner, repoRoot string) ([]string, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "ls-files", "--others", "--exclude-standard", "--directory")
+	if err != nil {
+		if stderr != "" {
+			return nil, fmt.Errorf("git ls-files --directory: %w: %s", err, stderr)
+		}
+		return nil, fmt.Errorf("git ls-files --directory: %w", err)
+	}
+	var out []string
+	for _, line := range strings.Split(stdout, "\n") {
+		rel := strings.TrimSpace(line)
+		if !strings.HasSuffix(rel, "/") {
+			continue
+		}
+		root := filepath.Join(repoRoot, filepath.FromSlash(rel))
+		if _, err := os.Lstat(filepath.Join(root, ".git")); err == nil {
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			if !entry.IsDir() {
+				return nil
+			}
+			children, err := os.ReadDir(path)
+			if err != nil {
+				return err
+			}
+			if len(children) == 0 {
+				relPath, err := filepath.Rel(repoRoot, path)
+				if err != nil {
+					return err
+				}
+				out = append(out, filepath.ToSlash(relPath))
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func listChangedPaths(ctx context.Context, runner git.Runner, repoRoot string, extra ...string) ([]string, error) {
+	args := append([]string{"-C", repoRoot, "diff", "--name-only"}, extra...)
+	args = append(args, "HEAD")
+	stdout, stderr, err := runner.Run(ctx, args...)
+	if err != nil {
+		if stderr != "" {
+			return nil, fmt.Errorf("git diff --name-only: %w: %s", err, stderr)
+		}
+		return nil, fmt.Errorf("git diff --name-only: %w", err)
+	}
+	var out []string
+	for _, line := range strings.Split(stdout, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		out = append(out, trimmed)
+	}
+	return out, nil
+}
+
+func (s transferSet) files() map[string]struct{} {
+	files := make(map[string]struct{}, len(s.tracked)+len(s.untracked))
+	for _, rel := range s.tracked {
+		files[rel] = struct{}{}
+	}
+	for _, rel := range s.untracked {
+		files[rel] = struct{}{}
+	}
+	return files
+}
+
+func (s transferSet) includesTracked(rel string) bool {
+	if !s.filtered {
+		return true
+	}
+	for _, candidate := range s.tracked {
+		if candidate == rel {
+			return true
+		}
+	}
+	return false
+}
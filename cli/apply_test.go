@@ -3,6 +3,7 @@ package cli
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -16,11 +17,13 @@ import (
 func TestDetectApplyConflicts(t *testing.T) {
 	runner := fakeRunner{
 		responses: map[string]fakeResponse{
-			"-C /repo status --porcelain":                    {stdout: " M file.txt\n"},
-			"-C /repo diff --name-only HEAD":                 {stdout: "file.txt\n"},
-			"-C /repo ls-files --others --exclude-standard":  {stdout: ""},
-			"-C /codex diff --name-only HEAD":                {stdout: "file.txt\n"},
-			"-C /codex ls-files --others --exclude-standard": {stdout: ""},
+			"-C /repo status --porcelain":                                {stdout: " M file.txt\n"},
+			"-C /repo diff --name-only HEAD":                             {stdout: "file.txt\n"},
+			"-C /repo ls-files --others --exclude-standard":              {stdout: ""},
+			"-C /codex diff --name-only HEAD":                            {stdout: "file.txt\n"},
+			"-C /codex diff --binary HEAD":                               {stdout: "diff --git a/file.txt b/file.txt\n"},
+			"-C /codex ls-files --others --exclude-standard":             {stdout: ""},
+			"-C /codex ls-files --others --exclude-standard --directory": {stdout: ""},
 		},
 	}
 
@@ -52,11 +55,13 @@ func TestDetectApplyConflicts(t *testing.T) {
 func TestDetectApplyConflictsNone(t *testing.T) {
 	runner := fakeRunner{
 		responses: map[string]fakeResponse{
-			"-C /repo status --porcelain":                    {stdout: ""},
-			"-C /repo diff --name-only HEAD":                 {stdout: "local.txt\n"},
-			"-C /repo ls-files --others --exclude-standard":  {stdout: ""},
-			"-C /codex diff --name-only HEAD":                {stdout: "other.txt\n"},
-			"-C /codex ls-files --others --exclude-standard": {stdout: ""},
+			"-C /repo status --porcelain":                                {stdout: ""},
+			"-C /repo diff --name-only HEAD":                             {stdout: "local.txt\n"},
+			"-C /repo ls-files --others --exclude-standard":              {stdout: ""},
+			"-C /codex diff --name-only HEAD":                            {stdout: "other.txt\n"},
+			"-C /codex diff --binary HEAD":                               {stdout: "diff --git a/other.txt b/other.txt\n"},
+			"-C /codex ls-files --others --exclude-standard":             {stdout: ""},
+			"-C /codex ls-files --others --exclude-standard --directory": {stdout: ""},
 		},
 	}
 
@@ -261,10 +266,6 @@ func (r *overwriteRunner) Run(_ context.Context, args ...string) (string, string
 		return "", "", nil
 	case len(args) == 4 && args[0] == "-C" && args[1] == r.dest && args[2] == "clean" && args[3] == "-fd":
 		return "", "", nil
-	case len(args) == 5 && args[0] == "-C" && args[1] == r.source && args[2] == "diff" && args[3] == "--binary" && args[4] == "HEAD":
-		return "diff --git a/a.txt b/a.txt\n", "", nil
-	case len(args) == 5 && args[0] == "-C" && args[1] == r.source && args[2] == "ls-files" && args[3] == "--others" && args[4] == "--exclude-standard":
-		return "", "", nil
 	case len(args) == 5 && args[0] == "-C" && args[1] == r.dest && args[2] == "apply" && args[3] == "--check":
 		r.seenCheck = true
 		return "", "", fmt.Errorf("unexpected apply --check in overwrite mode")
@@ -285,7 +286,8 @@ func TestTransferChangesOverwriteSkipsPatchCheck(t *testing.T) {
 	cmd.SetOut(io.Discard)
 	cmd.SetErr(io.Discard)
 
-	err := transferChanges(context.Background(), cmd, runner, "/codex", "/repo", false, true)
+	set := transferSet{patch: "diff --git a/a.txt b/a.txt\n", tracked: []string{"a.txt"}}
+	err := transferChanges(context.Background(), cmd, runner, "/codex", "/repo", set, false, true, nil)
 	if err != nil {
 		t.Fatalf("transferChanges() error = %v", err)
 	}
@@ -308,14 +310,10 @@ func (r *overwriteFallbackRunner) Run(_ context.Context, args ...string) (string
 		return "", "", nil
 	case len(args) == 4 && args[0] == "-C" && args[1] == r.dest && args[2] == "clean" && args[3] == "-fd":
 		return "", "", nil
-	case len(args) == 5 && args[0] == "-C" && args[1] == r.source && args[2] == "diff" && args[3] == "--binary" && args[4] == "HEAD":
-		return "diff --git a/tracked.txt b/tracked.txt\n", "", nil
 	case len(args) == 5 && args[0] == "-C" && args[1] == r.dest && args[2] == "apply":
 		return "", "", fmt.Errorf("simulated apply failure")
 	case len(args) == 5 && args[0] == "-C" && args[1] == r.source && args[2] == "diff" && args[3] == "--name-status" && args[4] == "HEAD":
 		return "M\ttracked.txt\n", "", nil
-	case len(args) == 5 && args[0] == "-C" && args[1] == r.source && args[2] == "ls-files" && args[3] == "--others" && args[4] == "--exclude-standard":
-		return "", "", nil
 	default:
 		return "", "", fmt.Errorf("unexpected args: %s", strings.Join(args, " "))
 	}
@@ -345,7 +343,8 @@ func TestTransferChangesOverwriteFallsBackAfterApplyFailure(t *testing.T) {
 	cmd.SetOut(io.Discard)
 	cmd.SetErr(io.Discard)
 
-	err := transferChanges(context.Background(), cmd, runner, sourceRoot, destinationRoot, false, true)
+	set := transferSet{patch: "diff --git a/tracked.txt b/tracked.txt\n", tracked: []string{"tracked.txt"}}
+	err := transferChanges(context.Background(), cmd, runner, sourceRoot, destinationRoot, set, false, true, nil)
 	if err != nil {
 		t.Fatalf("transferChanges() error = %v", err)
 	}
@@ -357,3 +356,344 @@ func TestTransferChangesOverwriteFallsBackAfterApplyFailure(t *testing.T) {
 		t.Fatalf("destination tracked.txt = %q, want %q", string(got), "new content\n")
 	}
 }
+
+func TestMatchPathGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{pattern: "*.go", rel: "cli/apply.go", want: true},
+		{pattern: "*.go", rel: "cli/apply.md", want: false},
+		{pattern: "vendor", rel: "third_party/vendor/a.txt", want: true},
+		{pattern: "cli/*.go", rel: "cli/apply.go", want: true},
+		{pattern: "cli/*.go", rel: "internal/cli/apply.go", want: false},
+		{pattern: "docs/**/*.md", rel: "docs/a.md", want: true},
+		{pattern: "docs/**/*.md", rel: "docs/plans/jobs/a.md", want: true},
+		{pattern: "internal/config", rel: "internal/config/config.go", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := matchPathGlob(tt.pattern, tt.rel); got != tt.want {
+			t.Fatalf("matchPathGlob(%q, %q) = %t, want %t", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestTransferSelectionMatches(t *testing.T) {
+	selection, err := newTransferSelection([]string{"*.go"}, []string{"*_test.go"}, []string{"./cli"}, false)
+	if err != nil {
+		t.Fatalf("newTransferSelection() error = %v", err)
+	}
+	for rel, want := range map[string]bool{
+		"cli/apply.go":      true,
+		"cli/apply_test.go": false,
+		"cli/README.md":     false,
+		"internal/a.go":     false,
+	} {
+		if got := selection.matches(rel); got != want {
+			t.Fatalf("matches(%q) = %t, want %t", rel, got, want)
+		}
+	}
+
+	if _, err := newTransferSelection(nil, nil, []string{"../outside"}, false); err == nil {
+		t.Fatalf("expected error for path outside the repository")
+	}
+	if _, err := newTransferSelection([]string{"[bad"}, nil, nil, false); err == nil {
+		t.Fatalf("expected error for invalid glob")
+	}
+}
+
+func TestCollectTransferSetFiltersPaths(t *testing.T) {
+	runner := fakeRunner{
+		responses: map[string]fakeResponse{
+			"-C /codex ls-files --others --exclude-standard":             {stdout: "notes.md\nsrc/new.go\n"},
+			"-C /codex ls-files --others --exclude-standard --directory": {stdout: "notes.md\nsrc/new.go\n"},
+			"-C /codex diff --name-only --no-renames HEAD":               {stdout: "src/a.go\nREADME.md\n"},
+			"-C /codex diff --binary --no-renames HEAD -- src/a.go":      {stdout: "diff --git a/src/a.go b/src/a.go\n"},
+		},
+	}
+	selection, err := newTransferSelection([]string{"*.go"}, nil, nil, false)
+	if err != nil {
+		t.Fatalf("newTransferSelection() error = %v", err)
+	}
+
+	set, err := collectTransferSet(context.Background(), runner, "/codex", selection)
+	if err != nil {
+		t.Fatalf("collectTransferSet() error = %v", err)
+	}
+	if len(set.tracked) != 1 || set.tracked[0] != "src/a.go" {
+		t.Fatalf("tracked = %v, want [src/a.go]", set.tracked)
+	}
+	if len(set.untracked) != 1 || set.untracked[0] != "src/new.go" {
+		t.Fatalf("untracked = %v, want [src/new.go]", set.untracked)
+	}
+	if !strings.Contains(set.patch, "src/a.go") {
+		t.Fatalf("expected filtered patch, got %q", set.patch)
+	}
+}
+
+func TestSelectTransferSetInteractively(t *testing.T) {
+	patch := strings.Join([]string{
+		"diff --git a/a.txt b/a.txt",
+		"index 1111111..2222222 100644",
+		"--- a/a.txt",
+		"+++ b/a.txt",
+		"@@ -1,1 +1,1 @@",
+		"-one",
+		"+ONE",
+		"@@ -10,1 +10,1 @@",
+		"-ten",
+		"+TEN",
+		"diff --git a/b.txt b/b.txt",
+		"index 3333333..4444444 100644",
+		"--- a/b.txt",
+		"+++ b/b.txt",
+		"@@ -1,1 +1,1 @@",
+		"-bee",
+		"+BEE",
+	}, "\n")
+	set := transferSet{
+		patch:     patch,
+		tracked:   []string{"a.txt", "b.txt"},
+		untracked: []string{"new.txt", "skip.txt"},
+	}
+
+	var out bytes.Buffer
+	got, err := selectTransferSetInteractively(strings.NewReader("n\ny\nn\ny\nn\n"), &out, set, "local checkout")
+	if err != nil {
+		t.Fatalf("selectTransferSetInteractively() error = %v", err)
+	}
+	if len(got.tracked) != 1 || got.tracked[0] != "a.txt" {
+		t.Fatalf("tracked = %v, want [a.txt]", got.tracked)
+	}
+	if strings.Contains(got.patch, "+ONE") || !strings.Contains(got.patch, "+TEN") {
+		t.Fatalf("expected only the second hunk of a.txt, got:\n%s", got.patch)
+	}
+	if strings.Contains(got.patch, "b.txt") {
+		t.Fatalf("did not expect b.txt in patch, got:\n%s", got.patch)
+	}
+	if len(got.untracked) != 1 || got.untracked[0] != "new.txt" {
+		t.Fatalf("untracked = %v, want [new.txt]", got.untracked)
+	}
+	if !got.partial {
+		t.Fatalf("expected interactive selection to be marked partial")
+	}
+}
+
+func TestSelectTransferSetInteractivelyWholeSections(t *testing.T) {
+	patch := strings.Join([]string{
+		"diff --git a/gone.txt b/gone.txt",
+		"deleted file mode 100644",
+		"index 1111111..0000000",
+		"--- a/gone.txt",
+		"+++ /dev/null",
+		"@@ -1,2 +0,0 @@",
+		"-a",
+		"-b",
+		"diff --git a/run.sh b/run.sh",
+		"old mode 100644",
+		"new mode 100755",
+	}, "\n")
+	set := transferSet{
+		patch:     patch,
+		tracked:   []string{"gone.txt", "run.sh"},
+		emptyDirs: []string{"cache", "tmp"},
+	}
+
+	var out bytes.Buffer
+	got, err := selectTransferSetInteractively(strings.NewReader("y\nn\ny\nn\n"), &out, set, "local checkout")
+	if err != nil {
+		t.Fatalf("selectTransferSetInteractively() error = %v", err)
+	}
+	if strings.Count(out.String(), "gone.txt in the local checkout?") != 1 || !strings.Contains(out.String(), "Delete gone.txt") {
+		t.Fatalf("expected a single delete prompt for gone.txt, got:\n%s", out.String())
+	}
+	if !strings.Contains(got.patch, "deleted file mode 100644") || !strings.Contains(got.patch, "-a\n-b") {
+		t.Fatalf("expected the whole deletion in patch, got:\n%s", got.patch)
+	}
+	if strings.Contains(got.patch, "run.sh") {
+		t.Fatalf("did not expect run.sh in patch, got:\n%s", got.patch)
+	}
+	if len(got.tracked) != 1 || got.tracked[0] != "gone.txt" {
+		t.Fatalf("tracked = %v, want [gone.txt]", got.tracked)
+	}
+	if len(got.emptyDirs) != 1 || got.emptyDirs[0] != "cache" {
+		t.Fatalf("emptyDirs = %v, want [cache]", got.emptyDirs)
+	}
+}
+
+func TestDryRunActionsThreeWay(t *testing.T) {
+	plan := transferPlan{
+		destinationRoot: "/repo",
+		sourceRoot:      "/codex",
+		threeWay:        true,
+	}
+	preflight := transferPreflight{
+		trackedPatch: true,
+	}
+
+	actions := dryRunActions(handoffApply, plan, preflight, false)
+	if len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %d (%v)", len(actions), actions)
+	}
+	if !strings.Contains(actions[0], "stash create") {
+		t.Fatalf("expected backup action, got %q", actions[0])
+	}
+	if !strings.Contains(actions[1], "apply --3way <temp-patch>") {
+		t.Fatalf("expected apply --3way action, got %q", actions[1])
+	}
+}
+
+func TestConflictMarkers(t *testing.T) {
+	got := string(conflictMarkers([]byte("ours"), []byte("theirs\n"), "local checkout", "Codex worktree"))
+	want := "<<<<<<< local checkout\nours\n=======\ntheirs\n>>>>>>> Codex worktree\n"
+	if got != want {
+		t.Fatalf("conflictMarkers() = %q, want %q", got, want)
+	}
+}
+
+func TestCollectTransferCommits(t *testing.T) {
+	runner := fakeRunner{
+		responses: map[string]fakeResponse{
+			"-C /repo rev-parse --verify HEAD":  {stdout: "aaa"},
+			"-C /codex rev-parse --verify HEAD": {stdout: "ccc"},
+			"-C /codex log --reverse --right-only --cherry-pick --format=%H%x1f%P%x1f%an <%ae>%x1f%s aaa...ccc": {
+				stdout: "bbb\x1faaa\x1fAda <ada@example.com>\x1ffirst\nccc\x1fbbb\x1fAda <ada@example.com>\x1fsecond",
+			},
+			"-C /codex diff --name-only --no-renames aaa...ccc": {stdout: "a.txt\nb.txt"},
+		},
+	}
+
+	rng, err := collectTransferCommits(context.Background(), runner, "/codex", "/repo")
+	if err != nil {
+		t.Fatalf("collectTransferCommits() error = %v", err)
+	}
+	if rng.base != "aaa" || len(rng.files) != 2 {
+		t.Fatalf("unexpected range: %+v", rng)
+	}
+	if got := strings.Join(rng.hashes(), " "); got != "bbb ccc" {
+		t.Fatalf("hashes() = %q, want %q", got, "bbb ccc")
+	}
+	if rng.commits[0].author != "Ada <ada@example.com>" || rng.commits[1].subject != "second" {
+		t.Fatalf("unexpected commits: %+v", rng.commits)
+	}
+}
+
+func TestCollectTransferCommitsRejectsMerges(t *testing.T) {
+	runner := fakeRunner{
+		responses: map[string]fakeResponse{
+			"-C /repo rev-parse --verify HEAD":  {stdout: "aaa"},
+			"-C /codex rev-parse --verify HEAD": {stdout: "mmm"},
+			"-C /codex log --reverse --right-only --cherry-pick --format=%H%x1f%P%x1f%an <%ae>%x1f%s aaa...mmm": {
+				stdout: "mmm\x1faaa bbb\x1fAda <ada@example.com>\x1fmerge",
+			},
+		},
+	}
+
+	if _, err := collectTransferCommits(context.Background(), runner, "/codex", "/repo"); err == nil || !strings.Contains(err.Error(), "is a merge") {
+		t.Fatalf("expected merge rejection, got %v", err)
+	}
+}
+
+func TestDryRunActionsCommits(t *testing.T) {
+	plan := transferPlan{destinationRoot: "/repo", sourceRoot: "/codex", commits: true}
+	preflight := transferPreflight{commits: 2, commitFiles: 1, commitHashes: []string{"bbb", "ccc"}, trackedPatch: true}
+
+	actions := dryRunActions(handoffApply, plan, preflight, false)
+	if len(actions) != 3 || !strings.Contains(actions[0], "cherry-pick bbb ccc") {
+		t.Fatalf("expected cherry-pick before patch actions, got %v", actions)
+	}
+}
+
+func TestGitlinkChanges(t *testing.T) {
+	patch := strings.Join([]string{
+		"diff --git a/a.txt b/a.txt",
+		"index 1111111..2222222 100644",
+		"--- a/a.txt",
+		"+++ b/a.txt",
+		"@@ -1 +1 @@",
+		"-old",
+		"+new",
+		"diff --git a/sub b/sub",
+		"index e8e1e4a..74e8683 160000",
+		"--- a/sub",
+		"+++ b/sub",
+		"@@ -1 +1 @@",
+		"-Subproject commit e8e1e4a43383089909c8eb95aa7645be1505a26b",
+		"+Subproject commit 74e8683e6c56586209a94a143f9086c1d6c9c1ec",
+		"",
+	}, "\n")
+
+	changes := gitlinkChanges(patch)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 gitlink change, got %+v", changes)
+	}
+	want := gitlinkChange{path: "sub", from: "e8e1e4a43383089909c8eb95aa7645be1505a26b", to: "74e8683e6c56586209a94a143f9086c1d6c9c1ec"}
+	if changes[0] != want {
+		t.Fatalf("gitlinkChanges() = %+v, want %+v", changes[0], want)
+	}
+
+	dirty := strings.Join([]string{
+		"diff --git a/sub b/sub",
+		"index e8e1e4a..74e8683 160000",
+		"--- a/sub",
+		"+++ b/sub",
+		"@@ -1 +1 @@",
+		"-Subproject commit ",
+		"+Subproject commit 74e8683e6c56586209a94a143f9086c1d6c9c1ec-dirty",
+		"",
+	}, "\n")
+	dirtyChanges := gitlinkChanges(dirty)
+	dirtyWant := gitlinkChange{path: "sub", to: "74e8683e6c56586209a94a143f9086c1d6c9c1ec"}
+	if len(dirtyChanges) != 1 || dirtyChanges[0] != dirtyWant {
+		t.Fatalf("gitlinkChanges() = %+v, want [%+v]", dirtyChanges, dirtyWant)
+	}
+
+	manifest := &transferManifest{}
+	manifest.recordPatch(patch, false)
+	if len(manifest.Copied) != 1 || manifest.Copied[0].Path != "a.txt" {
+		t.Fatalf("expected only a.txt recorded from the patch, got %+v", manifest.Copied)
+	}
+
+	err := applyGitlinkChanges(context.Background(), &cobra.Command{}, fakeRunner{}, t.TempDir(), t.TempDir(), changes, false, manifest)
+	if err != nil {
+		t.Fatalf("applyGitlinkChanges() error = %v", err)
+	}
+	if len(manifest.Skipped) != 1 || !strings.Contains(manifest.Skipped[0].Reason, "not initialized") {
+		t.Fatalf("expected uninitialized submodule to be skipped, got %+v", manifest.Skipped)
+	}
+}
+
+func TestCopyFileVerifiedMirrorsMode(t *testing.T) {
+	sourceRoot := t.TempDir()
+	destinationRoot := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceRoot, "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(destinationRoot, "run.sh"), []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := copyFileVerified(sourceRoot, destinationRoot, "run.sh", false, io.Discard, false)
+	if err != nil {
+		t.Fatalf("copyFileVerified() error = %v", err)
+	}
+	if len(sum) != 64 {
+		t.Fatalf("expected sha256 checksum, got %q", sum)
+	}
+	info, err := os.Stat(filepath.Join(destinationRoot, "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Fatalf("destination mode = %v, want 0755", info.Mode().Perm())
+	}
+
+	if err := os.Mkdir(filepath.Join(sourceRoot, "dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := copyFileVerified(sourceRoot, destinationRoot, "dir", false, io.Discard, false); !errors.Is(err, errUnsupportedFileType) {
+		t.Fatalf("expected errUnsupportedFileType for a directory, got %v", err)
+	}
+}
@@ -0,0 +1,472 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+const (
+	threeWayStateDir  = "gwtt/apply-3way"
+	threeWayStateFile = "state.json"
+	threeWayBackupRef = "refs/gwtt/apply-3way/backup"
+	theirsSuffix      = ".gwtt-theirs"
+)
+
+// threeWayState records an in-progress `apply --3way` so --continue and
+// --abort can finish or exactly undo it later.
+type threeWayState struct {
+	Task               string   `json:"task"`
+	To                 string   `json:"to"`
+	SourceRoot         string   `json:"source_root"`
+	SourceName         string   `json:"source_name"`
+	DestinationRoot    string   `json:"destination_root"`
+	DestinationName    string   `json:"destination_name"`
+	DestinationHead    string   `json:"destination_head"`
+	Base               string   `json:"base"`
+	Index              string   `json:"index,omitempty"`
+	Backup             string   `json:"backup,omitempty"`
+	Tracked            []string `json:"tracked,omitempty"`
+	Created            []string `json:"created,omitempty"`
+	Overwritten        []string `json:"overwritten,omitempty"`
+	Conflicts          []string `json:"conflicts,omitempty"`
+	UntrackedConflicts []string `json:"untracked_conflicts,omitempty"`
+}
+
+func threeWayStatePaths(ctx context.Context, runner git.Runner, repoRoot string) (dir, file string, err error) {
+	commonDir, err := git.CommonDirAt(ctx, runner, repoRoot)
+	if err != nil {
+		return "", "", err
+	}
+	dir = filepath.Join(commonDir, filepath.FromSlash(threeWayStateDir))
+	return dir, filepath.Join(dir, threeWayStateFile), nil
+}
+
+func loadThreeWayState(path string) (threeWayState, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return threeWayState{}, false, nil
+		}
+		return threeWayState{}, false, err
+	}
+	var state threeWayState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return threeWayState{}, false, fmt.Errorf("read apply --3way state %s: %w", path, err)
+	}
+	return state, true, nil
+}
+
+func saveThreeWayState(path string, state threeWayState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// transferChangesThreeWay applies the selected set with `git apply --3way`,
+// leaving conflict markers instead of refusing overlapping changes. It
+// returns the conflicted paths; an empty result means the apply completed.
+func transferChangesThreeWay(ctx context.Context, cmd *cobra.Command, runner git.Runner, opaqueID string, plan transferPlan, set transferSet) ([]string, error) {
+	stateDir, statePath, err := threeWayStatePaths(ctx, runner, plan.destinationRoot)
+	if err != nil {
+		return nil, err
+	}
+	if _, inProgress, err := loadThreeWayState(statePath); err != nil {
+		return nil, err
+	} else if inProgress {
+		return nil, fmt.Errorf("an apply --3way is already in progress (run gwtt apply --continue or gwtt apply --abort)")
+	}
+
+	head, err := git.RevParse(ctx, runner, plan.destinationRoot, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	base, err := threeWayBase(ctx, runner, plan, set, head)
+	if err != nil {
+		return nil, err
+	}
+	// The index as it was, so finishing leaves the user's own staged
+	// changes on the touched paths staged.
+	index, stderr, err := runner.Run(ctx, "-C", plan.destinationRoot, "write-tree")
+	if err != nil {
+		return nil, fmt.Errorf("record %s index: %w: %s", plan.destinationName, err, stderr)
+	}
+	backup, _, err := runner.Run(ctx, "-C", plan.destinationRoot, "stash", "create")
+	if err != nil {
+		return nil, fmt.Errorf("backup %s: %w", plan.destinationName, err)
+	}
+	backup = strings.TrimSpace(backup)
+	if backup != "" {
+		if err := runGit(ctx, cmd, false, runner, "-C", plan.destinationRoot, "update-ref", threeWayBackupRef, backup); err != nil {
+			return nil, err
+		}
+	}
+
+	state := threeWayState{
+		Task:            opaqueID,
+		To:              plan.to,
+		SourceRoot:      plan.sourceRoot,
+		SourceName:      plan.sourceName,
+		DestinationRoot: plan.destinationRoot,
+		DestinationName: plan.destinationName,
+		DestinationHead: head,
+		Base:            base,
+		Index:           strings.TrimSpace(index),
+		Backup:          backup,
+		Tracked:         set.tracked,
+	}
+	if err := saveThreeWayState(statePath, state); err != nil {
+		return nil, err
+	}
+
+	rollback := func(cause error) error {
+		if abortErr := abortThreeWay(ctx, cmd, runner, stateDir, state); abortErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", cause, abortErr)
+		}
+		return cause
+	}
+
+	if set.patch != "" {
+		dirtyTouched, err := unstagedTouchedPaths(ctx, runner, plan.destinationRoot, set.tracked)
+		if err != nil {
+			return nil, rollback(err)
+		}
+		if len(dirtyTouched) > 0 {
+			addArgs := append([]string{"-C", plan.destinationRoot, "add", "-A", "--"}, dirtyTouched...)
+			if err := runGit(ctx, cmd, false, runner, addArgs...); err != nil {
+				return nil, rollback(err)
+			}
+		}
+
+		patchFile, err := writeTempPatch(set.patch)
+		if err != nil {
+			return nil, rollback(err)
+		}
+		defer func() {
+			if err := removeTempPatch(patchFile); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: failed to remove temp patch %s: %v\n", patchFile, err)
+			}
+		}()
+		_, stderr, applyErr := runner.Run(ctx, "-C", plan.destinationRoot, "apply", "--3way", patchFile)
+		conflicts, err := unmergedPaths(ctx, runner, plan.destinationRoot)
+		if err != nil {
+			return nil, rollback(err)
+		}
+		if applyErr != nil && len(conflicts) == 0 {
+			if stderr != "" {
+				return nil, rollback(fmt.Errorf("apply --3way: %w: %s", applyErr, stderr))
+			}
+			return nil, rollback(fmt.Errorf("apply --3way: %w", applyErr))
+		}
+		state.Conflicts = conflicts
+	}
+
+	for _, rel := range set.untracked {
+		if err := transferUntrackedThreeWay(&state, stateDir, rel); err != nil {
+			return nil, rollback(err)
+		}
+	}
+
+	if err := saveThreeWayState(statePath, state); err != nil {
+		return nil, rollback(err)
+	}
+
+	conflicts := append(append([]string{}, state.Conflicts...), state.UntrackedConflicts...)
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+	return nil, finishThreeWay(ctx, cmd, runner, stateDir, state)
+}
+
+// threeWayBase returns the common base of the source and destination HEADs.
+// git apply --3way merges against the preimage blobs named in the patch,
+// which come from the source HEAD, so the source must not carry commits the
+// destination lacks; otherwise the merge would not run against the base.
+func threeWayBase(ctx context.Context, runner git.Runner, plan transferPlan, set transferSet, destinationHead string) (string, error) {
+	sourceHead, err := git.RevParse(ctx, runner, plan.sourceRoot, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	base, stderr, err := runner.Run(ctx, "-C", plan.destinationRoot, "merge-base", destinationHead, sourceHead)
+	if err != nil {
+		return "", fmt.Errorf("find the common base of the %s and the %s: %w: %s", plan.sourceName, plan.destinationName, err, stderr)
+	}
+	base = strings.TrimSpace(base)
+	if base != sourceHead && set.patch != "" {
+		return "", fmt.Errorf("the %s has commits the %s lacks, so --3way cannot merge against their common base %s (replay them with --commits first)", plan.sourceName, plan.destinationName, worktree.ShortHash(base, 7))
+	}
+	return base, nil
+}
+
+func unstagedTouchedPaths(ctx context.Context, runner git.Runner, repoRoot string, touched []string) ([]string, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "diff", "--name-only")
+	if err != nil {
+		if stderr != "" {
+			return nil, fmt.Errorf("git diff --name-only: %w: %s", err, stderr)
+		}
+		return nil, fmt.Errorf("git diff --name-only: %w", err)
+	}
+	dirty := map[string]struct{}{}
+	for _, line := range strings.Split(stdout, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			dirty[trimmed] = struct{}{}
+		}
+	}
+	var out []string
+	for _, rel := range touched {
+		if _, ok := dirty[rel]; ok {
+			out = append(out, rel)
+		}
+	}
+	return out, nil
+}
+
+func unmergedPaths(ctx context.Context, runner git.Runner, repoRoot string) ([]string, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		if stderr != "" {
+			return nil, fmt.Errorf("git diff --diff-filter=U: %w: %s", err, stderr)
+		}
+		return nil, fmt.Errorf("git diff --diff-filter=U: %w", err)
+	}
+	var out []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out, nil
+}
+
+// transferUntrackedThreeWay copies one untracked file. When the destination
+// already holds different content, the original is backed up and the file is
+// rewritten with conflict markers (or, for binary content, the source copy is
+// placed next to it with a .gwtt-theirs suffix).
+func transferUntrackedThreeWay(state *threeWayState, stateDir, rel string) error {
+	srcPath := filepath.Join(state.SourceRoot, rel)
+	dstPath := filepath.Join(state.DestinationRoot, rel)
+
+	dstInfo, err := os.Lstat(dstPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := copyFile(state.SourceRoot, state.DestinationRoot, rel, false, io.Discard, false); err != nil {
+			return err
+		}
+		state.Created = append(state.Created, rel)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	srcInfo, err := os.Lstat(srcPath)
+	if err != nil {
+		return err
+	}
+	if !dstInfo.Mode().IsRegular() || !srcInfo.Mode().IsRegular() {
+		return fmt.Errorf("cannot merge untracked non-regular file %s", rel)
+	}
+
+	ours, err := os.ReadFile(dstPath)
+	if err != nil {
+		return err
+	}
+	theirs, err := os.ReadFile(srcPath)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(ours, theirs) {
+		return nil
+	}
+
+	if err := copyFile(state.DestinationRoot, filepath.Join(stateDir, "untracked"), rel, false, io.Discard, false); err != nil {
+		return err
+	}
+	state.Overwritten = append(state.Overwritten, rel)
+	state.UntrackedConflicts = append(state.UntrackedConflicts, rel)
+
+	if bytes.IndexByte(ours, 0) >= 0 || bytes.IndexByte(theirs, 0) >= 0 {
+		if err := os.WriteFile(dstPath+theirsSuffix, theirs, srcInfo.Mode().Perm()); err != nil {
+			return err
+		}
+		state.Created = append(state.Created, rel+theirsSuffix)
+		return nil
+	}
+	return os.WriteFile(dstPath, conflictMarkers(ours, theirs, state.DestinationName, state.SourceName), dstInfo.Mode().Perm())
+}
+
+func conflictMarkers(ours, theirs []byte, oursLabel, theirsLabel string) []byte {
+	var b bytes.Buffer
+	writeSide := func(content []byte) {
+		b.Write(content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString("<<<<<<< " + oursLabel + "\n")
+	writeSide(ours)
+	b.WriteString("=======\n")
+	writeSide(theirs)
+	b.WriteString(">>>>>>> " + theirsLabel + "\n")
+	return b.Bytes()
+}
+
+// unresolvedThreeWayPaths lists conflicts that still need attention.
+func unresolvedThreeWayPaths(ctx context.Context, runner git.Runner, state threeWayState) ([]string, error) {
+	unresolved, err := unmergedPaths(ctx, runner, state.DestinationRoot)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range state.UntrackedConflicts {
+		dstPath := filepath.Join(state.DestinationRoot, rel)
+		if _, err := os.Lstat(dstPath + theirsSuffix); err == nil {
+			unresolved = append(unresolved, rel)
+			continue
+		}
+		content, err := os.ReadFile(dstPath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		if bytes.Contains(content, []byte("<<<<<<< ")) && bytes.Contains(content, []byte(">>>>>>> ")) {
+			unresolved = append(unresolved, rel)
+		}
+	}
+	return unresolved, nil
+}
+
+// finishThreeWay leaves transferred changes unstaged, matching plain apply:
+// the touched index entries go back to the recorded pre-apply index. It
+// then drops the recorded state and backup ref.
+func finishThreeWay(ctx context.Context, cmd *cobra.Command, runner git.Runner, stateDir string, state threeWayState) error {
+	if len(state.Tracked) > 0 {
+		resetArgs := []string{"-C", state.DestinationRoot, "reset", "-q"}
+		if state.Index != "" {
+			resetArgs = append(resetArgs, state.Index)
+		}
+		resetArgs = append(append(resetArgs, "--"), state.Tracked...)
+		if err := runGit(ctx, cmd, false, runner, resetArgs...); err != nil {
+			return err
+		}
+	}
+	return clearThreeWayState(ctx, cmd, runner, stateDir, state)
+}
+
+// abortThreeWay restores the destination to the exact pre-apply state.
+func abortThreeWay(ctx context.Context, cmd *cobra.Command, runner git.Runner, stateDir string, state threeWayState) error {
+	head, err := git.RevParse(ctx, runner, state.DestinationRoot, "HEAD")
+	if err != nil {
+		return err
+	}
+	if head != state.DestinationHead {
+		return fmt.Errorf("%s HEAD moved since apply --3way started (%s -> %s); restore manually from %s", state.DestinationName, state.DestinationHead, head, threeWayBackupRef)
+	}
+	if err := runGit(ctx, cmd, false, runner, "-C", state.DestinationRoot, "reset", "-q", "--hard", state.DestinationHead); err != nil {
+		return err
+	}
+	for _, rel := range state.Created {
+		if err := removeTrackedPath(state.DestinationRoot, rel); err != nil {
+			return err
+		}
+	}
+	for _, rel := range state.Overwritten {
+		if err := copyFile(filepath.Join(stateDir, "untracked"), state.DestinationRoot, rel, false, io.Discard, false); err != nil {
+			return err
+		}
+	}
+	if state.Backup != "" {
+		if err := runGit(ctx, cmd, false, runner, "-C", state.DestinationRoot, "stash", "apply", "--index", state.Backup); err != nil {
+			return err
+		}
+	}
+	return clearThreeWayState(ctx, cmd, runner, stateDir, state)
+}
+
+func clearThreeWayState(ctx context.Context, cmd *cobra.Command, runner git.Runner, stateDir string, state threeWayState) error {
+	if state.Backup != "" {
+		if err := runGit(ctx, cmd, false, runner, "-C", state.DestinationRoot, "update-ref", "-d", threeWayBackupRef); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(stateDir)
+}
+
+func runThreeWayResume(cmd *cobra.Command, abort bool) error {
+	ctx := cmd.Context()
+	modeCtx, err := resolveModeContext(cmd, false)
+	if err != nil {
+		return err
+	}
+	if modeCtx.mode != modeCodex {
+		return fmt.Errorf("%s is only supported in --mode=codex", handoffApply)
+	}
+	runner := defaultRunner(ctx)
+	repoRoot, err := repoRoot(ctx, runner)
+	if err != nil {
+		return err
+	}
+	stateDir, statePath, err := threeWayStatePaths(ctx, runner, repoRoot)
+	if err != nil {
+		return err
+	}
+	state, ok, err := loadThreeWayState(statePath)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no apply --3way in progress")
+	}
+
+	if abort {
+		if err := abortThreeWay(ctx, cmd, runner, stateDir, state); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), ui.SuccessStyle.Render(fmt.Sprintf("apply aborted; %s restored", state.DestinationName)))
+		return err
+	}
+
+	unresolved, err := unresolvedThreeWayPaths(ctx, runner, state)
+	if err != nil {
+		return err
+	}
+	if len(unresolved) > 0 {
+		if err := printThreeWayConflicts(cmd.OutOrStdout(), state.DestinationName, unresolved); err != nil {
+			return err
+		}
+		return errApplyConflicted
+	}
+	if err := finishThreeWay(ctx, cmd, runner, stateDir, state); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), ui.SuccessStyle.Render(fmt.Sprintf("%s complete", handoffApply)))
+	return err
+}
+
+func printThreeWayConflicts(out io.Writer, destinationName string, conflicts []string) error {
+	if _, err := fmt.Fprintf(out, "%s\n", ui.WarningStyle.Render(fmt.Sprintf("conflicts in %s:", destinationName))); err != nil {
+		return err
+	}
+	for _, rel := range conflicts {
+		if _, err := fmt.Fprintf(out, "- %s\n", ui.WarningStyle.Render(rel)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(out, "%s\n", ui.WarningStyle.Render("resolve the markers, then run gwtt apply --continue (or gwtt apply --abort to restore)"))
+	return err
+}
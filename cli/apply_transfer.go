@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/pi2pie/git-worktree-tasks/internal/backup"
 	"github.com/pi2pie/git-worktree-tasks/internal/git"
 	"github.com/spf13/cobra"
 )
@@ -29,6 +30,19 @@ func printDryRunPlan(out io.Writer, mode handoffMode, plan transferPlan, preflig
 	if _, err := fmt.Fprintf(out, "  overwrite: %t\n", mode == handoffOverwrite); err != nil {
 		return err
 	}
+	if _, err := fmt.Fprintf(out, "  selection: %s\n", plan.selection); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, "  three_way: %t\n", plan.threeWay); err != nil {
+		return err
+	}
+	commits := "off"
+	if plan.commits {
+		commits = fmt.Sprintf("%d", preflight.commits)
+	}
+	if _, err := fmt.Fprintf(out, "  commits: %s\n", commits); err != nil {
+		return err
+	}
 
 	if _, err := fmt.Fprintln(out, ""); err != nil {
 		return err
@@ -42,6 +56,12 @@ func printDryRunPlan(out io.Writer, mode handoffMode, plan transferPlan, preflig
 	if _, err := fmt.Fprintf(out, "  overlapping_files: %d\n", preflight.overlappingFiles); err != nil {
 		return err
 	}
+	if _, err := fmt.Fprintf(out, "  commit_files: %d\n", preflight.commitFiles); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, "  tracked_files: %d\n", preflight.trackedFiles); err != nil {
+		return err
+	}
 	trackedPatch := "none"
 	if preflight.trackedPatch {
 		trackedPatch = "present"
@@ -52,6 +72,15 @@ func printDryRunPlan(out io.Writer, mode handoffMode, plan transferPlan, preflig
 	if _, err := fmt.Fprintf(out, "  untracked_files: %d\n", len(preflight.untrackedFiles)); err != nil {
 		return err
 	}
+	if _, err := fmt.Fprintf(out, "  empty_dirs: %d\n", len(preflight.emptyDirs)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, "  submodules: %d\n", len(preflight.gitlinks)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, "  skipped: %d\n", len(preflight.skipped)); err != nil {
+		return err
+	}
 
 	if _, err := fmt.Fprintln(out, ""); err != nil {
 		return err
@@ -71,18 +100,39 @@ func printDryRunPlan(out io.Writer, mode handoffMode, plan transferPlan, preflig
 func dryRunActions(mode handoffMode, plan transferPlan, preflight transferPreflight, maskPaths bool) []string {
 	actions := make([]string, 0, len(preflight.untrackedFiles)+4)
 
+	if mode == handoffOverwrite && plan.backup {
+		actions = append(actions, fmt.Sprintf("backup %s -> %s<id> (restore with gwtt restore <id>)", maskPathForDryRun(plan.destinationRoot, maskPaths), backup.RefPrefix))
+	}
 	if mode == handoffOverwrite {
 		actions = append(actions, "[destructive] "+formatGitCommandForDryRun([]string{"-C", plan.destinationRoot, "reset", "--hard"}, maskPaths))
 		actions = append(actions, "[destructive] "+formatGitCommandForDryRun([]string{"-C", plan.destinationRoot, "clean", "-fd"}, maskPaths))
 	}
 
-	if preflight.trackedPatch {
+	if len(preflight.commitHashes) > 0 {
+		actions = append(actions, formatGitCommandForDryRun(append([]string{"-C", plan.destinationRoot, "cherry-pick"}, preflight.commitHashes...), maskPaths))
+	}
+
+	if plan.threeWay && (preflight.trackedPatch || len(preflight.untrackedFiles) > 0) {
+		actions = append(actions, formatGitCommandForDryRun([]string{"-C", plan.destinationRoot, "stash", "create"}, maskPaths)+" (backup for --abort)")
+	}
+
+	if preflight.trackedPatch && plan.threeWay {
+		actions = append(actions, formatGitCommandForDryRun([]string{"-C", plan.destinationRoot, "apply", "--3way", "<temp-patch>"}, maskPaths))
+	} else if preflight.trackedPatch {
 		if mode == handoffApply {
 			actions = append(actions, formatGitCommandForDryRun([]string{"-C", plan.destinationRoot, "apply", "--check", "<temp-patch>"}, maskPaths))
 		}
 		actions = append(actions, formatGitCommandForDryRun([]string{"-C", plan.destinationRoot, "apply", "<temp-patch>"}, maskPaths))
 	}
 
+	for _, change := range preflight.gitlinks {
+		if change.from == "" || change.to == "" {
+			actions = append(actions, fmt.Sprintf("skip submodule %s (added or removed in source)", change.path))
+			continue
+		}
+		actions = append(actions, formatGitCommandForDryRun([]string{"-C", filepath.Join(plan.destinationRoot, change.path), "checkout", "-q", "--detach", change.to}, maskPaths))
+	}
+
 	for _, rel := range preflight.untrackedFiles {
 		actions = append(actions, fmt.Sprintf(
 			"copy %s -> %s",
@@ -91,30 +141,115 @@ func dryRunActions(mode handoffMode, plan transferPlan, preflight transferPrefli
 		))
 	}
 
+	for _, rel := range preflight.emptyDirs {
+		actions = append(actions, "mkdir "+maskPathForDryRun(filepath.Join(plan.destinationRoot, rel), maskPaths))
+	}
+
+	for _, entry := range preflight.skipped {
+		actions = append(actions, fmt.Sprintf("skip %s (%s)", entry.Path, entry.Reason))
+	}
+
 	if len(actions) == 0 {
-		actions = append(actions, "no tracked or untracked changes detected")
+		actions = append(actions, "no commits, tracked or untracked changes detected")
 	}
 
 	return actions
 }
 
-func transferChanges(ctx context.Context, cmd *cobra.Command, runner git.Runner, sourceRoot, destinationRoot string, dryRun, resetDestination bool) error {
+func transferChanges(ctx context.Context, cmd *cobra.Command, runner git.Runner, sourceRoot, destinationRoot string, set transferSet, dryRun, resetDestination bool, manifest *transferManifest) error {
 	maskPaths := shouldMaskSensitivePaths(ctx)
 	if resetDestination {
 		if err := runGit(ctx, cmd, dryRun, runner, "-C", destinationRoot, "reset", "--hard"); err != nil {
 			return err
 		}
-		if err := runGit(ctx, cmd, dryRun, runner, "-C", destinationRoot, "clean", "-fd"); err != nil {
+		if err := cleanDestination(ctx, cmd, runner, destinationRoot, dryRun, manifest); err != nil {
 			return err
 		}
 	}
 
-	patch, err := gitDiff(ctx, runner, sourceRoot)
-	if err != nil {
+	if err := replayCommits(ctx, cmd, runner, destinationRoot, set.commits, dryRun); err != nil {
+		return err
+	}
+
+	if err := applyTrackedPatch(ctx, cmd, runner, sourceRoot, destinationRoot, set, dryRun, resetDestination); err != nil {
+		if len(set.commits.commits) > 0 && !dryRun {
+			// The destination was clean before the replay, so going back to
+			// the recorded HEAD drops the picked commits without losing work.
+			if _, stderr, resetErr := runner.Run(ctx, "-C", destinationRoot, "reset", "-q", "--hard", set.commits.base); resetErr != nil {
+				return fmt.Errorf("%w (rollback of replayed commits failed: %v: %s)", err, resetErr, stderr)
+			}
+		}
+		return err
+	}
+	manifest.recordCommits(set.commits.hashes())
+	manifest.recordPatch(set.patch, set.partial)
+
+	if err := applyGitlinkChanges(ctx, cmd, runner, sourceRoot, destinationRoot, set.gitlinks, dryRun, manifest); err != nil {
 		return err
 	}
 
-	patchFile, err := writeTempPatch(patch)
+	for _, rel := range set.untracked {
+		sum, err := copyFileVerified(sourceRoot, destinationRoot, rel, dryRun, cmd.OutOrStdout(), maskPaths)
+		if errors.Is(err, errUnsupportedFileType) {
+			manifest.skipped(manifestEntry{Path: rel, Kind: "special", Reason: "not a regular file or symlink"})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		kind := "file"
+		if sum == "" {
+			kind = "symlink"
+		}
+		manifest.copied(manifestEntry{Path: rel, Kind: kind, SHA256: sum})
+	}
+
+	for _, rel := range set.emptyDirs {
+		dstPath := filepath.Join(destinationRoot, filepath.FromSlash(rel))
+		if dryRun {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "mkdir %s\n", maskPathForDryRun(dstPath, maskPaths)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(dstPath, 0o755); err != nil {
+			return err
+		}
+		manifest.copied(manifestEntry{Path: rel, Kind: "dir", Reason: "empty directory"})
+	}
+
+	for _, entry := range set.skipped {
+		manifest.skipped(entry)
+	}
+	return nil
+}
+
+// cleanDestination runs `git clean -fd` and records what it removed.
+func cleanDestination(ctx context.Context, cmd *cobra.Command, runner git.Runner, destinationRoot string, dryRun bool, manifest *transferManifest) error {
+	args := []string{"-C", destinationRoot, "clean", "-fd"}
+	if dryRun {
+		return runGit(ctx, cmd, dryRun, runner, args...)
+	}
+	stdout, stderr, err := runner.Run(ctx, args...)
+	if err != nil {
+		if stderr != "" {
+			return fmt.Errorf("%s: %w: %s", formatGitCommand(args), err, stderr)
+		}
+		return fmt.Errorf("%s: %w", formatGitCommand(args), err)
+	}
+	for _, line := range strings.Split(stdout, "\n") {
+		rel, ok := strings.CutPrefix(strings.TrimSpace(line), "Removing ")
+		if !ok {
+			continue
+		}
+		manifest.removed(manifestEntry{Path: rel, Kind: "untracked", Reason: "cleaned from destination"})
+	}
+	return nil
+}
+
+func applyTrackedPatch(ctx context.Context, cmd *cobra.Command, runner git.Runner, sourceRoot, destinationRoot string, set transferSet, dryRun, resetDestination bool) error {
+	maskPaths := shouldMaskSensitivePaths(ctx)
+	patchFile, err := writeTempPatch(set.patch)
 	if err != nil {
 		return err
 	}
@@ -124,15 +259,15 @@ func transferChanges(ctx context.Context, cmd *cobra.Command, runner git.Runner,
 		}
 	}()
 
-	if patch != "" {
+	if set.patch != "" {
 		if !resetDestination {
 			if err := runGit(ctx, cmd, dryRun, runner, "-C", destinationRoot, "apply", "--check", patchFile); err != nil {
 				return &applyConflictError{reason: "apply patch check failed", err: err}
 			}
 		}
 		if err := runGit(ctx, cmd, dryRun, runner, "-C", destinationRoot, "apply", patchFile); err != nil {
-			if resetDestination {
-				if fallbackErr := syncTrackedChangesFallback(ctx, runner, sourceRoot, destinationRoot); fallbackErr != nil {
+			if resetDestination && !set.partial {
+				if fallbackErr := syncTrackedChangesFallback(ctx, runner, sourceRoot, destinationRoot, set); fallbackErr != nil {
 					return fmt.Errorf("overwrite apply patch: %w (fallback sync failed: %v)", err, fallbackErr)
 				}
 				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: overwrite apply patch failed; used tracked-file fallback sync: %v\n", err)
@@ -141,16 +276,6 @@ func transferChanges(ctx context.Context, cmd *cobra.Command, runner git.Runner,
 			}
 		}
 	}
-
-	untracked, err := listUntracked(ctx, runner, sourceRoot)
-	if err != nil {
-		return err
-	}
-	for _, rel := range untracked {
-		if err := copyFile(sourceRoot, destinationRoot, rel, dryRun, cmd.OutOrStdout(), maskPaths); err != nil {
-			return err
-		}
-	}
 	return nil
 }
 
@@ -160,25 +285,43 @@ type trackedChange struct {
 	newRel string
 }
 
-func syncTrackedChangesFallback(ctx context.Context, runner git.Runner, sourceRoot, destinationRoot string) error {
+func syncTrackedChangesFallback(ctx context.Context, runner git.Runner, sourceRoot, destinationRoot string, set transferSet) error {
 	changes, err := listTrackedChanges(ctx, runner, sourceRoot)
 	if err != nil {
 		return err
 	}
+	gitlinks := map[string]struct{}{}
+	for _, change := range set.gitlinks {
+		gitlinks[change.path] = struct{}{}
+	}
 	for _, change := range changes {
+		if _, ok := gitlinks[change.newRel]; ok {
+			continue
+		}
 		switch change.status {
 		case 'D':
+			if !set.includesTracked(change.newRel) {
+				continue
+			}
 			if err := removeTrackedPath(destinationRoot, change.newRel); err != nil {
 				return err
 			}
 		case 'R':
-			if err := removeTrackedPath(destinationRoot, change.oldRel); err != nil {
-				return err
+			if set.includesTracked(change.oldRel) {
+				if err := removeTrackedPath(destinationRoot, change.oldRel); err != nil {
+					return err
+				}
+			}
+			if !set.includesTracked(change.newRel) {
+				continue
 			}
 			if err := copyFile(sourceRoot, destinationRoot, change.newRel, false, io.Discard, false); err != nil {
 				return err
 			}
 		case 'A', 'M', 'T', 'C':
+			if !set.includesTracked(change.newRel) {
+				continue
+			}
 			if err := copyFile(sourceRoot, destinationRoot, change.newRel, false, io.Discard, false); err != nil {
 				return err
 			}
@@ -248,14 +391,23 @@ func removeTrackedPath(root, rel string) error {
 	return nil
 }
 
-func gitDiff(ctx context.Context, runner git.Runner, repoRoot string) (string, error) {
-	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "diff", "--binary", "HEAD")
+// gitDiff returns the binary diff of repoRoot against HEAD, or against the
+// given revision/pathspec arguments when provided.
+func gitDiff(ctx context.Context, runner git.Runner, repoRoot string, args ...string) (string, error) {
+	if len(args) == 0 {
+		args = []string{"HEAD"}
+	}
+	stdout, stderr, err := runner.Run(ctx, append([]string{"-C", repoRoot, "diff", "--binary"}, args...)...)
 	if err != nil {
 		if stderr != "" {
 			return "", fmt.Errorf("git diff: %w: %s", err, stderr)
 		}
 		return "", fmt.Errorf("git diff: %w", err)
 	}
+	// The runner trims output; git apply rejects a patch without its final newline.
+	if stdout != "" && !strings.HasSuffix(stdout, "\n") {
+		stdout += "\n"
+	}
 	return stdout, nil
 }
 
@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pi2pie/git-worktree-tasks/internal/backup"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+type backupsListOptions struct {
+	output string
+	abs    bool
+	grid   bool
+}
+
+type backupRow struct {
+	ID        string `json:"id"`
+	Created   string `json:"created"`
+	Reason    string `json:"reason"`
+	Task      string `json:"task"`
+	Path      string `json:"path"`
+	Branch    string `json:"branch"`
+	Head      string `json:"head"`
+	Tracked   bool   `json:"tracked_changes"`
+	Untracked int    `json:"untracked"`
+}
+
+type restoreOptions struct {
+	yes    bool
+	dryRun bool
+	force  bool
+}
+
+func newBackupsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backups",
+		Short: "Manage safety snapshots taken before destructive operations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+	cmd.AddCommand(newBackupsListCommand())
+	return cmd
+}
+
+func newBackupsListCommand() *cobra.Command {
+	opts := &backupsListOptions{output: "table"}
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List safety snapshots for this repository",
+		Aliases: []string{"ls"},
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if cfg, ok := configFromContext(ctx); ok && !cmd.Flags().Changed("grid") {
+				opts.grid = cfg.Table.Grid
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			commonDir, err := git.CommonDirAt(ctx, runner, repoRoot)
+			if err != nil {
+				return err
+			}
+			backups, err := backup.List(commonDir)
+			if err != nil {
+				return err
+			}
+			shortHashLen, err := worktree.ShortHashLength(ctx, runner, repoRoot)
+			if err != nil {
+				return err
+			}
+			rows := make([]backupRow, 0, len(backups))
+			for _, b := range backups {
+				rows = append(rows, backupRow{
+					ID:        b.ID,
+					Created:   b.Created.Format(time.RFC3339),
+					Reason:    b.Reason,
+					Task:      b.Task,
+					Path:      displayPath(repoRoot, b.Path, opts.abs),
+					Branch:    b.Branch,
+					Head:      worktree.ShortHash(b.Head, shortHashLen),
+					Tracked:   b.Stash != "",
+					Untracked: b.Untracked,
+				})
+			}
+			return renderBackups(cmd, opts.output, rows, opts.grid)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "output format: table or json")
+	cmd.Flags().BoolVar(&opts.abs, "absolute-path", false, "show absolute paths instead of relative")
+	cmd.Flags().BoolVar(&opts.abs, "abs", false, "alias for --absolute-path")
+	cmd.Flags().BoolVar(&opts.grid, "grid", false, "render table with grid borders")
+	return cmd
+}
+
+func renderBackups(cmd *cobra.Command, format string, rows []backupRow, grid bool) error {
+	switch format {
+	case "table":
+		columns := []tableColumn{
+			{Header: "ID", MinWidth: 16},
+			{Header: "CREATED", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+			{Header: "REASON", MinWidth: 6},
+			{Header: "TASK", MinWidth: 6, Flexible: true, Truncate: true},
+			{Header: "PATH", MinWidth: 16, Flexible: true, Truncate: true},
+			{Header: "BRANCH", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.AccentStyle }},
+			{Header: "HEAD", MinWidth: 7, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+			{Header: "TRACKED", MinWidth: 7},
+			{Header: "UNTRACKED", MinWidth: 9},
+		}
+		tableRows := make([][]string, 0, len(rows))
+		for _, row := range rows {
+			task := row.Task
+			if task == "" {
+				task = "-"
+			}
+			tableRows = append(tableRows, []string{
+				row.ID,
+				row.Created,
+				row.Reason,
+				task,
+				row.Path,
+				row.Branch,
+				row.Head,
+				strconv.FormatBool(row.Tracked),
+				strconv.Itoa(row.Untracked),
+			})
+		}
+		renderTable(cmd, columns, tableRows, grid)
+		return nil
+	case "json":
+		payload, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+		return err
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+func newRestoreCommand() *cobra.Command {
+	opts := &restoreOptions{}
+	cmd := &cobra.Command{
+		Use:   "restore <backup-id>",
+		Short: "Restore a checkout from a safety snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			cfg, hasCfg := configFromContext(ctx)
+			if hasCfg && !cmd.Flags().Changed("yes") {
+				opts.yes = !cfg.Cleanup.Confirm
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			commonDir, err := git.CommonDirAt(ctx, runner, repoRoot)
+			if err != nil {
+				return err
+			}
+			b, err := backup.Load(commonDir, args[0])
+			if err != nil {
+				return err
+			}
+
+			head, err := git.RevParse(ctx, runner, b.Path, "HEAD")
+			if err != nil {
+				return err
+			}
+			if head != b.Head && !opts.force {
+				return fmt.Errorf("HEAD of %s moved since backup %s (%s -> %s); rerun with --force to reset to the recorded HEAD", displayPath(repoRoot, b.Path, false), b.ID, worktree.ShortHash(b.Head, 0), worktree.ShortHash(head, 0))
+			}
+
+			maskPaths := shouldMaskSensitivePaths(ctx)
+			if opts.dryRun {
+				return printRestorePlan(cmd, b, maskPaths)
+			}
+
+			if !opts.yes {
+				ok, err := confirmPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), fmt.Sprintf("Restore %s from backup %s? Current changes there are snapshotted first.", displayPath(repoRoot, b.Path, false), b.ID))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return errCanceled
+				}
+			}
+
+			// Retention runs only once the restore is done, so it cannot
+			// delete the backup being restored.
+			if hasCfg && cfg.Backup.Enabled {
+				current, err := snapshotBackup(ctx, runner, commonDir, b.Path, "restore", b.Task)
+				if err != nil {
+					return err
+				}
+				if err := printBackupSaved(cmd, current); err != nil {
+					return err
+				}
+			}
+			if err := backup.Restore(ctx, runner, commonDir, b); err != nil {
+				return err
+			}
+			if hasCfg && cfg.Backup.Enabled {
+				pruneBackups(ctx, cmd, runner, commonDir)
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n",
+				ui.SuccessStyle.Render("restored"),
+				ui.AccentStyle.Render(b.ID),
+			)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.yes, "yes", false, "skip confirmation prompts")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show restore steps without executing")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "restore even if HEAD moved since the backup")
+	return cmd
+}
+
+func printRestorePlan(cmd *cobra.Command, b backup.Backup, maskPaths bool) error {
+	lines := []string{
+		"[destructive] " + formatGitCommandForDryRun([]string{"-C", b.Path, "reset", "-q", "--hard", b.Head}, maskPaths),
+		"[destructive] " + formatGitCommandForDryRun([]string{"-C", b.Path, "clean", "-fdq"}, maskPaths),
+	}
+	if b.Stash != "" {
+		lines = append(lines, formatGitCommandForDryRun([]string{"-C", b.Path, "stash", "apply", "--index", b.Stash}, maskPaths))
+	}
+	if b.Untracked > 0 {
+		lines = append(lines, fmt.Sprintf("extract %d untracked file(s) -> %s", b.Untracked, maskPathForDryRun(b.Path, maskPaths)))
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(cmd.OutOrStdout(), line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSafetyBackup snapshots path and applies the configured retention.
+func createSafetyBackup(ctx context.Context, cmd *cobra.Command, runner git.Runner, path, reason, task string) (backup.Backup, error) {
+	commonDir, err := git.CommonDirAt(ctx, runner, path)
+	if err != nil {
+		return backup.Backup{}, err
+	}
+	b, err := snapshotBackup(ctx, runner, commonDir, path, reason, task)
+	if err != nil {
+		return backup.Backup{}, err
+	}
+	pruneBackups(ctx, cmd, runner, commonDir)
+	return b, nil
+}
+
+// snapshotBackup snapshots path without applying retention.
+func snapshotBackup(ctx context.Context, runner git.Runner, commonDir, path, reason, task string) (backup.Backup, error) {
+	b, err := backup.Create(ctx, runner, backup.CreateOptions{
+		CommonDir: commonDir,
+		Path:      path,
+		Reason:    reason,
+		Task:      task,
+	})
+	if err != nil {
+		return backup.Backup{}, fmt.Errorf("safety backup: %w", err)
+	}
+	return b, nil
+}
+
+// pruneBackups applies the configured keep and max_age_days retention.
+func pruneBackups(ctx context.Context, cmd *cobra.Command, runner git.Runner, commonDir string) {
+	cfg, ok := configFromContext(ctx)
+	if !ok {
+		return
+	}
+	maxAge := time.Duration(cfg.Backup.MaxAgeDays) * 24 * time.Hour
+	if _, err := backup.Prune(ctx, runner, commonDir, cfg.Backup.Keep, maxAge, time.Now()); err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: failed to prune old backups: %v\n", err)
+	}
+}
+
+func printBackupSaved(cmd *cobra.Command, b backup.Backup) error {
+	_, err := fmt.Fprintf(cmd.OutOrStdout(), "%s %s %s\n",
+		ui.MutedStyle.Render("backup saved:"),
+		ui.AccentStyle.Render(b.ID),
+		ui.MutedStyle.Render(fmt.Sprintf("(restore with: gwtt restore %s)", b.ID)),
+	)
+	return err
+}
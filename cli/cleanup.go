@@ -14,9 +14,11 @@ type cleanupOptions struct {
 	removeWorktree bool
 	removeBranch   bool
 	forceBranch    bool
+	force          bool
 	worktreeOnly   bool
 	yes            bool
 	dryRun         bool
+	noRollback     bool
 }
 
 func newCleanupCommand() *cobra.Command {
@@ -28,7 +30,7 @@ func newCleanupCommand() *cobra.Command {
 		Aliases: []string{"rm"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			runner := defaultRunner()
+			runner := defaultRunner(ctx)
 			modeCtx, err := resolveModeContext(cmd, false)
 			if err != nil {
 				return err
@@ -70,8 +72,15 @@ func newCleanupCommand() *cobra.Command {
 			path := ""
 			branch := ""
 			if mode != modeCodex {
-				path = worktree.WorktreePath(repoRoot, repo, task)
-				branch = task
+				ref, err := resolveTaskRef(ctx, runner, repoRoot, task)
+				if err != nil {
+					return err
+				}
+				if preset, ok := taskPreset(ctx, ref); ok && preset.ForceBranch && !cmd.Flags().Changed("force-branch") {
+					opts.forceBranch = true
+				}
+				path = taskRefWorktreePath(ctx, repoRoot, repo, task, ref)
+				branch = ref.branch
 			}
 
 			if opts.worktreeOnly {
@@ -179,34 +188,43 @@ func newCleanupCommand() *cobra.Command {
 				}
 			}
 
+			forceRemove := false
+			if opts.removeWorktree && worktreeExists {
+				forceRemove, err = checkSubmoduleRemoval(ctx, runner, resolvedPath, opts.force)
+				if err != nil {
+					return fmt.Errorf("%w (use --force to remove it anyway)", err)
+				}
+			}
+
+			journal := newOpJournal("cleanup")
+			var deleted []deletedBranch
 			if opts.removeWorktree && worktreeExists {
 				if !opts.yes {
-					ok, err := confirmPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), "Remove worktree?")
-					if err != nil {
-						return err
-					}
-					if !ok {
-						return errCanceled
-					}
-					if mode == modeCodex {
-						if _, err := fmt.Fprintln(cmd.OutOrStdout(), ui.WarningStyle.Render("warning: codex-mode deletion cannot verify pinned/sidebar/thread linkage; restore is best-effort")); err != nil {
-							return err
-						}
-						ok, err := confirmPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), "Remove Codex worktree anyway?")
+					journal.confirm(func() error {
+						ok, err := confirmPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), "Remove worktree?")
 						if err != nil {
 							return err
 						}
 						if !ok {
 							return errCanceled
 						}
-					}
-				}
-				if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "worktree", "remove", resolvedPath); err != nil {
-					return err
-				}
-				if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "worktree", "prune"); err != nil {
-					return err
+						if mode == modeCodex {
+							if _, err := fmt.Fprintln(cmd.OutOrStdout(), ui.WarningStyle.Render("warning: codex-mode deletion cannot verify pinned/sidebar/thread linkage; restore is best-effort")); err != nil {
+								return err
+							}
+							ok, err := confirmPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), "Remove Codex worktree anyway?")
+							if err != nil {
+								return err
+							}
+							if !ok {
+								return errCanceled
+							}
+						}
+						return nil
+					})
 				}
+				journal.add(removeWorktreeStep(repoRoot, resolvedPath, forceRemove))
+				journal.git("-C", repoRoot, "worktree", "prune").idempotent = true
 			}
 
 			if opts.removeBranch {
@@ -224,27 +242,63 @@ func newCleanupCommand() *cobra.Command {
 						if !worktreeExists && opts.removeWorktree {
 							message = fmt.Sprintf("No worktree found for task %q. Remove branch %q anyway?", task, branch)
 						}
-						ok, err := confirmPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), message)
+						journal.confirm(func() error {
+							ok, err := confirmPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), message)
+							if err != nil {
+								return err
+							}
+							if !ok {
+								return errCanceled
+							}
+							return nil
+						})
+					}
+					removedPath := ""
+					if opts.removeWorktree && worktreeExists {
+						removedPath = resolvedPath
+					}
+					journal.add(recordBranchDeletion(deleteBranchStep(repoRoot, branch, opts.forceBranch), repoRoot, branch, removedPath, &deleted))
+
+					// A parked task whose branch goes would otherwise block
+					// create from reusing its name for good.
+					if mode != modeCodex {
+						wip, parked, err := parkedCommit(ctx, runner, repoRoot, task)
 						if err != nil {
 							return err
 						}
-						if !ok {
-							return errCanceled
+						if parked {
+							parkedRef := parkedRefPrefix + task
+							journal.add(&journalStep{
+								args: []string{"-C", repoRoot, "update-ref", "-d", parkedRef, wip},
+								undo: [][]string{{"-C", repoRoot, "update-ref", parkedRef, wip}},
+								done: func() {
+									if len(deleted) > 0 {
+										deleted[len(deleted)-1].Parked = wip
+									}
+								},
+							})
 						}
 					}
-					deleteFlag := "-d"
-					if opts.forceBranch {
-						deleteFlag = "-D"
-					}
-					if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "branch", deleteFlag, branch); err != nil {
-						return err
-					}
 				}
 			}
 
+			runErr := journal.run(ctx, cmd, runner, opts.dryRun, !opts.noRollback)
+			opID := ""
+			if !opts.dryRun {
+				opID = logOperation(cmd, runner, repoRoot, "cleanup", task, deleted, runErr, !opts.noRollback)
+			}
+			if runErr != nil {
+				return runErr
+			}
+
 			if _, err := fmt.Fprintln(cmd.OutOrStdout(), ui.SuccessStyle.Render("cleanup complete")); err != nil {
 				return err
 			}
+			if opID != "" && len(deleted) > 0 {
+				if _, err := fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render(fmt.Sprintf("(gwtt undo %s restores %s)", opID, branch))); err != nil {
+					return err
+				}
+			}
 			return nil
 		},
 	}
@@ -253,8 +307,10 @@ func newCleanupCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&opts.removeBranch, "remove-branch", opts.removeBranch, "remove the task branch")
 	cmd.Flags().BoolVar(&opts.worktreeOnly, "worktree-only", false, "remove only the task worktree (keep branch)")
 	cmd.Flags().BoolVar(&opts.forceBranch, "force-branch", false, "force delete branch when removing")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "remove the worktree even if it or its submodules have uncommitted or unpushed work")
 	cmd.Flags().BoolVar(&opts.yes, "yes", false, "skip confirmation prompts")
 	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
+	cmd.Flags().BoolVar(&opts.noRollback, "no-rollback", false, "on failure, stop and print resume commands instead of rolling back")
 
 	return cmd
 }
@@ -11,8 +11,24 @@ import (
 	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
 )
 
-func defaultRunner() git.Runner {
-	return git.ExecRunner{}
+type workDirKey struct{}
+
+// withWorkDir runs the commands executed with ctx from dir instead of the
+// process working directory, so ws can drive another repository without
+// os.Chdir.
+func withWorkDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, workDirKey{}, dir)
+}
+
+// workDir returns the directory set by withWorkDir, or "" for the process
+// working directory.
+func workDir(ctx context.Context) string {
+	dir, _ := ctx.Value(workDirKey{}).(string)
+	return dir
+}
+
+func defaultRunner(ctx context.Context) git.Runner {
+	return git.ExecRunner{Dir: workDir(ctx)}
 }
 
 func repoRoot(ctx context.Context, runner git.Runner) (string, error) {
@@ -114,7 +130,83 @@ func fallbackPathForBranch(ctx context.Context, runner git.Runner, repoRoot, bra
 	return path, true, nil
 }
 
-func deriveClassicTask(repoRoot, mainWorktree, repo string, wt worktree.Worktree) (string, error) {
+// taskWorktreePath returns the worktree path for task following the
+// [create.path] config, or the default layout when no config is loaded.
+func taskWorktreePath(ctx context.Context, repoRoot, repo, task string) string {
+	if cfg, ok := configFromContext(ctx); ok {
+		return worktree.TemplatePath(repoRoot, cfg.Create.Path.Root, cfg.Create.Path.Format, repo, task)
+	}
+	return worktree.WorktreePath(repoRoot, repo, task)
+}
+
+// taskWorktree is a linked task worktree as list names it: the Codex
+// opaque id in codex mode, otherwise the derived task name ("-" if none).
+type taskWorktree struct {
+	task   string
+	branch string
+	path   string
+}
+
+// listTaskWorktrees returns the linked worktrees of the current mode,
+// skipping the main checkout, bare and prunable entries.
+func listTaskWorktrees(ctx context.Context, runner git.Runner, modeCtx modeContext, repoRoot string) ([]taskWorktree, error) {
+	repo, err := git.RepoBaseName(ctx, runner)
+	if err != nil {
+		return nil, err
+	}
+	mainWorktree, err := mainWorktreePath(ctx, runner, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	worktrees, err := worktree.List(ctx, runner, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := git.BranchConfigValues(ctx, runner, repoRoot, branchTaskKey)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []taskWorktree
+	for idx, wt := range worktrees {
+		if idx == 0 || wt.Bare || wt.Prunable {
+			continue
+		}
+		wtAbs, err := worktree.NormalizePath(repoRoot, wt.Path)
+		if err != nil {
+			return nil, err
+		}
+		task := ""
+		opaqueID, _, isCodex := codexWorktreeInfo(modeCtx.codexWorktrees, wtAbs)
+		if modeCtx.mode == modeCodex {
+			if !isCodex {
+				continue
+			}
+			task = opaqueID
+		} else {
+			if isCodex {
+				continue
+			}
+			task, err = deriveClassicTask(repoRoot, mainWorktree, repo, recorded, wt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if task == "" {
+			task = "-"
+		}
+		branch := strings.TrimPrefix(wt.Branch, "refs/heads/")
+		if branch == "" {
+			branch = "detached"
+		}
+		tasks = append(tasks, taskWorktree{task: task, branch: branch, path: wtAbs})
+	}
+	return tasks, nil
+}
+
+// deriveClassicTask names a classic worktree after its path in the current
+// layout, then after the gwttTask recorded for its branch, then after the
+// branch itself.
+func deriveClassicTask(repoRoot, mainWorktree, repo string, recorded map[string]string, wt worktree.Worktree) (string, error) {
 	if task, ok := worktree.TaskFromPath(repo, wt.Path); ok && task != "" {
 		return task, nil
 	}
@@ -139,6 +231,9 @@ func deriveClassicTask(repoRoot, mainWorktree, repo string, wt worktree.Worktree
 		return "", nil
 	}
 
+	if task := recorded[branch]; task != "" {
+		return task, nil
+	}
 	return worktree.SlugifyTask(branch), nil
 }
 
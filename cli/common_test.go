@@ -136,6 +136,7 @@ func TestDeriveClassicTask(t *testing.T) {
 		name         string
 		repoRoot     string
 		mainWorktree string
+		recorded     map[string]string
 		wt           worktree.Worktree
 		want         string
 	}{
@@ -155,6 +156,15 @@ func TestDeriveClassicTask(t *testing.T) {
 			},
 			want: "new-task",
 		},
+		{
+			name:     "recorded task wins over branch",
+			recorded: map[string]string{"feat/login": "login"},
+			wt: worktree.Worktree{
+				Path:   "/tmp/old-repo_login",
+				Branch: "refs/heads/feat/login",
+			},
+			want: "login",
+		},
 		{
 			name: "fallback branch task is slugified",
 			wt: worktree.Worktree{
@@ -210,7 +220,7 @@ func TestDeriveClassicTask(t *testing.T) {
 			if mainWorktree == "" {
 				mainWorktree = defaultMainWorktree
 			}
-			got, err := deriveClassicTask(repoRoot, mainWorktree, repo, tt.wt)
+			got, err := deriveClassicTask(repoRoot, mainWorktree, repo, tt.recorded, tt.wt)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+type conflictsOptions struct {
+	target string
+	output string
+	grid   bool
+}
+
+// conflictPair is one test merge: a task against the target, or two tasks
+// against each other.
+type conflictPair struct {
+	Left        string   `json:"left"`
+	LeftBranch  string   `json:"left_branch"`
+	Right       string   `json:"right"`
+	RightBranch string   `json:"right_branch"`
+	Files       []string `json:"files"`
+}
+
+func newConflictsCommand() *cobra.Command {
+	opts := &conflictsOptions{output: "table"}
+	cmd := &cobra.Command{
+		Use:   "conflicts",
+		Short: "Predict merge conflicts between task branches and their target",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if opts.output != "table" && opts.output != "matrix" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format: %s", opts.output)
+			}
+			if cfg, ok := configFromContext(ctx); ok && !cmd.Flags().Changed("grid") {
+				opts.grid = cfg.Status.Grid
+			}
+			modeCtx, err := resolveModeContext(cmd, true)
+			if err != nil {
+				return err
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			target := opts.target
+			if target == "" {
+				target, err = git.CurrentBranch(ctx, runner)
+				if err != nil {
+					return err
+				}
+			}
+			tasks, err := listTaskWorktrees(ctx, runner, modeCtx, repoRoot)
+			if err != nil {
+				return err
+			}
+
+			var branches []taskWorktree
+			seen := map[string]bool{target: true, "detached": true}
+			for _, tw := range tasks {
+				if !seen[tw.branch] {
+					seen[tw.branch] = true
+					branches = append(branches, tw)
+				}
+			}
+			var pairs []conflictPair
+			for idx, left := range branches {
+				pairs = append(pairs, conflictPair{Left: left.task, LeftBranch: left.branch, Right: target, RightBranch: target})
+				for _, right := range branches[idx+1:] {
+					pairs = append(pairs, conflictPair{Left: left.task, LeftBranch: left.branch, Right: right.task, RightBranch: right.branch})
+				}
+			}
+			if err := testMerges(ctx, runner, repoRoot, pairs); err != nil {
+				return err
+			}
+
+			switch opts.output {
+			case "json":
+				payload, err := json.MarshalIndent(pairs, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+				return err
+			case "matrix":
+				labels := []string{target}
+				branchNames := []string{target}
+				for _, tw := range branches {
+					labels = append(labels, tw.task)
+					branchNames = append(branchNames, tw.branch)
+				}
+				renderConflictMatrix(cmd, labels, branchNames, pairs, opts.grid)
+				return nil
+			default:
+				return renderConflictTable(cmd, pairs, opts.grid)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.target, "target", "", "target branch to test task branches against (default: current branch)")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "output format: table, matrix, or json")
+	cmd.Flags().BoolVar(&opts.grid, "grid", false, "render table with grid borders")
+
+	return cmd
+}
+
+// testMerges runs the test merge of every pair concurrently and fills in
+// its conflicting files.
+func testMerges(ctx context.Context, runner git.Runner, repoRoot string, pairs []conflictPair) error {
+	errs := make([]error, len(pairs))
+	limit := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for idx := range pairs {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			files, err := git.MergeConflicts(ctx, runner, repoRoot, pairs[idx].RightBranch, pairs[idx].LeftBranch)
+			if files == nil {
+				files = []string{}
+			}
+			pairs[idx].Files, errs[idx] = files, err
+		}(idx)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderConflictTable lists the pairs that conflict.
+func renderConflictTable(cmd *cobra.Command, pairs []conflictPair, grid bool) error {
+	var rows [][]string
+	for _, pair := range pairs {
+		if len(pair.Files) > 0 {
+			rows = append(rows, []string{pair.Left, pair.Right, strconv.Itoa(len(pair.Files)), strings.Join(pair.Files, ", ")})
+		}
+	}
+	if len(rows) == 0 {
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), ui.SuccessStyle.Render(fmt.Sprintf("no conflicts in %d test merge(s)", len(pairs))))
+		return err
+	}
+	renderTable(cmd, []tableColumn{
+		{Header: "TASK", MinWidth: 6},
+		{Header: "AGAINST", MinWidth: 8},
+		{Header: "COUNT", MinWidth: 5, Style: func(value string) lipgloss.Style { return ui.ErrorStyle }},
+		{Header: "FILES", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+	}, rows, grid)
+	return nil
+}
+
+// renderConflictMatrix shows the number of conflicting files for every pair
+// of branches; the target comes first.
+func renderConflictMatrix(cmd *cobra.Command, labels, branches []string, pairs []conflictPair, grid bool) {
+	counts := map[[2]string]int{}
+	for _, pair := range pairs {
+		counts[[2]string{pair.LeftBranch, pair.RightBranch}] = len(pair.Files)
+		counts[[2]string{pair.RightBranch, pair.LeftBranch}] = len(pair.Files)
+	}
+	columns := []tableColumn{{Header: "", MinWidth: 6}}
+	cellStyle := func(value string) lipgloss.Style {
+		if value == "-" || value == "0" {
+			return ui.MutedStyle
+		}
+		return ui.ErrorStyle
+	}
+	for _, label := range labels {
+		columns = append(columns, tableColumn{Header: label, MinWidth: 3, Style: cellStyle})
+	}
+	rows := make([][]string, 0, len(labels))
+	for idx, label := range labels {
+		row := []string{label}
+		for other := range labels {
+			if other == idx {
+				row = append(row, "-")
+				continue
+			}
+			row = append(row, strconv.Itoa(counts[[2]string{branches[idx], branches[other]}]))
+		}
+		rows = append(rows, row)
+	}
+	renderTable(cmd, columns, rows, grid)
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/pi2pie/git-worktree-tasks/internal/config"
 	"github.com/pi2pie/git-worktree-tasks/internal/git"
 	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
 	"github.com/pi2pie/git-worktree-tasks/ui"
@@ -15,7 +16,13 @@ import (
 
 type createOptions struct {
 	base         string
+	on           string
 	path         string
+	preset       string
+	sparse       []string
+	noSparse     bool
+	carry        bool
+	submodules   bool
 	output       string
 	dryRun       bool
 	skipExisting bool
@@ -29,15 +36,60 @@ func newCreateCommand() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			runner := defaultRunner()
+			runner := defaultRunner(ctx)
 			if cfg, ok := configFromContext(ctx); ok && cfg.Mode == modeCodex {
 				return fmt.Errorf("create is not supported in --mode=codex yet (use Codex App to create worktrees or run with --mode=classic)")
 			}
 
+			preset := config.PresetConfig{}
+			if opts.preset != "" {
+				found, err := lookupPreset(ctx, opts.preset)
+				if err != nil {
+					return err
+				}
+				preset = found
+				if !cmd.Flags().Changed("base") && preset.Base != "" {
+					opts.base = preset.Base
+				}
+			}
+
+			if cfg, ok := configFromContext(ctx); ok {
+				if !cmd.Flags().Changed("sparse") {
+					opts.sparse = cfg.Create.Sparse.Dirs
+				}
+				if !cmd.Flags().Changed("recurse-submodules") {
+					opts.submodules = cfg.Create.RecurseSubmodules
+				}
+			}
+			if opts.noSparse {
+				if cmd.Flags().Changed("sparse") {
+					return fmt.Errorf("use either --sparse or --no-sparse, not both")
+				}
+				opts.sparse = nil
+			}
+			if opts.carry && len(opts.sparse) > 0 {
+				return fmt.Errorf("--carry needs a full checkout (use --no-sparse)")
+			}
+			sparseDirs, err := normalizeSparseDirs(opts.sparse)
+			if err != nil {
+				return err
+			}
+
 			repoRoot, err := repoRoot(ctx, runner)
 			if err != nil {
 				return err
 			}
+			parentBranch := ""
+			if opts.on != "" {
+				if cmd.Flags().Changed("base") {
+					return fmt.Errorf("use either --on or --base, not both")
+				}
+				parentBranch, err = resolveParentTask(ctx, runner, repoRoot, opts.on)
+				if err != nil {
+					return err
+				}
+				opts.base = parentBranch
+			}
 			currentBranch, err := git.CurrentBranchAt(ctx, runner, repoRoot)
 			if err != nil {
 				return err
@@ -51,7 +103,15 @@ func newCreateCommand() *cobra.Command {
 				return err
 			}
 			task := worktree.SlugifyTask(args[0])
-			path := worktree.WorktreePath(repoRoot, repo, task)
+			if _, parked, err := parkedCommit(ctx, runner, repoRoot, task); err != nil {
+				return err
+			} else if parked {
+				return fmt.Errorf("task %q is parked; run gwtt resume %s", task, task)
+			}
+			path := taskWorktreePath(ctx, repoRoot, repo, task)
+			if opts.preset != "" {
+				path = presetWorktreePath(ctx, repoRoot, repo, task, preset)
+			}
 			if opts.path != "" {
 				path = worktreePathOverride(repoRoot, opts.path)
 			}
@@ -60,6 +120,9 @@ func newCreateCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if worktreeExists && opts.carry {
+				return fmt.Errorf("--carry needs a new worktree, but %s already exists", displayPath(repoRoot, path, false))
+			}
 			if worktreeExists {
 				if opts.skipExisting {
 					branch, err := existingWorktreeBranch(ctx, runner, repoRoot, path, task)
@@ -75,16 +138,56 @@ func newCreateCommand() *cobra.Command {
 				return fmt.Errorf("worktree path already exists: %s", path)
 			}
 
-			branch := task
+			branch := preset.BranchPrefix + task
 			branchExists, err := git.BranchExists(ctx, runner, repoRoot, branch)
 			if err != nil {
 				return err
 			}
-			gitArgs := buildCreateWorktreeArgs(repoRoot, path, branch, base, branchExists)
+			parentBase := ""
+			if parentBranch != "" {
+				parentBase, err = resolveParentBase(ctx, runner, repoRoot, branch, parentBranch, branchExists)
+				if err != nil {
+					return err
+				}
+			}
+			var carried transferSet
+			if opts.carry {
+				carried, err = carryPreflight(ctx, runner, repoRoot)
+				if err != nil {
+					return err
+				}
+			}
+			gitArgs := buildCreateWorktreeArgs(repoRoot, path, branch, base, branchExists, len(sparseDirs) > 0)
 			if opts.dryRun {
 				if _, err := fmt.Fprintln(cmd.OutOrStdout(), formatGitCommandForDryRun(gitArgs, shouldMaskSensitivePaths(ctx))); err != nil {
 					return err
 				}
+				if opts.carry {
+					if err := carryChanges(ctx, cmd, runner, repoRoot, repoRoot, path, branch, branchExists, carried, true); err != nil {
+						return err
+					}
+					if err := clearCarried(ctx, cmd, runner, repoRoot, carried, true); err != nil {
+						return err
+					}
+				}
+				if len(sparseDirs) > 0 {
+					if err := setUpSparseCheckout(ctx, cmd, runner, path, sparseDirs, true); err != nil {
+						return err
+					}
+				}
+				if opts.submodules {
+					if err := runGit(ctx, cmd, true, runner, updateSubmodulesArgs(path)...); err != nil {
+						return err
+					}
+				}
+				if parentBranch != "" {
+					if err := recordTaskParent(ctx, cmd, runner, repoRoot, branch, parentBranch, parentBase, true); err != nil {
+						return err
+					}
+				}
+				if opts.preset != "" {
+					return setUpPresetWorktree(ctx, cmd, runner, repoRoot, path, branch, task, opts.preset, preset, true)
+				}
 				return nil
 			}
 
@@ -95,6 +198,34 @@ func newCreateCommand() *cobra.Command {
 				}
 				return fmt.Errorf("create worktree: %w", err)
 			}
+			if opts.carry {
+				if err := carryChanges(ctx, cmd, runner, repoRoot, repoRoot, path, branch, branchExists, carried, false); err != nil {
+					return err
+				}
+				if err := clearCarried(ctx, cmd, runner, repoRoot, carried, false); err != nil {
+					return fmt.Errorf("%w (the changes were carried but are still in %s)", err, repoRoot)
+				}
+			}
+			if len(sparseDirs) > 0 {
+				if err := setUpSparseCheckout(ctx, cmd, runner, path, sparseDirs, false); err != nil {
+					return fmt.Errorf("%w (the worktree was kept)", err)
+				}
+			}
+			if opts.submodules {
+				if err := runGit(ctx, cmd, false, runner, updateSubmodulesArgs(path)...); err != nil {
+					return fmt.Errorf("%w (the worktree was kept)", err)
+				}
+			}
+			if parentBranch != "" {
+				if err := recordTaskParent(ctx, cmd, runner, repoRoot, branch, parentBranch, parentBase, false); err != nil {
+					return fmt.Errorf("%w (the worktree was kept)", err)
+				}
+			}
+			if opts.preset != "" {
+				if err := setUpPresetWorktree(ctx, cmd, runner, repoRoot, path, branch, task, opts.preset, preset, false); err != nil {
+					return err
+				}
+			}
 
 			display := displayPath(repoRoot, path, false)
 			switch opts.output {
@@ -106,6 +237,11 @@ func newCreateCommand() *cobra.Command {
 				); err != nil {
 					return err
 				}
+				if opts.carry {
+					if _, err := fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render(fmt.Sprintf("carried %d tracked and %d untracked file(s) from %s", len(carried.tracked), len(carried.untracked), repoRoot))); err != nil {
+						return err
+					}
+				}
 			case "raw":
 				if _, err := fmt.Fprintln(cmd.OutOrStdout(), display); err != nil {
 					return err
@@ -118,7 +254,13 @@ func newCreateCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&opts.base, "base", opts.base, "base branch to create from (default: current branch)")
+	cmd.Flags().StringVar(&opts.on, "on", "", "stack the task on another task: branch from it and record it as the parent")
 	cmd.Flags().StringVarP(&opts.path, "path", "p", "", "override worktree path (relative to repo root or absolute)")
+	cmd.Flags().StringVar(&opts.preset, "preset", "", "apply a [presets.<name>] config (base, path, branch prefix, copy rules, hooks)")
+	cmd.Flags().StringSliceVar(&opts.sparse, "sparse", nil, "check out only these directories (cone-mode sparse checkout)")
+	cmd.Flags().BoolVar(&opts.noSparse, "no-sparse", false, "full checkout even when [create.sparse] sets directories")
+	cmd.Flags().BoolVar(&opts.carry, "carry", false, "move the current checkout's uncommitted changes into the new worktree")
+	cmd.Flags().BoolVar(&opts.submodules, "recurse-submodules", false, "initialize and update submodules in the new worktree")
 	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "output format: text or raw")
 	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
 	cmd.Flags().BoolVar(&opts.skipExisting, "skip-existing", false, "reuse an existing worktree path if present")
@@ -179,8 +321,11 @@ func handleExistingWorktree(cmd *cobra.Command, repoRoot, path, branch string, o
 	return nil
 }
 
-func buildCreateWorktreeArgs(repoRoot, path, branch, base string, branchExists bool) []string {
+func buildCreateWorktreeArgs(repoRoot, path, branch, base string, branchExists, noCheckout bool) []string {
 	args := []string{"-C", repoRoot, "worktree", "add"}
+	if noCheckout {
+		args = append(args, "--no-checkout")
+	}
 	if branchExists {
 		return append(args, path, branch)
 	}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// carryPreflight collects the uncommitted changes of the current checkout
+// for `create --carry` and refuses what cannot be moved safely.
+func carryPreflight(ctx context.Context, runner git.Runner, source string) (transferSet, error) {
+	unmerged, err := unmergedPaths(ctx, runner, source)
+	if err != nil {
+		return transferSet{}, err
+	}
+	if len(unmerged) > 0 {
+		return transferSet{}, fmt.Errorf("cannot carry: %s has unresolved conflicts (%s)", source, strings.Join(unmerged, ", "))
+	}
+	set, err := collectTransferSet(ctx, runner, source, transferSelection{})
+	if err != nil {
+		return transferSet{}, err
+	}
+	if len(set.gitlinks) > 0 {
+		paths := make([]string, 0, len(set.gitlinks))
+		for _, change := range set.gitlinks {
+			paths = append(paths, change.path)
+		}
+		return transferSet{}, fmt.Errorf("cannot carry submodule pointer changes (%s); commit or reset them first", strings.Join(paths, ", "))
+	}
+	if set.patch == "" && len(set.untracked) == 0 && len(set.emptyDirs) == 0 {
+		return transferSet{}, fmt.Errorf("nothing to carry: %s has no uncommitted changes", source)
+	}
+	return set, nil
+}
+
+// carryChanges moves set from source into the new worktree at path. If the
+// transfer fails, the worktree and the branch create made are removed and
+// source is left untouched.
+func carryChanges(ctx context.Context, cmd *cobra.Command, runner git.Runner, repoRoot, source, path, branch string, branchExisted bool, set transferSet, dryRun bool) error {
+	err := transferChanges(ctx, cmd, runner, source, path, set, dryRun, false, nil)
+	if err == nil || dryRun {
+		return err
+	}
+	rollback := [][]string{{"-C", repoRoot, "worktree", "remove", "--force", path}}
+	if !branchExisted {
+		rollback = append(rollback, []string{"-C", repoRoot, "branch", "-D", branch})
+	}
+	for _, args := range rollback {
+		if _, stderr, rollbackErr := runner.Run(ctx, args...); rollbackErr != nil {
+			return fmt.Errorf("carry changes: %w (rollback %s failed: %v: %s)", err, formatGitCommand(args), rollbackErr, stderr)
+		}
+	}
+	return fmt.Errorf("carry changes: %w (the new worktree was removed; %s is unchanged)", err, source)
+}
+
+// clearCarried removes the carried changes from source: tracked files go
+// back to HEAD and the copied untracked files and directories are deleted.
+func clearCarried(ctx context.Context, cmd *cobra.Command, runner git.Runner, source string, set transferSet, dryRun bool) error {
+	maskPaths := shouldMaskSensitivePaths(ctx)
+	if set.patch != "" {
+		if err := runGit(ctx, cmd, dryRun, runner, "-C", source, "reset", "-q", "--hard", "HEAD"); err != nil {
+			return err
+		}
+	}
+	removed := append(append([]string(nil), set.untracked...), set.emptyDirs...)
+	// Deepest paths first, so directories are empty by the time they go.
+	sort.Slice(removed, func(i, j int) bool { return removed[i] > removed[j] })
+	for _, rel := range removed {
+		path := filepath.Join(source, filepath.FromSlash(rel))
+		if dryRun {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "remove %s\n", maskPathForDryRun(path, maskPaths)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		removeEmptyParents(source, filepath.Dir(path))
+	}
+	return nil
+}
+
+// removeEmptyParents deletes dir and its parents up to root while they are
+// empty, as git clean would.
+func removeEmptyParents(root, dir string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,399 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pi2pie/git-worktree-tasks/internal/config"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+// annotationLenientConfig marks commands that must still run when the
+// config is invalid, so they can report the problem themselves.
+const annotationLenientConfig = "gwtt/lenient-config"
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+var pathFormatPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+type doctorOptions struct {
+	fix    bool
+	dryRun bool
+	output string
+	grid   bool
+}
+
+// doctorFinding is one detected problem. fixArgs holds the git commands
+// that repair it; findings without them are reported only.
+type doctorFinding struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Subject  string `json:"subject"`
+	Detail   string `json:"detail"`
+	Fix      string `json:"fix,omitempty"`
+	Fixed    bool   `json:"fixed"`
+
+	fixArgs [][]string
+}
+
+func newDoctorCommand() *cobra.Command {
+	opts := &doctorOptions{output: "table"}
+	cmd := &cobra.Command{
+		Use:         "doctor",
+		Short:       "Diagnose worktree, branch and config inconsistencies",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{annotationLenientConfig: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if cfg, ok := configFromContext(ctx); ok && !cmd.Flags().Changed("grid") {
+				opts.grid = cfg.Table.Grid
+			}
+			if opts.output != "table" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format: %s", opts.output)
+			}
+
+			findings := checkConfig()
+			if root, err := repoRoot(ctx, runner); err != nil {
+				findings = append(findings, doctorFinding{Severity: severityInfo, Check: "repository", Subject: ".", Detail: "not inside a git repository; worktree checks skipped"})
+			} else {
+				repoFindings, err := checkRepository(ctx, runner, root)
+				if err != nil {
+					return err
+				}
+				findings = append(findings, repoFindings...)
+			}
+			if modeCtx, err := resolveModeContext(cmd, true); err == nil && modeCtx.codexWorktrees != "" {
+				codexFindings, err := checkCodexWorktrees(modeCtx.codexWorktrees)
+				if err != nil {
+					return err
+				}
+				findings = append(findings, codexFindings...)
+			}
+			sortFindings(findings)
+
+			if opts.fix {
+				fixFindings(ctx, cmd, runner, findings, opts.dryRun)
+			}
+			if err := renderDoctor(cmd, opts, findings); err != nil {
+				return err
+			}
+
+			unresolved := 0
+			for _, finding := range findings {
+				if finding.Severity == severityError && !finding.Fixed {
+					unresolved++
+				}
+			}
+			if unresolved > 0 {
+				return fmt.Errorf("doctor found %d unresolved error(s)", unresolved)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.fix, "fix", false, "repair problems that have a safe fix")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "with --fix, show repair commands without executing")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "output format: table or json")
+	cmd.Flags().BoolVar(&opts.grid, "grid", false, "render table with grid borders")
+	return cmd
+}
+
+// checkConfig reloads the config files on its own: the command context only
+// holds the lenient fallback used to get this far.
+func checkConfig() []doctorFinding {
+	cfg, err := config.Load()
+	if err != nil {
+		return []doctorFinding{{Severity: severityError, Check: "config", Subject: "load", Detail: err.Error()}}
+	}
+	var findings []doctorFinding
+	if _, err := normalizeMode(cfg.Mode); err != nil {
+		findings = append(findings, doctorFinding{Severity: severityError, Check: "config", Subject: "mode", Detail: err.Error()})
+	}
+	if err := validateThemeName(cfg.Theme.Name); err != nil {
+		findings = append(findings, doctorFinding{Severity: severityError, Check: "config", Subject: "theme.name", Detail: err.Error()})
+	}
+	findings = append(findings, checkCreatePath("create.path", cfg.Create.Path)...)
+	if err := validateFinishChecks(cfg.Finish.Checks); err != nil {
+		findings = append(findings, doctorFinding{Severity: severityError, Check: "config", Subject: "finish.checks", Detail: err.Error()})
+	}
+	if err := validateFinishPolicy(cfg.Finish.Policy); err != nil {
+		findings = append(findings, doctorFinding{Severity: severityError, Check: "config", Subject: "finish.policy", Detail: err.Error()})
+	}
+	names := make([]string, 0, len(cfg.Presets))
+	for name := range cfg.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		preset := cfg.Presets[name]
+		if err := applyMergeMode(&finishOptions{}, preset.MergeMode); err != nil {
+			findings = append(findings, doctorFinding{Severity: severityError, Check: "config", Subject: "presets." + name + ".merge_mode", Detail: err.Error()})
+		}
+		findings = append(findings, checkCreatePath("presets."+name+".path", preset.Path)...)
+	}
+	return findings
+}
+
+func validateThemeName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	for _, known := range ui.ThemeNames() {
+		if strings.EqualFold(known, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(ui.ThemeNames(), ", "))
+}
+
+func checkCreatePath(section string, path config.CreatePathConfig) []doctorFinding {
+	var findings []doctorFinding
+	for _, placeholder := range pathFormatPlaceholder.FindAllString(path.Format, -1) {
+		if placeholder != "{repo}" && placeholder != "{task}" {
+			findings = append(findings, doctorFinding{Severity: severityError, Check: "config", Subject: section + ".format", Detail: fmt.Sprintf("unknown placeholder %s (use {repo} and {task})", placeholder)})
+		}
+	}
+	if path.Format != "" && !strings.Contains(path.Format, "{task}") {
+		findings = append(findings, doctorFinding{Severity: severityWarning, Check: "config", Subject: section + ".format", Detail: "format has no {task}; task names cannot be derived from paths"})
+	}
+	if strings.ContainsAny(path.Format, `/\`) {
+		findings = append(findings, doctorFinding{Severity: severityWarning, Check: "config", Subject: section + ".format", Detail: "format contains a path separator; use " + section + ".root for the parent directory"})
+	}
+	if path.Root != "" && filepath.IsAbs(path.Root) {
+		if info, err := os.Stat(path.Root); err != nil || !info.IsDir() {
+			findings = append(findings, doctorFinding{Severity: severityWarning, Check: "config", Subject: section + ".root", Detail: fmt.Sprintf("%s is not an existing directory", path.Root)})
+		}
+	}
+	return findings
+}
+
+func checkRepository(ctx context.Context, runner git.Runner, repoRoot string) ([]doctorFinding, error) {
+	worktrees, err := worktree.List(ctx, runner, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	prune := []string{"-C", repoRoot, "worktree", "prune"}
+	var findings []doctorFinding
+	branchPaths := map[string][]string{}
+	for idx, wt := range worktrees {
+		subject := displayPath(repoRoot, wt.Path, false)
+		if wt.Branch != "" {
+			branchPaths[strings.TrimPrefix(wt.Branch, "refs/heads/")] = append(branchPaths[strings.TrimPrefix(wt.Branch, "refs/heads/")], subject)
+		}
+		switch {
+		case wt.Prunable && wt.Locked:
+			findings = append(findings, doctorFinding{Severity: severityWarning, Check: "worktree", Subject: subject, Detail: "directory missing but the worktree is locked; unlock it to allow pruning"})
+			continue
+		case wt.Prunable:
+			findings = append(findings, doctorFinding{Severity: severityWarning, Check: "worktree", Subject: subject, Detail: "directory missing; stale .git/worktrees entry", Fix: "git worktree prune", fixArgs: [][]string{prune}})
+			continue
+		case wt.Locked:
+			findings = append(findings, doctorFinding{Severity: severityInfo, Check: "worktree", Subject: subject, Detail: "locked"})
+		}
+		if idx == 0 || wt.Bare {
+			continue
+		}
+		if detail := checkWorktreeLink(wt.Path); detail != "" {
+			repair := []string{"-C", repoRoot, "worktree", "repair", wt.Path}
+			findings = append(findings, doctorFinding{Severity: severityError, Check: "worktree", Subject: subject, Detail: detail, Fix: "git worktree repair " + subject, fixArgs: [][]string{repair}})
+		}
+	}
+
+	branches := make([]string, 0, len(branchPaths))
+	for branch := range branchPaths {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	for _, branch := range branches {
+		paths := branchPaths[branch]
+		if len(paths) < 2 {
+			continue
+		}
+		findings = append(findings, doctorFinding{Severity: severityError, Check: "branch", Subject: branch, Detail: "checked out in several worktrees: " + strings.Join(paths, ", ")})
+	}
+	return findings, nil
+}
+
+// checkWorktreeLink verifies that a linked worktree's .git file points at an
+// existing admin directory; `git worktree repair` rewrites broken links.
+func checkWorktreeLink(path string) string {
+	gitdir, err := readGitdirLink(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "worktree directory has no .git file"
+		}
+		return err.Error()
+	}
+	if _, err := os.Stat(gitdir); err != nil {
+		return fmt.Sprintf(".git file points at missing %s", gitdir)
+	}
+	return ""
+}
+
+func readGitdirLink(path string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(path, ".git"))
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(data))
+	gitdir, ok := strings.CutPrefix(line, "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("unexpected .git file contents in %s", path)
+	}
+	gitdir = strings.TrimSpace(gitdir)
+	if !filepath.IsAbs(gitdir) {
+		gitdir = filepath.Join(path, gitdir)
+	}
+	return filepath.Clean(gitdir), nil
+}
+
+// checkCodexWorktrees reports Codex worktrees whose repository is gone.
+// Removing them is left to the user since Codex may still reference them.
+func checkCodexWorktrees(root string) ([]doctorFinding, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var findings []doctorFinding
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		children, err := os.ReadDir(filepath.Join(root, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if !child.IsDir() {
+				continue
+			}
+			path := filepath.Join(root, entry.Name(), child.Name())
+			gitdir, err := readGitdirLink(path)
+			if err != nil {
+				continue
+			}
+			if _, err := os.Stat(gitdir); err == nil {
+				continue
+			}
+			findings = append(findings, doctorFinding{
+				Severity: severityWarning,
+				Check:    "codex",
+				Subject:  filepath.Join(entry.Name(), child.Name()),
+				Detail:   fmt.Sprintf("points at missing repository %s; remove the directory if Codex no longer needs it", gitdir),
+			})
+		}
+	}
+	return findings, nil
+}
+
+func sortFindings(findings []doctorFinding) {
+	rank := map[string]int{severityError: 0, severityWarning: 1, severityInfo: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return rank[findings[i].Severity] < rank[findings[j].Severity]
+	})
+}
+
+// fixFindings runs each distinct repair command once and marks every
+// finding it covers as fixed.
+func fixFindings(ctx context.Context, cmd *cobra.Command, runner git.Runner, findings []doctorFinding, dryRun bool) {
+	done := map[string]bool{}
+	for idx := range findings {
+		if len(findings[idx].fixArgs) == 0 {
+			continue
+		}
+		ok := true
+		for _, args := range findings[idx].fixArgs {
+			key := strings.Join(args, "\x00")
+			if succeeded, seen := done[key]; seen {
+				ok = ok && succeeded
+				continue
+			}
+			err := runGit(ctx, cmd, dryRun, runner, args...)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+			}
+			done[key] = err == nil
+			ok = ok && err == nil
+		}
+		findings[idx].Fixed = ok && !dryRun
+	}
+}
+
+func renderDoctor(cmd *cobra.Command, opts *doctorOptions, findings []doctorFinding) error {
+	if opts.output == "json" {
+		if findings == nil {
+			findings = []doctorFinding{}
+		}
+		payload, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+		return err
+	}
+
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), ui.SuccessStyle.Render("no problems found"))
+		return err
+	}
+	columns := []tableColumn{
+		{Header: "SEVERITY", MinWidth: 8, Style: severityStyle},
+		{Header: "CHECK", MinWidth: 6},
+		{Header: "SUBJECT", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.AccentStyle }},
+		{Header: "DETAIL", MinWidth: 20, Flexible: true, Truncate: true},
+		{Header: "FIX", MinWidth: 6, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+	}
+	rows := make([][]string, 0, len(findings))
+	counts := map[string]int{}
+	fixed := 0
+	for _, finding := range findings {
+		counts[finding.Severity]++
+		fix := finding.Fix
+		switch {
+		case finding.Fixed:
+			fixed++
+			fix = "fixed"
+		case fix == "":
+			fix = "-"
+		}
+		rows = append(rows, []string{finding.Severity, finding.Check, finding.Subject, finding.Detail, fix})
+	}
+	renderTable(cmd, columns, rows, opts.grid)
+	summary := fmt.Sprintf("%d error(s), %d warning(s), %d info", counts[severityError], counts[severityWarning], counts[severityInfo])
+	if opts.fix {
+		summary += fmt.Sprintf("; fixed %d", fixed)
+	}
+	_, err := fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render(summary))
+	return err
+}
+
+func severityStyle(value string) lipgloss.Style {
+	switch value {
+	case severityError:
+		return ui.ErrorStyle
+	case severityWarning:
+		return ui.WarningStyle
+	default:
+		return ui.MutedStyle
+	}
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/config"
+)
+
+func TestCheckCreatePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     config.CreatePathConfig
+		subjects []string
+		severity []string
+	}{
+		{name: "default", path: config.CreatePathConfig{Format: "{repo}_{task}"}},
+		{name: "unknown placeholder", path: config.CreatePathConfig{Format: "{repo}_{task}_{date}"}, subjects: []string{"create.path.format"}, severity: []string{severityError}},
+		{name: "missing task", path: config.CreatePathConfig{Format: "{repo}"}, subjects: []string{"create.path.format"}, severity: []string{severityWarning}},
+		{name: "separator", path: config.CreatePathConfig{Format: "{repo}/{task}"}, subjects: []string{"create.path.format"}, severity: []string{severityWarning}},
+		{name: "missing root", path: config.CreatePathConfig{Format: "{task}", Root: "/nonexistent/gwtt-root"}, subjects: []string{"create.path.root"}, severity: []string{severityWarning}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := checkCreatePath("create.path", tt.path)
+			if len(findings) != len(tt.subjects) {
+				t.Fatalf("findings = %+v, want %d", findings, len(tt.subjects))
+			}
+			for i, finding := range findings {
+				if finding.Subject != tt.subjects[i] || finding.Severity != tt.severity[i] {
+					t.Fatalf("finding %d = %+v, want %s/%s", i, finding, tt.subjects[i], tt.severity[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateThemeName(t *testing.T) {
+	if err := validateThemeName(""); err != nil {
+		t.Fatalf("empty theme: %v", err)
+	}
+	if err := validateThemeName("DEFAULT"); err != nil {
+		t.Fatalf("case-insensitive theme: %v", err)
+	}
+	if err := validateThemeName("no-such-theme"); err == nil {
+		t.Fatalf("expected unknown theme error")
+	}
+}
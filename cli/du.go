@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+type duOptions struct {
+	output     string
+	sort       string
+	olderThan  string
+	largerThan string
+	abs        bool
+	grid       bool
+}
+
+type duRow struct {
+	Task         string `json:"task"`
+	Branch       string `json:"branch"`
+	Path         string `json:"path"`
+	SizeBytes    int64  `json:"size_bytes"`
+	LastActivity string `json:"last_activity"`
+	lastActivity time.Time
+}
+
+func newDuCommand() *cobra.Command {
+	opts := &duOptions{output: "table", sort: "size"}
+	cmd := &cobra.Command{
+		Use:   "du",
+		Short: "Show disk usage and last activity of task worktrees",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			modeCtx, err := resolveModeContext(cmd, true)
+			if err != nil {
+				return err
+			}
+			if cfg, ok := configFromContext(ctx); ok && !cmd.Flags().Changed("grid") {
+				opts.grid = cfg.Status.Grid
+			}
+			var olderThan time.Duration
+			if opts.olderThan != "" {
+				olderThan, err = parseAge(opts.olderThan)
+				if err != nil {
+					return err
+				}
+			}
+			var largerThan int64
+			if opts.largerThan != "" {
+				largerThan, err = parseSize(opts.largerThan)
+				if err != nil {
+					return err
+				}
+			}
+			if opts.sort != "size" && opts.sort != "age" && opts.sort != "task" {
+				return fmt.Errorf("unsupported sort %q (use size, age or task)", opts.sort)
+			}
+
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			tasks, err := listTaskWorktrees(ctx, runner, modeCtx, repoRoot)
+			if err != nil {
+				return err
+			}
+
+			rows := make([]duRow, 0, len(tasks))
+			paths := make([]string, 0, len(tasks))
+			for _, tw := range tasks {
+				rows = append(rows, duRow{
+					Task:   tw.task,
+					Branch: tw.branch,
+					Path:   displayPathForMode(repoRoot, tw.path, opts.abs, modeCtx.mode, modeCtx.codexHome),
+				})
+				paths = append(paths, tw.path)
+			}
+
+			usages, err := collectUsage(ctx, runner, paths)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			filtered := rows[:0]
+			for idx, row := range rows {
+				usage := usages[idx]
+				if largerThan > 0 && usage.Bytes <= largerThan {
+					continue
+				}
+				if olderThan > 0 && now.Sub(usage.LastActivity) <= olderThan {
+					continue
+				}
+				row.SizeBytes = usage.Bytes
+				row.lastActivity = usage.LastActivity
+				row.LastActivity = usage.LastActivity.UTC().Format(time.RFC3339)
+				filtered = append(filtered, row)
+			}
+			sortDuRows(filtered, opts.sort)
+			return renderDu(cmd, opts.output, filtered, opts.grid, now)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "output format: table, json, csv, or raw (task names)")
+	cmd.Flags().StringVar(&opts.sort, "sort", opts.sort, "sort by size (largest first), age (oldest first) or task")
+	cmd.Flags().StringVar(&opts.olderThan, "older-than", "", "only worktrees idle for longer than this (e.g. 30d, 2w, 12h)")
+	cmd.Flags().StringVar(&opts.largerThan, "larger-than", "", "only worktrees larger than this (e.g. 500M, 2G)")
+	cmd.Flags().BoolVar(&opts.abs, "absolute-path", false, "show absolute paths instead of relative")
+	cmd.Flags().BoolVar(&opts.abs, "abs", false, "alias for --absolute-path")
+	cmd.Flags().BoolVar(&opts.grid, "grid", false, "render table with grid borders")
+
+	return cmd
+}
+
+// collectUsage measures paths concurrently; results keep the order of paths.
+func collectUsage(ctx context.Context, runner git.Runner, paths []string) ([]worktree.UsageInfo, error) {
+	usages := make([]worktree.UsageInfo, len(paths))
+	errs := make([]error, len(paths))
+	limit := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for idx, path := range paths {
+		wg.Add(1)
+		go func(idx int, path string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			usages[idx], errs[idx] = worktree.Usage(ctx, runner, path)
+		}(idx, path)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return usages, nil
+}
+
+func sortDuRows(rows []duRow, by string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		switch by {
+		case "age":
+			return rows[i].lastActivity.Before(rows[j].lastActivity)
+		case "task":
+			return rows[i].Task < rows[j].Task
+		default:
+			return rows[i].SizeBytes > rows[j].SizeBytes
+		}
+	})
+}
+
+func renderDu(cmd *cobra.Command, format string, rows []duRow, grid bool, now time.Time) error {
+	switch format {
+	case "table":
+		tableRows := make([][]string, 0, len(rows))
+		var total int64
+		for _, row := range rows {
+			tableRows = append(tableRows, []string{row.Task, row.Branch, row.Path, formatSize(row.SizeBytes), row.LastActivity, formatAge(now.Sub(row.lastActivity))})
+			total += row.SizeBytes
+		}
+		renderTable(cmd, duColumns(), tableRows, grid)
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render(fmt.Sprintf("total: %s in %d worktree(s)", formatSize(total), len(rows))))
+		return err
+	case "json":
+		if rows == nil {
+			rows = []duRow{}
+		}
+		payload, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+		return err
+	case "csv":
+		writer := csv.NewWriter(cmd.OutOrStdout())
+		if err := writer.Write([]string{"task", "branch", "path", "size_bytes", "last_activity"}); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := writer.Write([]string{row.Task, row.Branch, row.Path, strconv.FormatInt(row.SizeBytes, 10), row.LastActivity}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case "raw":
+		for _, row := range rows {
+			if row.Task == "-" {
+				continue
+			}
+			if _, err := fmt.Fprintln(cmd.OutOrStdout(), row.Task); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+func duColumns() []tableColumn {
+	return []tableColumn{
+		{Header: "TASK", MinWidth: 6},
+		{Header: "BRANCH", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.AccentStyle }},
+		{Header: "PATH", MinWidth: 16, Flexible: true, Truncate: true},
+		{Header: "SIZE", MinWidth: 6},
+		{Header: "LAST_ACTIVITY", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+		{Header: "AGE", MinWidth: 4, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+	}
+}
+
+var sizeUnits = []string{"B", "K", "M", "G", "T"}
+
+// formatSize renders bytes with binary units, e.g. 1.5G.
+func formatSize(bytes int64) string {
+	value := float64(bytes)
+	unit := 0
+	for value >= 1024 && unit < len(sizeUnits)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	return fmt.Sprintf("%.1f%s", value, sizeUnits[unit])
+}
+
+// parseSize accepts a byte count with an optional binary unit: 500M, 2G,
+// 1.5GiB or 4096.
+func parseSize(raw string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(raw))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "IB"), "B")
+	multiplier := int64(1)
+	for idx := len(sizeUnits) - 1; idx > 0; idx-- {
+		if trimmed, ok := strings.CutSuffix(value, sizeUnits[idx]); ok {
+			value = trimmed
+			multiplier = int64(1) << (10 * idx)
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500M or 2G)", raw)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// parseAge accepts Go durations plus d (days) and w (weeks): 30d, 2w, 12h.
+func parseAge(raw string) (time.Duration, error) {
+	value := strings.TrimSpace(raw)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if trimmed, ok := strings.CutSuffix(value, suffix); ok {
+			number, err := strconv.ParseFloat(trimmed, 64)
+			if err != nil || number < 0 {
+				return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w or 12h)", raw)
+			}
+			return time.Duration(number * float64(unit)), nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w or 12h)", raw)
+	}
+	return age, nil
+}
+
+// formatAge renders the largest whole unit of an age: 12d, 5h, 40m.
+func formatAge(age time.Duration) string {
+	switch {
+	case age >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(age/(24*time.Hour)))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh", int(age/time.Hour))
+	case age >= time.Minute:
+		return fmt.Sprintf("%dm", int(age/time.Minute))
+	default:
+		return "now"
+	}
+}
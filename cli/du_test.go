@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{input: "4096", want: 4096},
+		{input: "10B", want: 10},
+		{input: "500M", want: 500 << 20},
+		{input: "2g", want: 2 << 30},
+		{input: "1.5GiB", want: 3 << 29},
+		{input: "64KB", want: 64 << 10},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.input)
+		if err != nil {
+			t.Fatalf("parseSize(%q) error = %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Fatalf("parseSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+	for _, input := range []string{"", "big", "-1M", "12X"} {
+		if _, err := parseSize(input); err == nil {
+			t.Fatalf("parseSize(%q) expected error", input)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{input: "30d", want: 30 * 24 * time.Hour},
+		{input: "2w", want: 14 * 24 * time.Hour},
+		{input: "12h", want: 12 * time.Hour},
+		{input: "90m", want: 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.input)
+		if err != nil {
+			t.Fatalf("parseAge(%q) error = %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Fatalf("parseAge(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+	for _, input := range []string{"", "soon", "-3d"} {
+		if _, err := parseAge(input); err == nil {
+			t.Fatalf("parseAge(%q) expected error", input)
+		}
+	}
+}
+
+func TestFormatSizeAndAge(t *testing.T) {
+	if got := formatSize(512); got != "512B" {
+		t.Fatalf("formatSize(512) = %q", got)
+	}
+	if got := formatSize(3 << 29); got != "1.5G" {
+		t.Fatalf("formatSize(1.5G) = %q", got)
+	}
+	if got := formatAge(50 * time.Hour); got != "2d" {
+		t.Fatalf("formatAge(50h) = %q", got)
+	}
+	if got := formatAge(10 * time.Second); got != "now" {
+		t.Fatalf("formatAge(10s) = %q", got)
+	}
+}
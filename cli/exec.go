@@ -0,0 +1,331 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+type execOptions struct {
+	all    bool
+	tasks  []string
+	branch []string
+	dirty  bool
+	ahead  bool
+	target string
+	strict bool
+	jobs   int
+	prefix bool
+	output string
+	grid   bool
+}
+
+type execResult struct {
+	Task       string `json:"task"`
+	Branch     string `json:"branch"`
+	Path       string `json:"path"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Output     string `json:"output,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func newExecCommand() *cobra.Command {
+	opts := &execOptions{jobs: runtime.NumCPU(), output: "text"}
+	cmd := &cobra.Command{
+		Use:   "exec [--all | --task <q>... | --dirty | --ahead] -- <cmd> [args...]",
+		Short: "Run a command in every matching task worktree",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() < 0 || len(args) == 0 {
+				return fmt.Errorf("pass the command after --, e.g. gwtt exec --all -- git pull")
+			}
+			if cmd.ArgsLenAtDash() > 0 {
+				return fmt.Errorf("unexpected arguments before --: %s", strings.Join(args[:cmd.ArgsLenAtDash()], " "))
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if !opts.all && len(opts.tasks) == 0 && len(opts.branch) == 0 && !opts.dirty && !opts.ahead {
+				return fmt.Errorf("select worktrees with --all, --task, --branch, --dirty or --ahead")
+			}
+			if opts.all && (len(opts.tasks) > 0 || len(opts.branch) > 0) {
+				return fmt.Errorf("use either --all or --task/--branch, not both")
+			}
+			if opts.jobs < 1 {
+				return fmt.Errorf("--jobs must be at least 1")
+			}
+			if opts.output != "text" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format: %s", opts.output)
+			}
+			modeCtx, err := resolveModeContext(cmd, true)
+			if err != nil {
+				return err
+			}
+			if cfg, ok := configFromContext(ctx); ok && !cmd.Flags().Changed("grid") {
+				opts.grid = cfg.Status.Grid
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			targets, err := selectExecTargets(cmd, runner, modeCtx, repoRoot, opts)
+			if err != nil {
+				return err
+			}
+			if len(targets) == 0 {
+				return fmt.Errorf("no task worktrees match")
+			}
+
+			results := runExec(cmd, targets, args, opts)
+			for idx := range results {
+				results[idx].Path = displayPathForMode(repoRoot, targets[idx].path, false, modeCtx.mode, modeCtx.codexHome)
+			}
+			if opts.output == "json" {
+				payload, err := json.MarshalIndent(results, "", "  ")
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintln(cmd.OutOrStdout(), string(payload)); err != nil {
+					return err
+				}
+			} else if err := renderExecSummary(cmd, results, opts.grid); err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, result := range results {
+				if result.ExitCode != 0 {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d command(s) failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.all, "all", false, "run in every task worktree")
+	cmd.Flags().StringArrayVar(&opts.tasks, "task", nil, "run in worktrees matching this task (repeatable)")
+	cmd.Flags().StringArrayVar(&opts.branch, "branch", nil, "run in worktrees on this branch (repeatable)")
+	cmd.Flags().BoolVar(&opts.dirty, "dirty", false, "only worktrees with uncommitted changes")
+	cmd.Flags().BoolVar(&opts.ahead, "ahead", false, "only worktrees with commits not in --target")
+	cmd.Flags().StringVar(&opts.target, "target", "", "target branch for --ahead (default: current branch)")
+	cmd.Flags().BoolVar(&opts.strict, "strict", false, "require exact task match (after trimming and slugifying)")
+	cmd.Flags().IntVarP(&opts.jobs, "jobs", "j", opts.jobs, "maximum commands to run at once")
+	cmd.Flags().BoolVar(&opts.prefix, "prefix", false, "stream output with a [task] prefix instead of grouping it per task")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "output format: text or json")
+	cmd.Flags().BoolVar(&opts.grid, "grid", false, "render the summary table with grid borders")
+
+	return cmd
+}
+
+// selectExecTargets applies the task and branch filters the way list does,
+// then the --dirty and --ahead status filters.
+func selectExecTargets(cmd *cobra.Command, runner git.Runner, modeCtx modeContext, repoRoot string, opts *execOptions) ([]taskWorktree, error) {
+	ctx := cmd.Context()
+	tasks, err := listTaskWorktrees(ctx, runner, modeCtx, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	queries := make([]string, 0, len(opts.tasks))
+	for _, raw := range opts.tasks {
+		query := strings.TrimSpace(raw)
+		if modeCtx.mode != modeCodex {
+			query, err = normalizeTaskQuery(raw)
+		}
+		if err != nil || query == "" {
+			return nil, fmt.Errorf("task query cannot be empty")
+		}
+		queries = append(queries, query)
+	}
+	target := opts.target
+	if opts.ahead && target == "" {
+		target, err = git.CurrentBranch(ctx, runner)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var selected []taskWorktree
+	for _, tw := range tasks {
+		if len(queries) > 0 || len(opts.branch) > 0 {
+			matched := false
+			for _, query := range queries {
+				if matchesTask(tw.task, query, opts.strict) {
+					matched = true
+				}
+			}
+			for _, branch := range opts.branch {
+				if tw.branch == branch {
+					matched = true
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		if opts.dirty || opts.ahead {
+			info, err := worktree.Status(ctx, runner, tw.path, target)
+			if err != nil {
+				return nil, err
+			}
+			if opts.dirty && !info.Dirty {
+				continue
+			}
+			if opts.ahead && info.Ahead == 0 {
+				continue
+			}
+		}
+		selected = append(selected, tw)
+	}
+	return selected, nil
+}
+
+// runExec runs args in every target with at most opts.jobs at once. Output
+// is grouped per task once it finishes, streamed with a [task] prefix, or
+// kept for the JSON report.
+func runExec(cmd *cobra.Command, targets []taskWorktree, args []string, opts *execOptions) []execResult {
+	results := make([]execResult, len(targets))
+	limit := make(chan struct{}, opts.jobs)
+	var outputMu sync.Mutex
+	var wg sync.WaitGroup
+	for idx, target := range targets {
+		wg.Add(1)
+		go func(idx int, target taskWorktree) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			var buf bytes.Buffer
+			var stdout, stderr io.Writer = &buf, &buf
+			var stdoutPrefix, stderrPrefix *prefixWriter
+			if opts.prefix && opts.output == "text" {
+				prefix := ui.AccentStyle.Render("["+target.task+"]") + " "
+				stdoutPrefix = &prefixWriter{mu: &outputMu, out: cmd.OutOrStdout(), prefix: prefix}
+				stderrPrefix = &prefixWriter{mu: &outputMu, out: cmd.ErrOrStderr(), prefix: prefix}
+				stdout, stderr = stdoutPrefix, stderrPrefix
+			}
+
+			child := exec.CommandContext(cmd.Context(), args[0], args[1:]...)
+			child.Dir = target.path
+			child.Env = append(os.Environ(), "GWTT_TASK="+target.task, "GWTT_BRANCH="+target.branch, "GWTT_WORKTREE="+target.path)
+			child.Stdout = stdout
+			child.Stderr = stderr
+			started := time.Now()
+			err := child.Run()
+			result := execResult{Task: target.task, Branch: target.branch, DurationMS: time.Since(started).Milliseconds()}
+			if err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+					result.ExitCode = exitErr.ExitCode()
+				} else {
+					result.ExitCode = -1
+					result.Error = err.Error()
+				}
+			}
+			if stdoutPrefix != nil {
+				stdoutPrefix.flush()
+				stderrPrefix.flush()
+			}
+
+			switch {
+			case opts.output == "json":
+				result.Output = buf.String()
+			case !opts.prefix:
+				outputMu.Lock()
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", ui.AccentStyle.Render("==> "+target.task), ui.MutedStyle.Render("("+target.branch+")"))
+				_, _ = cmd.OutOrStdout().Write(buf.Bytes())
+				if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+					_, _ = fmt.Fprintln(cmd.OutOrStdout())
+				}
+				if result.Error != "" {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s: %s\n", target.task, result.Error)
+				}
+				outputMu.Unlock()
+			}
+			results[idx] = result
+		}(idx, target)
+	}
+	wg.Wait()
+	return results
+}
+
+func renderExecSummary(cmd *cobra.Command, results []execResult, grid bool) error {
+	if _, err := fmt.Fprintln(cmd.OutOrStdout()); err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		exitCode := strconv.Itoa(result.ExitCode)
+		if result.Error != "" {
+			exitCode = "error"
+		}
+		duration := (time.Duration(result.DurationMS) * time.Millisecond).String()
+		rows = append(rows, []string{result.Task, result.Branch, exitCode, duration})
+	}
+	renderTable(cmd, []tableColumn{
+		{Header: "TASK", MinWidth: 6},
+		{Header: "BRANCH", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.AccentStyle }},
+		{Header: "EXIT", MinWidth: 4, Style: func(value string) lipgloss.Style {
+			if value != "0" {
+				return ui.ErrorStyle
+			}
+			return ui.SuccessStyle
+		}},
+		{Header: "DURATION", MinWidth: 8, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+	}, rows, grid)
+	return nil
+}
+
+// prefixWriter writes complete lines to out, each starting with prefix.
+// Writers sharing mu never interleave within a line.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			return len(p), nil
+		}
+		w.writeLine(w.buf[:idx+1])
+		w.buf = w.buf[idx+1:]
+	}
+}
+
+func (w *prefixWriter) flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = io.WriteString(w.out, w.prefix)
+	_, _ = w.out.Write(line)
+}
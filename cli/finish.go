@@ -1,9 +1,12 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/pi2pie/git-worktree-tasks/internal/config"
 	"github.com/pi2pie/git-worktree-tasks/internal/git"
 	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
 	"github.com/pi2pie/git-worktree-tasks/ui"
@@ -21,21 +24,107 @@ type finishOptions struct {
 	rebase         bool
 	yes            bool
 	dryRun         bool
+	noRollback     bool
+	allReady       bool
+	check          string
+	resume         bool
+	abort          bool
+	skipChecks     string
+	overridePolicy string
+	output         string
+}
+
+// finishReport is the result of finishing one task, printed by -o json.
+type finishReport struct {
+	Task          string              `json:"task"`
+	Branch        string              `json:"branch"`
+	Target        string              `json:"target"`
+	Merged        bool                `json:"merged"`
+	DryRun        bool                `json:"dry_run,omitempty"`
+	Plan          []string            `json:"plan,omitempty"`
+	Checks        []finishCheckResult `json:"checks,omitempty"`
+	ChecksSkipped string              `json:"checks_skipped,omitempty"`
+	Operation     string              `json:"operation,omitempty"`
+	Policy        *policyOverride     `json:"policy_override,omitempty"`
+	Error         string              `json:"error,omitempty"`
 }
 
 func newFinishCommand() *cobra.Command {
-	opts := &finishOptions{}
+	opts := &finishOptions{output: "text"}
 	cmd := &cobra.Command{
-		Use:   "finish <task>",
-		Short: "Merge a task branch into a target branch",
-		Args:  cobra.ExactArgs(1),
+		Use:   "finish <task>... | --all-ready | --continue | --abort",
+		Short: "Merge task branches into a target branch",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			runner := defaultRunner()
+			runner := defaultRunner(ctx)
 			if cfg, ok := configFromContext(ctx); ok && cfg.Mode == modeCodex {
 				return fmt.Errorf("finish is not supported in --mode=codex (use gwtt apply or run with --mode=classic)")
 			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			statePath, err := finishQueueStatePath(ctx, runner, repoRoot)
+			if err != nil {
+				return err
+			}
+			state, inProgress, err := loadFinishQueue(statePath)
+			if err != nil {
+				return err
+			}
+			resumed := opts.resume || opts.abort
+			if resumed {
+				if opts.resume && opts.abort {
+					return fmt.Errorf("choose only one of --continue or --abort")
+				}
+				if len(args) > 0 || opts.allReady {
+					return fmt.Errorf("--continue and --abort do not take tasks")
+				}
+				if !inProgress {
+					return fmt.Errorf("no finish queue in progress")
+				}
+				if opts.abort {
+					if err := os.Remove(statePath); err != nil {
+						return err
+					}
+					_, err := fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", ui.WarningStyle.Render("discarded finish queue:"), strings.Join(state.Tasks, ", "))
+					return err
+				}
+				// Replay the flags of the original run so defaults resolve the same way.
+				for name, value := range state.Flags {
+					if err := cmd.Flags().Set(name, value); err != nil {
+						return fmt.Errorf("restore --%s: %w", name, err)
+					}
+				}
+				if opts.target == "" {
+					opts.target = state.Target
+				}
+				args = state.Tasks
+			} else {
+				if inProgress {
+					return fmt.Errorf("a finish queue is in progress (%s); run gwtt finish --continue or --abort", strings.Join(state.Tasks, ", "))
+				}
+				if opts.allReady == (len(args) > 0) {
+					return fmt.Errorf("name the tasks to finish or pass --all-ready")
+				}
+			}
+
+			if opts.output != "text" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format: %s", opts.output)
+			}
+			if cmd.Flags().Changed("skip-checks") && strings.TrimSpace(opts.skipChecks) == "" {
+				return fmt.Errorf("--skip-checks needs a reason")
+			}
+			if cmd.Flags().Changed("override-policy") && strings.TrimSpace(opts.overridePolicy) == "" {
+				return fmt.Errorf("--override-policy needs a reason")
+			}
 			if cfg, ok := configFromContext(cmd.Context()); ok {
+				if err := validateFinishChecks(cfg.Finish.Checks); err != nil {
+					return err
+				}
+				if err := validateFinishPolicy(cfg.Finish.Policy); err != nil {
+					return err
+				}
 				if !cmd.Flags().Changed("yes") {
 					opts.yes = !cfg.Finish.Confirm
 				}
@@ -57,107 +146,43 @@ func newFinishCommand() *cobra.Command {
 					}
 				}
 			}
-
-			repoRoot, err := repoRoot(ctx, runner)
-			if err != nil {
-				return err
-			}
-			repo, err := git.RepoBaseName(ctx, runner)
-			if err != nil {
-				return err
-			}
-
-			if err := validateMergeStrategy(opts); err != nil {
-				return err
-			}
-
-			task := worktree.SlugifyTask(args[0])
-			branch := task
-			path := worktree.WorktreePath(repoRoot, repo, task)
-
-			target := opts.target
-			if target == "" {
-				current, err := git.CurrentBranch(ctx, runner)
+			if opts.target == "" {
+				opts.target, err = git.CurrentBranch(ctx, runner)
 				if err != nil {
 					return err
 				}
-				target = current
-			}
-
-			if opts.cleanup {
-				opts.removeBranch = true
-				opts.removeWorktree = true
 			}
 
-			if opts.rebase {
-				if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "checkout", branch); err != nil {
-					return err
-				}
-				if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "rebase", target); err != nil {
-					return err
-				}
-				if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "checkout", target); err != nil {
-					return err
-				}
-				if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "merge", "--ff-only", branch); err != nil {
+			queue := args
+			if opts.allReady {
+				queue, err = readyTasks(ctx, cmd, runner, repoRoot, opts.target)
+				if err != nil {
 					return err
 				}
-			} else {
-				if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "checkout", target); err != nil {
-					return err
-				}
-
-				mergeArgs := []string{"-C", repoRoot, "merge"}
-				if opts.noFF {
-					mergeArgs = append(mergeArgs, "--no-ff")
-				}
-				if opts.squash {
-					mergeArgs = append(mergeArgs, "--squash")
-				}
-				mergeArgs = append(mergeArgs, branch)
-				if err := runGit(ctx, cmd, opts.dryRun, runner, mergeArgs...); err != nil {
+				if len(queue) == 0 {
+					_, err := fmt.Fprintln(cmd.OutOrStdout(), "no tasks are ready to finish")
 					return err
 				}
 			}
-
-			if opts.removeWorktree || opts.removeBranch {
-				if !opts.yes {
-					ok, err := confirmPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), "Remove worktree/branch?")
+			if len(queue) == 1 && !opts.allReady && !resumed && opts.check == "" {
+				report, err := finishTask(cmd, runner, repoRoot, queue[0], *opts)
+				if opts.output == "json" && report.Task != "" {
 					if err != nil {
-						return err
-					}
-					if !ok {
-						return errCanceled
-					}
-				}
-
-				if opts.removeWorktree {
-					if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "worktree", "remove", path); err != nil {
-						return err
+						report.Error = err.Error()
 					}
-					if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "worktree", "prune"); err != nil {
-						return err
-					}
-				}
-				if opts.removeBranch {
-					deleteFlag := "-d"
-					if opts.forceBranch {
-						deleteFlag = "-D"
-					}
-					if err := runGit(ctx, cmd, opts.dryRun, runner, "-C", repoRoot, "branch", deleteFlag, branch); err != nil {
-						return err
+					if printErr := printFinishJSON(cmd, report); printErr != nil {
+						return printErr
 					}
 				}
-			}
-
-			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s %s %s\n",
-				ui.SuccessStyle.Render("merged"),
-				ui.AccentStyle.Render(branch),
-				ui.MutedStyle.Render(fmt.Sprintf("into %s", target)),
-			); err != nil {
 				return err
 			}
-			return nil
+			if !resumed {
+				queue, err = orderFinishQueue(ctx, runner, repoRoot, opts.target, queue)
+				if err != nil {
+					return err
+				}
+			}
+			return runFinishQueue(cmd, runner, repoRoot, statePath, queue, opts)
 		},
 	}
 
@@ -171,10 +196,234 @@ func newFinishCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&opts.rebase, "rebase", false, "rebase task branch onto target before merging")
 	cmd.Flags().BoolVar(&opts.yes, "yes", false, "skip confirmation prompts")
 	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
+	cmd.Flags().BoolVar(&opts.noRollback, "no-rollback", false, "on failure, stop and print resume commands instead of rolling back")
+	cmd.Flags().BoolVar(&opts.allReady, "all-ready", false, "finish every clean task that is ahead of the target and merges without conflicts")
+	cmd.Flags().StringVar(&opts.check, "check", "", "command to run with sh -c in the target checkout after each merge; a failure stops the queue")
+	cmd.Flags().BoolVar(&opts.resume, "continue", false, "resume a stopped finish queue")
+	cmd.Flags().BoolVar(&opts.abort, "abort", false, "discard a stopped finish queue")
+	cmd.Flags().StringVar(&opts.skipChecks, "skip-checks", "", "skip the [[finish.checks]] commands, giving the reason")
+	cmd.Flags().StringVar(&opts.overridePolicy, "override-policy", "", "merge despite [finish.policy] violations, giving the reason")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "output format: text or json")
 
 	return cmd
 }
 
+// finishTask runs the [[finish.checks]] in the task worktree, merges the
+// task into opts.target, then removes its worktree and branch as the cleanup
+// flags ask. opts is a copy: the task's preset may change it. The report is
+// filled in as far as finishing got.
+func finishTask(cmd *cobra.Command, runner git.Runner, repoRoot, rawTask string, opts finishOptions) (finishReport, error) {
+	ctx := cmd.Context()
+	jsonOutput := opts.output == "json"
+	repo, err := git.RepoBaseName(ctx, runner)
+	if err != nil {
+		return finishReport{}, err
+	}
+
+	task := worktree.SlugifyTask(rawTask)
+	ref, err := resolveTaskRef(ctx, runner, repoRoot, task)
+	if err != nil {
+		return finishReport{}, err
+	}
+	if preset, ok := taskPreset(ctx, ref); ok {
+		if err := applyFinishPreset(cmd, &opts, preset); err != nil {
+			return finishReport{}, err
+		}
+	}
+	if err := validateMergeStrategy(&opts); err != nil {
+		return finishReport{}, err
+	}
+	branch := ref.branch
+	path, found, err := taskBranchWorktree(ctx, runner, repoRoot, branch)
+	if err != nil {
+		return finishReport{}, err
+	}
+	if !found {
+		path = taskRefWorktreePath(ctx, repoRoot, repo, task, ref)
+	}
+	target := opts.target
+	report := finishReport{Task: task, Branch: branch, Target: target, DryRun: opts.dryRun}
+
+	var checks []config.FinishCheckConfig
+	var policy config.FinishPolicyConfig
+	if cfg, ok := configFromContext(ctx); ok {
+		checks = cfg.Finish.Checks
+		policy = cfg.Finish.Policy
+	}
+	violations, err := policyViolations(ctx, runner, repoRoot, policy, branch, target, path, found, opts)
+	if err != nil {
+		return report, err
+	}
+	if len(violations) > 0 {
+		if opts.overridePolicy == "" {
+			return report, fmt.Errorf("finish policy blocks merging %s into %s:\n  - %s\n(override with --override-policy <reason>)", branch, target, strings.Join(violations, "\n  - "))
+		}
+		override := newPolicyOverride(task, branch, target, opts.overridePolicy, violations)
+		report.Policy = &override
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "%s overriding finish policy (%s): %s\n", ui.WarningStyle.Render("warning:"), opts.overridePolicy, strings.Join(violations, "; ")); err != nil {
+			return report, err
+		}
+	}
+	switch {
+	case len(checks) == 0:
+	case opts.skipChecks != "":
+		report.ChecksSkipped = opts.skipChecks
+		report.Checks = planFinishChecks(checks, checkSkipped)
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "%s skipping %d finish check(s): %s\n", ui.WarningStyle.Render("warning:"), len(checks), opts.skipChecks); err != nil {
+			return report, err
+		}
+	case opts.dryRun:
+		report.Checks = planFinishChecks(checks, checkPlanned)
+		if !jsonOutput {
+			for _, check := range checks {
+				dir := ""
+				if check.Dir != "" {
+					dir = " (in " + check.Dir + ")"
+				}
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "check %s: sh -c %q%s\n", check.Name, check.Run, dir); err != nil {
+					return report, err
+				}
+			}
+		}
+	default:
+		if !found {
+			return report, fmt.Errorf("finish checks run in the task worktree, but %s has none (skip with --skip-checks <reason>)", branch)
+		}
+		env := []string{"GWTT_TASK=" + task, "GWTT_BRANCH=" + branch, "GWTT_TARGET=" + target, "GWTT_WORKTREE=" + path}
+		report.Checks, err = runFinishChecks(ctx, cmd, checks, path, env, jsonOutput)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if opts.cleanup {
+		opts.removeBranch = true
+		opts.removeWorktree = true
+	}
+
+	forceRemove := false
+	if opts.removeWorktree {
+		if _, err := os.Stat(path); err == nil {
+			forceRemove, err = checkSubmoduleRemoval(ctx, runner, path, false)
+			if err != nil {
+				return report, fmt.Errorf("%w (finish without --cleanup, or use gwtt cleanup --force)", err)
+			}
+		}
+	}
+
+	stack, err := loadTaskStack(ctx, runner, repoRoot)
+	if err != nil {
+		return report, err
+	}
+	children := stack.children(branch)
+	if len(children) > 0 {
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "%s %s has dependent tasks: %s (they are retargeted onto %s; run gwtt restack afterwards)\n",
+			ui.WarningStyle.Render("warning:"), branch, strings.Join(children, ", "), target,
+		); err != nil {
+			return report, err
+		}
+	}
+
+	journal := newOpJournal("finish")
+	var deleted []deletedBranch
+	var mergeStep *journalStep
+	if opts.rebase {
+		journal.add(checkoutStep(repoRoot, branch))
+		journal.add(headMovingStep(repoRoot, []string{"-C", repoRoot, "rebase", target}, [][]string{{"-C", repoRoot, "rebase", "--abort"}}))
+		journal.add(checkoutStep(repoRoot, target))
+		mergeStep = headMovingStep(repoRoot, []string{"-C", repoRoot, "merge", "--ff-only", branch}, nil)
+	} else {
+		journal.add(checkoutStep(repoRoot, target))
+
+		mergeArgs := []string{"-C", repoRoot, "merge"}
+		if opts.noFF {
+			mergeArgs = append(mergeArgs, "--no-ff")
+		}
+		if opts.squash {
+			mergeArgs = append(mergeArgs, "--squash")
+		}
+		mergeArgs = append(mergeArgs, branch)
+		mergeStep = headMovingStep(repoRoot, mergeArgs, nil)
+	}
+	merged := false
+	mergeStep.done = func() { merged = true }
+	journal.add(mergeStep)
+
+	for _, child := range children {
+		journal.add(retargetStep(repoRoot, child, branch, target))
+	}
+
+	if opts.removeWorktree || opts.removeBranch {
+		if !opts.yes {
+			journal.confirm(func() error {
+				ok, err := confirmPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), "Remove worktree/branch?")
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return errCanceled
+				}
+				return nil
+			})
+		}
+
+		if opts.removeWorktree {
+			journal.add(removeWorktreeStep(repoRoot, path, forceRemove))
+			journal.git("-C", repoRoot, "worktree", "prune").idempotent = true
+		}
+		if opts.removeBranch {
+			removedPath := ""
+			if opts.removeWorktree && found {
+				removedPath = path
+			}
+			journal.add(recordBranchDeletion(deleteBranchStep(repoRoot, branch, opts.forceBranch), repoRoot, branch, removedPath, &deleted))
+		}
+	}
+
+	if jsonOutput && opts.dryRun {
+		report.Plan, err = journal.plan(shouldMaskSensitivePaths(ctx))
+		return report, err
+	}
+	runErr := journal.run(ctx, cmd, runner, opts.dryRun, !opts.noRollback)
+	if !opts.dryRun {
+		report.Operation = logOperation(cmd, runner, repoRoot, "finish", task, deleted, runErr, !opts.noRollback)
+	}
+	// The override is only logged for a merge that happened and stayed.
+	if report.Policy != nil && merged && (runErr == nil || opts.noRollback) {
+		if err := recordPolicyOverride(ctx, runner, repoRoot, *report.Policy); err != nil {
+			if _, werr := fmt.Fprintf(cmd.ErrOrStderr(), "%s failed to record the policy override: %v\n", ui.WarningStyle.Render("warning:"), err); werr != nil {
+				return report, werr
+			}
+		}
+	}
+	if runErr != nil {
+		return report, runErr
+	}
+	report.Merged = !opts.dryRun
+	if jsonOutput {
+		return report, nil
+	}
+
+	if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s %s %s\n",
+		ui.SuccessStyle.Render("merged"),
+		ui.AccentStyle.Render(branch),
+		ui.MutedStyle.Render(fmt.Sprintf("into %s", target)),
+	); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// printFinishJSON prints a finish report, or the reports of a queue.
+func printFinishJSON(cmd *cobra.Command, value any) error {
+	payload, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+	return err
+}
+
 func applyMergeMode(opts *finishOptions, mode string) error {
 	value := strings.ToLower(strings.TrimSpace(mode))
 	switch value {
@@ -194,6 +443,19 @@ func applyMergeMode(opts *finishOptions, mode string) error {
 	}
 }
 
+// applyFinishPreset lets the preset a task was created with override the
+// [finish] defaults; flags still win.
+func applyFinishPreset(cmd *cobra.Command, opts *finishOptions, preset config.PresetConfig) error {
+	if !cmd.Flags().Changed("force-branch") && preset.ForceBranch {
+		opts.forceBranch = true
+	}
+	if preset.MergeMode == "" || cmd.Flags().Changed("no-ff") || cmd.Flags().Changed("squash") || cmd.Flags().Changed("rebase") {
+		return nil
+	}
+	opts.noFF, opts.squash, opts.rebase = false, false, false
+	return applyMergeMode(opts, preset.MergeMode)
+}
+
 func validateMergeStrategy(opts *finishOptions) error {
 	strategyCount := 0
 	if opts.noFF {
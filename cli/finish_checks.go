@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/config"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+// Finish check states reported in finish output.
+const (
+	checkPassed  = "passed"
+	checkFailed  = "failed"
+	checkTimeout = "timeout"
+	checkPlanned = "planned"
+	checkSkipped = "skipped"
+)
+
+type finishCheckResult struct {
+	Name       string `json:"name"`
+	Command    string `json:"command"`
+	Dir        string `json:"dir,omitempty"`
+	Status     string `json:"status"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Output     string `json:"output,omitempty"`
+}
+
+// validateFinishChecks reports the first [[finish.checks]] entry that
+// cannot run.
+func validateFinishChecks(checks []config.FinishCheckConfig) error {
+	seen := map[string]bool{}
+	for idx, check := range checks {
+		if check.Name == "" {
+			return fmt.Errorf("finish.checks[%d]: name is required", idx)
+		}
+		if seen[check.Name] {
+			return fmt.Errorf("finish.checks: duplicate name %q", check.Name)
+		}
+		seen[check.Name] = true
+		if strings.TrimSpace(check.Run) == "" {
+			return fmt.Errorf("finish.checks %q: run is required", check.Name)
+		}
+		dir := filepath.ToSlash(filepath.Clean(check.Dir))
+		if filepath.IsAbs(check.Dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+			return fmt.Errorf("finish.checks %q: dir must be inside the worktree", check.Name)
+		}
+		if check.Timeout != "" {
+			if timeout, err := time.ParseDuration(check.Timeout); err != nil || timeout <= 0 {
+				return fmt.Errorf("finish.checks %q: invalid timeout %q (use e.g. 90s or 10m)", check.Name, check.Timeout)
+			}
+		}
+	}
+	return nil
+}
+
+// planFinishChecks lists the checks without running them, for --dry-run.
+func planFinishChecks(checks []config.FinishCheckConfig, status string) []finishCheckResult {
+	results := make([]finishCheckResult, 0, len(checks))
+	for _, check := range checks {
+		results = append(results, finishCheckResult{Name: check.Name, Command: check.Run, Dir: check.Dir, Status: status})
+	}
+	return results
+}
+
+// runFinishChecks runs each check with sh -c in the task worktree and stops
+// at the first failure. Output is captured so a failure can show it.
+func runFinishChecks(ctx context.Context, cmd *cobra.Command, checks []config.FinishCheckConfig, worktreePath string, env []string, quiet bool) ([]finishCheckResult, error) {
+	results := make([]finishCheckResult, 0, len(checks))
+	for _, check := range checks {
+		result := finishCheckResult{Name: check.Name, Command: check.Run, Dir: check.Dir}
+		runCtx := ctx
+		cancel := func() {}
+		if check.Timeout != "" {
+			timeout, _ := time.ParseDuration(check.Timeout)
+			runCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		var output bytes.Buffer
+		shell := exec.CommandContext(runCtx, "sh", "-c", check.Run)
+		shell.Dir = filepath.Join(worktreePath, filepath.FromSlash(check.Dir))
+		shell.Env = append(append(os.Environ(), env...), checkEnv(check.Env)...)
+		shell.Stdout = &output
+		shell.Stderr = &output
+		started := time.Now()
+		err := shell.Run()
+		cancel()
+		result.DurationMS = time.Since(started).Milliseconds()
+		result.Output = output.String()
+		result.Status = checkPassed
+		if err != nil {
+			result.Status = checkFailed
+			result.ExitCode = -1
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				result.ExitCode = exitErr.ExitCode()
+			}
+			if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+				result.Status = checkTimeout
+			}
+		}
+		results = append(results, result)
+
+		if !quiet {
+			style := ui.SuccessStyle
+			if result.Status != checkPassed {
+				style = ui.ErrorStyle
+			}
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s %s %s\n",
+				ui.MutedStyle.Render("check"),
+				ui.AccentStyle.Render(check.Name),
+				style.Render(fmt.Sprintf("%s (%s)", result.Status, time.Duration(result.DurationMS)*time.Millisecond)),
+			); err != nil {
+				return results, err
+			}
+		}
+		if result.Status != checkPassed {
+			detail := strings.TrimRight(result.Output, "\n")
+			if detail != "" {
+				detail = "\n" + detail
+			}
+			if result.Status == checkTimeout {
+				return results, fmt.Errorf("finish check %q timed out after %s; merge blocked (skip with --skip-checks <reason>)%s", check.Name, check.Timeout, detail)
+			}
+			return results, fmt.Errorf("finish check %q failed with exit code %d; merge blocked (skip with --skip-checks <reason>)%s", check.Name, result.ExitCode, detail)
+		}
+	}
+	return results, nil
+}
+
+func checkEnv(env map[string]string) []string {
+	vars := make([]string, 0, len(env))
+	for key, value := range env {
+		vars = append(vars, key+"="+value)
+	}
+	return vars
+}
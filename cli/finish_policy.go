@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/config"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+)
+
+const (
+	finishPolicyLogDir  = "gwtt/finish-policy"
+	finishPolicyLogFile = "overrides.jsonl"
+)
+
+// policyOverride records a finish that went through despite policy
+// violations, and why.
+type policyOverride struct {
+	Time       string   `json:"time"`
+	Task       string   `json:"task"`
+	Branch     string   `json:"branch"`
+	Target     string   `json:"target"`
+	Reason     string   `json:"reason"`
+	Violations []string `json:"violations"`
+}
+
+// validateFinishPolicy reports [finish.policy] globs and merge modes that
+// cannot be used.
+func validateFinishPolicy(policy config.FinishPolicyConfig) error {
+	for _, pattern := range policy.Protected {
+		if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("finish.policy.protected: invalid branch pattern %q", pattern)
+		}
+	}
+	targets := make([]string, 0, len(policy.MergeModes))
+	for target := range policy.MergeModes {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		if _, err := path.Match(target, ""); err != nil || strings.TrimSpace(target) == "" {
+			return fmt.Errorf("finish.policy.merge_modes: invalid branch pattern %q", target)
+		}
+		for _, mode := range policy.MergeModes[target] {
+			if err := applyMergeMode(&finishOptions{}, mode); err != nil || strings.TrimSpace(mode) == "" {
+				return fmt.Errorf("finish.policy.merge_modes %q: unsupported merge mode %q (use ff, no-ff, squash or rebase)", target, mode)
+			}
+		}
+	}
+	return nil
+}
+
+// finishMergeMode names the merge strategy opts selects, as merge_mode does.
+func finishMergeMode(opts finishOptions) string {
+	switch {
+	case opts.squash:
+		return "squash"
+	case opts.rebase:
+		return "rebase"
+	case opts.noFF:
+		return "no-ff"
+	default:
+		return "ff"
+	}
+}
+
+func matchBranch(pattern, branch string) bool {
+	ok, err := path.Match(pattern, branch)
+	return err == nil && ok
+}
+
+// policyViolations lists every rule the merge of branch into target breaks.
+// It only reads the repository.
+func policyViolations(ctx context.Context, runner git.Runner, repoRoot string, policy config.FinishPolicyConfig, branch, target, worktreePath string, found bool, opts finishOptions) ([]string, error) {
+	var violations []string
+	for _, pattern := range policy.Protected {
+		if matchBranch(pattern, target) {
+			violations = append(violations, fmt.Sprintf("%s is protected (matches %q)", target, pattern))
+			break
+		}
+	}
+
+	mode := finishMergeMode(opts)
+	var patterns []string
+	for pattern := range policy.MergeModes {
+		if matchBranch(pattern, target) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		allowed := policy.MergeModes[pattern]
+		permitted := false
+		for _, candidate := range allowed {
+			if strings.EqualFold(strings.TrimSpace(candidate), mode) {
+				permitted = true
+			}
+		}
+		if !permitted {
+			violations = append(violations, fmt.Sprintf("merge mode %s is not allowed into %s (allowed: %s)", mode, target, strings.Join(allowed, ", ")))
+		}
+	}
+
+	// The rebase flow brings the task up to date itself.
+	checkBehind := policy.RequireUpToDate && mode != "rebase"
+	if !checkBehind && !policy.RequireClean {
+		return violations, nil
+	}
+	if found {
+		info, err := worktree.Status(ctx, runner, worktreePath, target)
+		if err != nil {
+			return nil, err
+		}
+		if policy.RequireClean && info.Dirty {
+			violations = append(violations, fmt.Sprintf("task worktree %s has uncommitted changes", worktreePath))
+		}
+		if checkBehind && info.Behind > 0 {
+			violations = append(violations, fmt.Sprintf("%s is %d commit(s) behind %s (run gwtt sync first)", branch, info.Behind, target))
+		}
+		return violations, nil
+	}
+	if checkBehind {
+		stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "rev-list", "--count", branch+".."+target)
+		if err != nil {
+			return nil, fmt.Errorf("count commits behind %s: %w: %s", target, err, stderr)
+		}
+		if behind := strings.TrimSpace(stdout); behind != "0" {
+			violations = append(violations, fmt.Sprintf("%s is %s commit(s) behind %s (run gwtt sync first)", branch, behind, target))
+		}
+	}
+	return violations, nil
+}
+
+// recordPolicyOverride appends override to the log in the git common dir.
+func recordPolicyOverride(ctx context.Context, runner git.Runner, repoRoot string, override policyOverride) error {
+	commonDir, err := git.CommonDirAt(ctx, runner, repoRoot)
+	if err != nil {
+		return err
+	}
+	logPath := filepath.Join(commonDir, filepath.FromSlash(finishPolicyLogDir), finishPolicyLogFile)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		return err
+	}
+	line, err := json.Marshal(override)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func newPolicyOverride(task, branch, target, reason string, violations []string) policyOverride {
+	return policyOverride{
+		Time:       time.Now().UTC().Format(time.RFC3339),
+		Task:       task,
+		Branch:     branch,
+		Target:     target,
+		Reason:     reason,
+		Violations: violations,
+	}
+}
@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	finishQueueStateDir  = "gwtt/finish-queue"
+	finishQueueStateFile = "state.json"
+)
+
+// finishQueueState records the tasks a stopped `finish` queue has left, the
+// target it merged into and the flags it ran with, so --continue resumes it
+// the same way from any checkout.
+type finishQueueState struct {
+	Tasks  []string          `json:"tasks"`
+	Target string            `json:"target,omitempty"`
+	Flags  map[string]string `json:"flags,omitempty"`
+}
+
+// queuedTask is a task waiting in a finish queue and how many conflicts its
+// test merges predict.
+type queuedTask struct {
+	task      string
+	branch    string
+	conflicts int
+}
+
+func finishQueueStatePath(ctx context.Context, runner git.Runner, repoRoot string) (string, error) {
+	commonDir, err := git.CommonDirAt(ctx, runner, repoRoot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(commonDir, filepath.FromSlash(finishQueueStateDir), finishQueueStateFile), nil
+}
+
+func loadFinishQueue(path string) (finishQueueState, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return finishQueueState{}, false, nil
+		}
+		return finishQueueState{}, false, err
+	}
+	var state finishQueueState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return finishQueueState{}, false, fmt.Errorf("read finish queue %s: %w", path, err)
+	}
+	return state, true, nil
+}
+
+func saveFinishQueue(path string, state finishQueueState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// queueFlags returns the flags set on this run that --continue replays.
+func queueFlags(cmd *cobra.Command) map[string]string {
+	flags := map[string]string{}
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		switch flag.Name {
+		case "all-ready", "continue", "abort", "dry-run":
+			return
+		}
+		flags[flag.Name] = flag.Value.String()
+	})
+	return flags
+}
+
+// readyTasks returns the tasks that are clean, have commits the target lacks
+// and merge into it without conflicts.
+func readyTasks(ctx context.Context, cmd *cobra.Command, runner git.Runner, repoRoot, target string) ([]string, error) {
+	modeCtx, err := resolveModeContext(cmd, true)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := listTaskWorktrees(ctx, runner, modeCtx, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	var ready []string
+	for _, tw := range tasks {
+		if tw.task == "-" || tw.branch == "detached" || tw.branch == target {
+			continue
+		}
+		info, err := worktree.Status(ctx, runner, tw.path, target)
+		if err != nil {
+			return nil, err
+		}
+		if info.Dirty || info.Ahead == 0 {
+			continue
+		}
+		conflicts, err := git.MergeConflicts(ctx, runner, repoRoot, target, tw.branch)
+		if err != nil {
+			return nil, err
+		}
+		if len(conflicts) == 0 {
+			ready = append(ready, tw.task)
+		}
+	}
+	return ready, nil
+}
+
+// orderFinishQueue puts stacked parents before their children and otherwise
+// merges the tasks least likely to conflict first, scored by the files that
+// conflict with the target plus the other queued tasks they conflict with.
+func orderFinishQueue(ctx context.Context, runner git.Runner, repoRoot, target string, tasks []string) ([]string, error) {
+	items := make([]queuedTask, 0, len(tasks))
+	var pairs []conflictPair
+	for _, raw := range tasks {
+		task := worktree.SlugifyTask(raw)
+		ref, err := resolveTaskRef(ctx, runner, repoRoot, task)
+		if err != nil {
+			return nil, err
+		}
+		exists, err := git.BranchExists(ctx, runner, repoRoot, ref.branch)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("task %q has no branch %s", raw, ref.branch)
+		}
+		items = append(items, queuedTask{task: task, branch: ref.branch})
+	}
+	for idx, item := range items {
+		pairs = append(pairs, conflictPair{Left: item.task, LeftBranch: item.branch, RightBranch: target})
+		for _, other := range items[idx+1:] {
+			pairs = append(pairs, conflictPair{Left: item.task, LeftBranch: item.branch, Right: other.task, RightBranch: other.branch})
+		}
+	}
+	if err := testMerges(ctx, runner, repoRoot, pairs); err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		for idx := range items {
+			switch {
+			case pair.Right == "" && items[idx].task == pair.Left:
+				items[idx].conflicts += len(pair.Files)
+			case pair.Right != "" && len(pair.Files) > 0 && (items[idx].task == pair.Left || items[idx].task == pair.Right):
+				items[idx].conflicts++
+			}
+		}
+	}
+	stack, err := loadTaskStack(ctx, runner, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]string, 0, len(items))
+	for _, item := range orderQueue(items, stack.parents) {
+		ordered = append(ordered, item.task)
+	}
+	return ordered, nil
+}
+
+// orderQueue sorts items by conflicts, then moves every item after the
+// queued tasks it is stacked on.
+func orderQueue(items []queuedTask, parents map[string]string) []queuedTask {
+	sorted := append([]queuedTask(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].conflicts < sorted[j].conflicts })
+	queued := map[string]bool{}
+	for _, item := range items {
+		queued[item.branch] = true
+	}
+	waitsFor := func(branch string, done map[string]bool) bool {
+		seen := map[string]bool{}
+		for parent, ok := parents[branch]; ok && !seen[parent]; parent, ok = parents[parent] {
+			seen[parent] = true
+			if queued[parent] && !done[parent] {
+				return true
+			}
+		}
+		return false
+	}
+
+	ordered := make([]queuedTask, 0, len(sorted))
+	done := map[string]bool{}
+	for len(sorted) > 0 {
+		pick := 0
+		for idx, item := range sorted {
+			if !waitsFor(item.branch, done) {
+				pick = idx
+				break
+			}
+		}
+		ordered = append(ordered, sorted[pick])
+		done[sorted[pick].branch] = true
+		sorted = append(sorted[:pick], sorted[pick+1:]...)
+	}
+	return ordered
+}
+
+// runFinishQueue finishes queue in order and runs the check command after
+// each merge. At the first failure the remaining tasks are saved for
+// `finish --continue`.
+func runFinishQueue(cmd *cobra.Command, runner git.Runner, repoRoot, statePath string, queue []string, opts *finishOptions) error {
+	ctx := cmd.Context()
+	jsonOutput := opts.output == "json"
+	reports := []finishReport{}
+	stop := func(remaining []string, cause error) error {
+		if jsonOutput {
+			if len(reports) > 0 {
+				reports[len(reports)-1].Error = cause.Error()
+			}
+			if err := printFinishJSON(cmd, reports); err != nil {
+				return err
+			}
+		}
+		if opts.dryRun {
+			return cause
+		}
+		if len(remaining) == 0 {
+			if err := os.Remove(statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("%w (removing the finish queue failed: %v)", cause, err)
+			}
+			return cause
+		}
+		if err := saveFinishQueue(statePath, finishQueueState{Tasks: remaining, Target: opts.target, Flags: queueFlags(cmd)}); err != nil {
+			return fmt.Errorf("%w (saving the finish queue failed: %v)", cause, err)
+		}
+		return fmt.Errorf("%w\nfinish queue stopped with %d task(s) left (%s); run gwtt finish --continue or --abort", cause, len(remaining), formatArgs(remaining))
+	}
+
+	for idx, task := range queue {
+		if !jsonOutput {
+			if _, err := fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render(fmt.Sprintf("==> finish %s (%d/%d)", task, idx+1, len(queue)))); err != nil {
+				return err
+			}
+		}
+		report, err := finishTask(cmd, runner, repoRoot, task, *opts)
+		if report.Task != "" {
+			reports = append(reports, report)
+		}
+		if err != nil {
+			return stop(queue[idx:], err)
+		}
+		if opts.check == "" {
+			continue
+		}
+		if opts.dryRun {
+			if jsonOutput {
+				reports[len(reports)-1].Plan = append(reports[len(reports)-1].Plan, fmt.Sprintf("sh -c %q", opts.check))
+				continue
+			}
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "sh -c %q\n", opts.check); err != nil {
+				return err
+			}
+			continue
+		}
+		check := exec.CommandContext(ctx, "sh", "-c", opts.check)
+		check.Dir = repoRoot
+		check.Env = append(os.Environ(), "GWTT_TASK="+task, "GWTT_TARGET="+opts.target)
+		check.Stdout = cmd.OutOrStdout()
+		check.Stderr = cmd.ErrOrStderr()
+		if jsonOutput {
+			check.Stdout = cmd.ErrOrStderr()
+		}
+		if err := check.Run(); err != nil {
+			return stop(queue[idx+1:], fmt.Errorf("check %q failed after merging %s into %s: %w", opts.check, task, opts.target, err))
+		}
+	}
+	if jsonOutput {
+		if err := printFinishJSON(cmd, reports); err != nil {
+			return err
+		}
+	}
+	if opts.dryRun {
+		return nil
+	}
+	if err := os.Remove(statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
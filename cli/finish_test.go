@@ -1,6 +1,11 @@
 package cli
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/config"
+)
 
 func TestApplyMergeMode(t *testing.T) {
 	cases := []struct {
@@ -67,3 +72,48 @@ func TestValidateMergeStrategy(t *testing.T) {
 		})
 	}
 }
+
+func TestOrderQueue(t *testing.T) {
+	items := []queuedTask{
+		{task: "ui", branch: "ui", conflicts: 0},
+		{task: "risky", branch: "risky", conflicts: 3},
+		{task: "api", branch: "api", conflicts: 1},
+		{task: "docs", branch: "docs", conflicts: 0},
+	}
+	parents := map[string]string{"ui": "api", "api": "main"}
+	var got []string
+	for _, item := range orderQueue(items, parents) {
+		got = append(got, item.task)
+	}
+	want := []string{"docs", "api", "ui", "risky"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("orderQueue() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateFinishChecks(t *testing.T) {
+	cases := []struct {
+		name    string
+		checks  []config.FinishCheckConfig
+		wantErr string
+	}{
+		{name: "valid", checks: []config.FinishCheckConfig{{Name: "test", Run: "go test ./...", Dir: "cli", Timeout: "5m"}}},
+		{name: "missing name", checks: []config.FinishCheckConfig{{Run: "make"}}, wantErr: "name is required"},
+		{name: "duplicate", checks: []config.FinishCheckConfig{{Name: "a", Run: "true"}, {Name: "a", Run: "true"}}, wantErr: "duplicate"},
+		{name: "missing run", checks: []config.FinishCheckConfig{{Name: "a", Run: " "}}, wantErr: "run is required"},
+		{name: "dir outside", checks: []config.FinishCheckConfig{{Name: "a", Run: "true", Dir: "../other"}}, wantErr: "inside the worktree"},
+		{name: "dotted dir", checks: []config.FinishCheckConfig{{Name: "a", Run: "true", Dir: "..cache"}}},
+		{name: "bad timeout", checks: []config.FinishCheckConfig{{Name: "a", Run: "true", Timeout: "soon"}}, wantErr: "invalid timeout"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateFinishChecks(tc.checks)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("validateFinishChecks() error = %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("validateFinishChecks() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -15,6 +15,19 @@ import (
 	"github.com/pi2pie/git-worktree-tasks/cli"
 )
 
+// TestMain keeps the repo registry, updated by every command, out of the
+// user's real state directory.
+func TestMain(m *testing.M) {
+	stateHome, err := os.MkdirTemp("", "gwtt-state-")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("XDG_STATE_HOME", stateHome)
+	code := m.Run()
+	_ = os.RemoveAll(stateHome)
+	os.Exit(code)
+}
+
 type listRow struct {
 	Task    string `json:"task"`
 	Branch  string `json:"branch"`
@@ -94,6 +107,43 @@ func TestIntegrationCreateListStatusFinish(t *testing.T) {
 	}
 }
 
+func TestIntegrationFinishRollsBackOnMergeConflict(t *testing.T) {
+	repoDir := initRepo(t, true)
+	worktreePath := strings.TrimSpace(runCLI(t, repoDir, "", "--nocolor", "create", "conflict-task", "--output", "raw"))
+	absWorktreePath := filepath.Clean(filepath.Join(repoDir, worktreePath))
+
+	writeFile(t, absWorktreePath, "README.md", "task side\n")
+	runGit(t, absWorktreePath, "commit", "-am", "task change")
+	writeFile(t, repoDir, "README.md", "main side\n")
+	runGit(t, repoDir, "commit", "-am", "main change")
+	mainHead := runGit(t, repoDir, "rev-parse", "HEAD")
+
+	output, err := runCLIError(t, repoDir, "", "--nocolor", "finish", "conflict-task", "--cleanup", "--yes")
+	if err == nil || !strings.Contains(err.Error(), "finish failed and was rolled back") {
+		t.Fatalf("expected rolled back finish, got %v", err)
+	}
+	if !strings.Contains(output, "finish failed at step 2/5") || !strings.Contains(output, "rolled back: git -C") {
+		t.Fatalf("expected rollback report, got:\n%s", output)
+	}
+	if head := runGit(t, repoDir, "rev-parse", "HEAD"); head != mainHead {
+		t.Fatalf("expected HEAD restored to %s, got %s", mainHead, head)
+	}
+	if status := runGit(t, repoDir, "status", "--porcelain"); status != "" {
+		t.Fatalf("expected clean checkout after rollback, got %q", status)
+	}
+	if _, err := os.Stat(absWorktreePath); err != nil {
+		t.Fatalf("expected worktree to remain, got %v", err)
+	}
+
+	output, err = runCLIError(t, repoDir, "", "--nocolor", "finish", "conflict-task", "--cleanup", "--yes", "--no-rollback")
+	if err == nil || !strings.Contains(err.Error(), "finish stopped") {
+		t.Fatalf("expected stopped finish, got %v", err)
+	}
+	if !strings.Contains(output, "resume after fixing the cause with:") || !strings.Contains(output, "branch -d conflict-task") {
+		t.Fatalf("expected resume commands, got:\n%s", output)
+	}
+}
+
 func TestIntegrationListStatusCustomPathTaskInference(t *testing.T) {
 	repoDir := initRepo(t, true)
 	customRelPath := filepath.Join(".claude", "worktrees", "new-task")
@@ -446,6 +496,316 @@ func TestIntegrationApplyDryRunPlanOutput(t *testing.T) {
 	}
 }
 
+func TestIntegrationApplySelectiveTransfer(t *testing.T) {
+	repoDir := initRepo(t, true)
+	codexHome := setCodexHome(t)
+	opaqueID := "applysel1"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	writeFile(t, repoDir, "local.txt", "local change\n")
+	runGit(t, repoDir, "add", "local.txt")
+	runGit(t, repoDir, "commit", "-m", "local file")
+	runGit(t, codexPath, "checkout", "--detach", "main")
+	writeFile(t, codexPath, "README.md", "codex readme\n")
+	writeFile(t, codexPath, "keep.go", "package keep\n")
+	writeFile(t, codexPath, "skip.txt", "skip\n")
+
+	output := runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", opaqueID, "--include", "*.go", "--include", "README.md", "--exclude", "README.md", "--dry-run")
+	for _, want := range []string{
+		"selection: include=*.go,README.md exclude=README.md",
+		"tracked_files: 0",
+		"untracked_files: 1",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", opaqueID, "--", "README.md")
+	content, err := os.ReadFile(filepath.Join(repoDir, "README.md"))
+	if err != nil {
+		t.Fatalf("read README.md: %v", err)
+	}
+	if string(content) != "codex readme\n" {
+		t.Fatalf("expected selected README.md transferred, got %q", string(content))
+	}
+	for _, name := range []string{"keep.go", "skip.txt"} {
+		if _, err := os.Stat(filepath.Join(repoDir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected %s not to be transferred, stat error: %v", name, err)
+		}
+	}
+}
+
+func TestIntegrationApplyPatchDeletedFile(t *testing.T) {
+	repoDir := initRepo(t, true)
+	codexHome := setCodexHome(t)
+	opaqueID := "applypatch1"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	if err := os.Remove(filepath.Join(codexPath, "README.md")); err != nil {
+		t.Fatalf("remove README.md: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(codexPath, "cache"), 0o755); err != nil {
+		t.Fatalf("mkdir cache: %v", err)
+	}
+
+	runCLI(t, repoDir, "y\ny\n", "--nocolor", "--mode", "codex", "apply", opaqueID, "--patch")
+	if _, err := os.Stat(filepath.Join(repoDir, "README.md")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected README.md to be deleted, stat error: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(repoDir, "cache")); err != nil || !info.IsDir() {
+		t.Fatalf("expected empty cache directory to be created, stat error: %v", err)
+	}
+}
+
+func TestIntegrationApplyPatchRenameSides(t *testing.T) {
+	repoDir := initRepo(t, true)
+	codexHome := setCodexHome(t)
+	opaqueID := "applypatch2"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	runGit(t, codexPath, "mv", "README.md", "docs.md")
+
+	output := runCLI(t, repoDir, "y\nn\n", "--nocolor", "--mode", "codex", "apply", opaqueID, "--patch")
+	if !strings.Contains(output, "Delete README.md") || !strings.Contains(output, "Transfer docs.md") {
+		t.Fatalf("expected the rename offered as a deletion and an addition, got:\n%s", output)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "README.md")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected only the deletion side to be applied, stat error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "docs.md")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected docs.md to be skipped, stat error: %v", err)
+	}
+}
+
+func TestIntegrationApplyThreeWayAbortAndContinue(t *testing.T) {
+	repoDir := initRepo(t, true)
+	codexHome := setCodexHome(t)
+	opaqueID := "apply3way"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	writeFile(t, repoDir, "README.md", "local edit\n")
+	writeFile(t, repoDir, "notes.txt", "local notes\n")
+	writeFile(t, codexPath, "README.md", "codex edit\n")
+	writeFile(t, codexPath, "notes.txt", "codex notes\n")
+	writeFile(t, codexPath, "fresh.txt", "fresh\n")
+
+	output, err := runCLIError(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", opaqueID, "--3way")
+	if err == nil || !strings.Contains(err.Error(), "apply stopped with conflicts") {
+		t.Fatalf("expected three-way conflict stop, got %v\n%s", err, output)
+	}
+	for _, want := range []string{"- README.md", "- notes.txt", "gwtt apply --continue"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(repoDir, "README.md"))
+	if err != nil {
+		t.Fatalf("read README.md: %v", err)
+	}
+	if !strings.Contains(string(content), "<<<<<<<") {
+		t.Fatalf("expected conflict markers, got %q", string(content))
+	}
+
+	stateData, err := os.ReadFile(filepath.Join(repoDir, ".git", "gwtt", "apply-3way", "state.json"))
+	if err != nil || !strings.Contains(string(stateData), `"base": "`+runGit(t, repoDir, "rev-parse", "HEAD")+`"`) {
+		t.Fatalf("expected the common base recorded in the state, got %s (%v)", stateData, err)
+	}
+
+	runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", "--abort")
+	for name, want := range map[string]string{"README.md": "local edit\n", "notes.txt": "local notes\n"} {
+		content, err := os.ReadFile(filepath.Join(repoDir, name))
+		if err != nil {
+			t.Fatalf("read %s after abort: %v", name, err)
+		}
+		if string(content) != want {
+			t.Fatalf("%s after abort = %q, want %q", name, string(content), want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "fresh.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected fresh.txt removed by abort, stat error: %v", err)
+	}
+	if staged := runGit(t, repoDir, "diff", "--cached", "--name-only"); staged != "" {
+		t.Fatalf("expected nothing staged after abort, got:\n%s", staged)
+	}
+
+	_, err = runCLIError(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", opaqueID, "--3way")
+	if err == nil {
+		t.Fatalf("expected second three-way apply to stop with conflicts")
+	}
+	_, err = runCLIError(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", "--continue")
+	if err == nil || !strings.Contains(err.Error(), "apply stopped with conflicts") {
+		t.Fatalf("expected continue to refuse unresolved conflicts, got %v", err)
+	}
+
+	writeFile(t, repoDir, "README.md", "resolved\n")
+	writeFile(t, repoDir, "notes.txt", "resolved notes\n")
+	runGit(t, repoDir, "add", "README.md")
+	runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", "--continue")
+	if staged := runGit(t, repoDir, "diff", "--cached", "--name-only"); staged != "" {
+		t.Fatalf("expected transferred paths left unstaged after continue, got:\n%s", staged)
+	}
+	if changed := runGit(t, repoDir, "diff", "--name-only"); changed != "README.md" {
+		t.Fatalf("expected README.md modified after continue, got:\n%s", changed)
+	}
+	if _, err := runCLIError(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", "--abort"); err == nil {
+		t.Fatalf("expected abort without an in-progress apply to fail")
+	}
+}
+
+func TestIntegrationApplyThreeWayRefusesSourceCommits(t *testing.T) {
+	repoDir := initRepo(t, true)
+	codexHome := setCodexHome(t)
+	opaqueID := "apply3base"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	writeFile(t, codexPath, "README.md", "codex commit\n")
+	runGit(t, codexPath, "commit", "-am", "codex work")
+	writeFile(t, codexPath, "README.md", "codex edit\n")
+
+	_, err := runCLIError(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", opaqueID, "--3way")
+	if err == nil || !strings.Contains(err.Error(), "cannot merge against their common base") {
+		t.Fatalf("expected --3way to refuse source commits, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, ".git", "gwtt", "apply-3way")); !os.IsNotExist(err) {
+		t.Fatalf("expected no 3-way state after the refusal, got %v", err)
+	}
+}
+
+func TestIntegrationApplyThreeWayKeepsStagedChanges(t *testing.T) {
+	repoDir := initRepo(t, true)
+	writeFile(t, repoDir, "multi.txt", "one\ntwo\nthree\nfour\nfive\n")
+	runGit(t, repoDir, "add", "multi.txt")
+	runGit(t, repoDir, "commit", "-m", "add multi")
+	codexHome := setCodexHome(t)
+	opaqueID := "apply3staged"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	writeFile(t, repoDir, "multi.txt", "ONE\ntwo\nthree\nfour\nfive\n")
+	runGit(t, repoDir, "add", "multi.txt")
+	writeFile(t, codexPath, "multi.txt", "one\ntwo\nthree\nfour\nFIVE\n")
+
+	runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", opaqueID, "--3way")
+	content, err := os.ReadFile(filepath.Join(repoDir, "multi.txt"))
+	if err != nil {
+		t.Fatalf("read multi.txt: %v", err)
+	}
+	if string(content) != "ONE\ntwo\nthree\nfour\nFIVE\n" {
+		t.Fatalf("expected both changes merged, got %q", string(content))
+	}
+	if staged := runGit(t, repoDir, "show", ":multi.txt"); staged != "ONE\ntwo\nthree\nfour\nfive" {
+		t.Fatalf("expected the staged change to stay staged, got %q", staged)
+	}
+}
+
+func TestIntegrationApplyCommits(t *testing.T) {
+	repoDir := initRepo(t, true)
+	codexHome := setCodexHome(t)
+	opaqueID := "commits01"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	writeFile(t, codexPath, "feature.txt", "committed in codex\n")
+	runGit(t, codexPath, "add", "feature.txt")
+	runGit(t, codexPath, "-c", "user.name=Codex Author", "-c", "user.email=codex@example.com", "commit", "-m", "add feature")
+	writeFile(t, codexPath, "README.md", "uncommitted edit\n")
+
+	output := runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", opaqueID, "--commits", "--dry-run")
+	for _, want := range []string{"  commits: 1", "  commit_files: 1", "  tracked_files: 1", "cherry-pick "} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected dry-run output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	output = runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", opaqueID, "--commits")
+	if !strings.Contains(output, "replayed 1 commit(s)") {
+		t.Fatalf("expected replay summary, got:\n%s", output)
+	}
+	if got := runGit(t, repoDir, "log", "-1", "--format=%an <%ae>|%s"); got != "Codex Author <codex@example.com>|add feature" {
+		t.Fatalf("expected replayed commit with original author, got %q", got)
+	}
+	content, err := os.ReadFile(filepath.Join(repoDir, "README.md"))
+	if err != nil {
+		t.Fatalf("read README.md: %v", err)
+	}
+	if string(content) != "uncommitted edit\n" {
+		t.Fatalf("expected uncommitted change transferred, got %q", string(content))
+	}
+}
+
+func TestIntegrationApplyManifestModesAndEmptyDirs(t *testing.T) {
+	repoDir := initRepo(t, true)
+	codexHome := setCodexHome(t)
+	opaqueID := "manifest01"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	writeFile(t, codexPath, "tool.sh", "#!/bin/sh\necho hi\n")
+	if err := os.Chmod(filepath.Join(codexPath, "tool.sh"), 0o755); err != nil {
+		t.Fatalf("chmod tool.sh: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(codexPath, "cache", "empty"), 0o755); err != nil {
+		t.Fatalf("mkdir empty dir: %v", err)
+	}
+	runGit(t, codexPath, "init", "-q", "vendor-repo")
+
+	output := runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", opaqueID, "-o", "json")
+	var manifest struct {
+		Mode   string `json:"mode"`
+		Copied []struct {
+			Path   string `json:"path"`
+			Kind   string `json:"kind"`
+			SHA256 string `json:"sha256"`
+		} `json:"copied"`
+		Skipped []struct {
+			Path   string `json:"path"`
+			Reason string `json:"reason"`
+		} `json:"skipped"`
+	}
+	if err := json.Unmarshal([]byte(output), &manifest); err != nil {
+		t.Fatalf("parse manifest json: %v\n%s", err, output)
+	}
+	kinds := map[string]string{}
+	for _, entry := range manifest.Copied {
+		kinds[entry.Path] = entry.Kind
+		if entry.Kind == "file" && len(entry.SHA256) != 64 {
+			t.Fatalf("expected checksum for %s, got %q", entry.Path, entry.SHA256)
+		}
+	}
+	if manifest.Mode != "apply" || kinds["tool.sh"] != "file" || kinds["cache/empty"] != "dir" {
+		t.Fatalf("unexpected copied entries: %+v", manifest.Copied)
+	}
+	if len(manifest.Skipped) != 1 || manifest.Skipped[0].Path != "vendor-repo" {
+		t.Fatalf("expected nested repository to be skipped, got %+v", manifest.Skipped)
+	}
+
+	info, err := os.Stat(filepath.Join(repoDir, "tool.sh"))
+	if err != nil {
+		t.Fatalf("stat tool.sh: %v", err)
+	}
+	if info.Mode().Perm()&0o111 == 0 {
+		t.Fatalf("expected executable bit to be preserved, got %v", info.Mode())
+	}
+	if info, err := os.Stat(filepath.Join(repoDir, "cache", "empty")); err != nil || !info.IsDir() {
+		t.Fatalf("expected empty directory to be created, got %v", err)
+	}
+}
+
+func TestIntegrationOverwriteManifestListsRemovedFiles(t *testing.T) {
+	repoDir := initRepo(t, true)
+	codexHome := setCodexHome(t)
+	opaqueID := "manifest02"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	writeFile(t, repoDir, "stale.txt", "only local\n")
+	writeFile(t, codexPath, "README.md", "from codex\n")
+
+	output := runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "overwrite", opaqueID, "--yes")
+	for _, want := range []string{"removed  stale.txt", "copied   README.md", "overwrite complete"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
 func TestIntegrationOverwriteDryRunPlanOutput(t *testing.T) {
 	repoDir := initRepo(t, true)
 	codexHome := setCodexHome(t)
@@ -502,6 +862,105 @@ func TestIntegrationOverwriteToLocalConfirmation(t *testing.T) {
 	}
 }
 
+func TestIntegrationOverwriteBackupAndRestore(t *testing.T) {
+	repoDir := initRepo(t, true)
+	codexHome := setCodexHome(t)
+	opaqueID := "backup01"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	writeFile(t, repoDir, "README.md", "local edit\n")
+	writeFile(t, repoDir, "notes.txt", "local untracked\n")
+	writeFile(t, codexPath, "README.md", "codex edit\n")
+
+	output := runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "overwrite", opaqueID, "--to", "local", "--yes")
+	if !strings.Contains(output, "backup saved:") {
+		t.Fatalf("expected backup notice, got:\n%s", output)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "notes.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected overwrite to clean untracked notes.txt, got %v", err)
+	}
+
+	var backups []struct {
+		ID        string `json:"id"`
+		Reason    string `json:"reason"`
+		Tracked   bool   `json:"tracked_changes"`
+		Untracked int    `json:"untracked"`
+	}
+	listOutput := runCLI(t, repoDir, "", "--nocolor", "backups", "list", "-o", "json")
+	if err := json.Unmarshal([]byte(listOutput), &backups); err != nil {
+		t.Fatalf("parse backups json: %v\n%s", err, listOutput)
+	}
+	if len(backups) != 1 || backups[0].Reason != "overwrite" || !backups[0].Tracked || backups[0].Untracked != 1 {
+		t.Fatalf("unexpected backups: %+v", backups)
+	}
+
+	runCLI(t, repoDir, "", "--nocolor", "restore", backups[0].ID, "--yes")
+	for name, want := range map[string]string{"README.md": "local edit\n", "notes.txt": "local untracked\n"} {
+		content, err := os.ReadFile(filepath.Join(repoDir, name))
+		if err != nil {
+			t.Fatalf("read %s after restore: %v", name, err)
+		}
+		if string(content) != want {
+			t.Fatalf("expected %s restored to %q, got %q", name, want, string(content))
+		}
+	}
+
+	listOutput = runCLI(t, repoDir, "", "--nocolor", "backups", "list", "-o", "json")
+	if err := json.Unmarshal([]byte(listOutput), &backups); err != nil {
+		t.Fatalf("parse backups json: %v", err)
+	}
+	if len(backups) != 2 || backups[0].Reason != "restore" {
+		t.Fatalf("expected restore to snapshot the overwritten state first, got %+v", backups)
+	}
+}
+
+func TestIntegrationRestoreOldestBackupAtKeepLimit(t *testing.T) {
+	repoDir := initRepo(t, true)
+	writeFile(t, repoDir, "gwtt.config.toml", "[backup]\nkeep = 2\n")
+	runGit(t, repoDir, "add", "gwtt.config.toml")
+	runGit(t, repoDir, "commit", "-m", "keep two backups")
+	codexHome := setCodexHome(t)
+	opaqueID := "keep01"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+
+	writeFile(t, repoDir, "README.md", "first local edit\n")
+	writeFile(t, repoDir, "notes.txt", "first untracked\n")
+	writeFile(t, codexPath, "README.md", "codex edit\n")
+	runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "overwrite", opaqueID, "--to", "local", "--yes")
+	writeFile(t, repoDir, "README.md", "second local edit\n")
+	runCLI(t, repoDir, "", "--nocolor", "--mode", "codex", "overwrite", opaqueID, "--to", "local", "--yes")
+
+	var backups []struct {
+		ID string `json:"id"`
+	}
+	listOutput := runCLI(t, repoDir, "", "--nocolor", "backups", "list", "-o", "json")
+	if err := json.Unmarshal([]byte(listOutput), &backups); err != nil {
+		t.Fatalf("parse backups json: %v\n%s", err, listOutput)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected two backups, got %+v", backups)
+	}
+	oldest := backups[1].ID
+
+	runCLI(t, repoDir, "", "--nocolor", "restore", oldest, "--yes")
+	for name, want := range map[string]string{"README.md": "first local edit\n", "notes.txt": "first untracked\n"} {
+		content, err := os.ReadFile(filepath.Join(repoDir, name))
+		if err != nil {
+			t.Fatalf("read %s after restore: %v", name, err)
+		}
+		if string(content) != want {
+			t.Fatalf("expected %s restored from the oldest backup to %q, got %q", name, want, string(content))
+		}
+	}
+	listOutput = runCLI(t, repoDir, "", "--nocolor", "backups", "list", "-o", "json")
+	if err := json.Unmarshal([]byte(listOutput), &backups); err != nil {
+		t.Fatalf("parse backups json: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected retention to keep two backups after the restore, got %+v", backups)
+	}
+}
+
 func TestIntegrationApplyAndOverwriteToWorktree(t *testing.T) {
 	repoDir := initRepo(t, true)
 	codexHome := setCodexHome(t)
@@ -568,6 +1027,1452 @@ func TestIntegrationCodexCleanupScopeAndConfirm(t *testing.T) {
 	}
 }
 
+func TestIntegrationDoctorPrunesMissingWorktree(t *testing.T) {
+	repo := initRepo(t, true)
+	worktreePath := addClassicWorktree(t, repo, "gone")
+	if err := os.RemoveAll(worktreePath); err != nil {
+		t.Fatalf("remove worktree dir: %v", err)
+	}
+
+	output := runCLI(t, repo, "", "doctor", "-o", "json")
+	var findings []map[string]any
+	if err := json.Unmarshal([]byte(output), &findings); err != nil {
+		t.Fatalf("parse json: %v\n%s", err, output)
+	}
+	if len(findings) != 1 || findings[0]["severity"] != "warning" || findings[0]["fix"] != "git worktree prune" {
+		t.Fatalf("expected one prunable finding, got: %s", output)
+	}
+
+	output = runCLI(t, repo, "", "doctor", "--fix", "--nocolor")
+	if !strings.Contains(output, "fixed 1") {
+		t.Fatalf("expected fix summary, got: %s", output)
+	}
+	if list := runGit(t, repo, "worktree", "list", "--porcelain"); strings.Contains(list, "gone") {
+		t.Fatalf("expected worktree to be pruned, got: %s", list)
+	}
+
+	output = runCLI(t, repo, "", "doctor", "-o", "json")
+	findings = nil
+	if err := json.Unmarshal([]byte(output), &findings); err != nil {
+		t.Fatalf("parse json: %v\n%s", err, output)
+	}
+	if len(findings) != 0 {
+		t.Fatalf("expected clean doctor run, got: %s", output)
+	}
+}
+
+func TestIntegrationDoctorReportsInvalidConfig(t *testing.T) {
+	repo := initRepo(t, true)
+	writeFile(t, repo, "gwtt.config.toml", "mode = \"bogus\"\n\n[create.path]\nformat = \"{repo}_{task}_{date}\"\n")
+
+	if _, err := runCLIError(t, repo, "", "list"); err == nil {
+		t.Fatalf("expected list to reject the invalid mode")
+	}
+
+	output, err := runCLIError(t, repo, "", "doctor", "-o", "json")
+	if err == nil {
+		t.Fatalf("expected doctor to exit with an error")
+	}
+	for _, want := range []string{`"subject": "mode"`, `"subject": "create.path.format"`, "{date}"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output, got: %s", want, output)
+		}
+	}
+}
+
+func TestIntegrationCreatePresetRemembersFinishDefaults(t *testing.T) {
+	repo := initRepo(t, true)
+	writeFile(t, repo, ".env", "SECRET=1\n")
+	writeFile(t, repo, "gwtt.config.toml", `
+[presets.hotfix]
+base = "release/current"
+branch_prefix = "hotfix/"
+merge_mode = "no-ff"
+copy = [".env"]
+post_create = ["echo \"$GWTT_PRESET\" > preset.txt"]
+
+[presets.hotfix.path]
+format = "{repo}_hotfix_{task}"
+`)
+	writeFile(t, repo, ".gitignore", ".env\npreset.txt\n")
+	runGit(t, repo, "add", "gwtt.config.toml", ".gitignore")
+	runGit(t, repo, "commit", "-m", "add config")
+	runGit(t, repo, "branch", "release/current")
+
+	if _, err := runCLIError(t, repo, "", "create", "--preset", "missing", "x"); err == nil {
+		t.Fatalf("expected error for unknown preset")
+	}
+
+	output := runCLI(t, repo, "", "create", "--preset", "hotfix", "login", "--dry-run")
+	if !strings.Contains(output, "-b hotfix/login") || !strings.Contains(output, "release/current") || !strings.Contains(output, "gwttPreset") {
+		t.Fatalf("expected preset dry-run plan, got: %s", output)
+	}
+
+	path := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_hotfix_login")
+	runCLI(t, repo, "", "--nocolor", "create", "--preset", "hotfix", "login")
+	if branch := runGit(t, path, "rev-parse", "--abbrev-ref", "HEAD"); branch != "hotfix/login" {
+		t.Fatalf("preset worktree is on %q", branch)
+	}
+	for _, name := range []string{".env", "preset.txt"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			t.Fatalf("expected %s in the worktree: %v", name, err)
+		}
+	}
+
+	writeFile(t, path, "fix.txt", "fix\n")
+	runGit(t, path, "add", "fix.txt")
+	runGit(t, path, "commit", "-m", "fix")
+
+	output = runCLI(t, repo, "", "--nocolor", "finish", "login", "--target", "release/current", "--cleanup", "--yes", "--dry-run")
+	if !strings.Contains(output, "--no-ff") || !strings.Contains(output, "hotfix/login") {
+		t.Fatalf("expected finish to use the preset merge mode, got: %s", output)
+	}
+	runCLI(t, repo, "", "--nocolor", "finish", "login", "--target", "release/current", "--cleanup", "--yes")
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected worktree removed, stat error: %v", err)
+	}
+	if branchExists(t, repo, "hotfix/login") {
+		t.Fatalf("expected preset branch to be removed")
+	}
+	if parents := runGit(t, repo, "rev-list", "--parents", "-n", "1", "release/current"); len(strings.Fields(parents)) != 3 {
+		t.Fatalf("expected a merge commit on release/current, got: %s", parents)
+	}
+}
+
+func TestIntegrationCreateSparseAndAdjust(t *testing.T) {
+	repo := initRepo(t, true)
+	for _, dir := range []string{"services/api", "services/web", "docs"} {
+		if err := os.MkdirAll(filepath.Join(repo, dir), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+		writeFile(t, filepath.Join(repo, dir), "file.txt", dir+"\n")
+	}
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "-m", "layout")
+	writeFile(t, repo, "gwtt.config.toml", "[create.sparse]\ndirs = [\"docs\"]\n")
+
+	output := runCLI(t, repo, "", "create", "api", "--sparse", "services/api", "--dry-run")
+	if !strings.Contains(output, "--no-checkout") || !strings.Contains(output, "sparse-checkout set --cone services/api") {
+		t.Fatalf("expected sparse create plan, got: %s", output)
+	}
+
+	path := strings.TrimSpace(runCLI(t, repo, "", "create", "api", "--sparse", "services/api", "-o", "raw"))
+	path = filepath.Clean(filepath.Join(repo, path))
+	if _, err := os.Stat(filepath.Join(path, "services", "api", "file.txt")); err != nil {
+		t.Fatalf("expected services/api to be checked out: %v", err)
+	}
+	for _, dir := range []string{"services/web", "docs"} {
+		if _, err := os.Stat(filepath.Join(path, dir)); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected %s to be outside the sparse checkout, stat error: %v", dir, err)
+		}
+	}
+
+	statusOutput := runCLI(t, repo, "", "status", "api", "--output", "json")
+	if !strings.Contains(statusOutput, `"sparse": [`) || !strings.Contains(statusOutput, `"services/api"`) {
+		t.Fatalf("expected status to report the sparse profile, got: %s", statusOutput)
+	}
+
+	runCLI(t, repo, "", "sparse", "api", "add", "services/web")
+	if _, err := os.Stat(filepath.Join(path, "services", "web", "file.txt")); err != nil {
+		t.Fatalf("expected services/web after sparse add: %v", err)
+	}
+	runCLI(t, repo, "", "sparse", "api", "remove", "services/api")
+	if output := runCLI(t, repo, "", "sparse", "api", "list"); strings.TrimSpace(output) != "services/web" {
+		t.Fatalf("expected only services/web, got: %s", output)
+	}
+	if _, err := runCLIError(t, repo, "", "sparse", "api", "remove", "docs"); err == nil {
+		t.Fatalf("expected error removing a directory that is not checked out")
+	}
+
+	defaultPath := strings.TrimSpace(runCLI(t, repo, "", "create", "docs-task", "-o", "raw"))
+	if _, err := os.Stat(filepath.Join(repo, defaultPath, "services")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected [create.sparse] to apply by default, stat error: %v", err)
+	}
+	fullPath := strings.TrimSpace(runCLI(t, repo, "", "create", "full", "--no-sparse", "-o", "raw"))
+	if output := runCLI(t, repo, "", "sparse", "full", "list"); !strings.Contains(output, "full checkout") {
+		t.Fatalf("expected a full checkout, got: %s", output)
+	}
+	if _, err := os.Stat(filepath.Join(repo, fullPath, "services", "web", "file.txt")); err != nil {
+		t.Fatalf("expected --no-sparse to check out everything: %v", err)
+	}
+}
+
+func TestIntegrationApplyWarnsOutsideSparseCheckout(t *testing.T) {
+	repoDir := initRepo(t, true)
+	if err := os.MkdirAll(filepath.Join(repoDir, "docs"), 0o755); err != nil {
+		t.Fatalf("mkdir docs: %v", err)
+	}
+	writeFile(t, filepath.Join(repoDir, "docs"), "guide.md", "guide\n")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "docs")
+	codexHome := setCodexHome(t)
+	opaqueID := "sparse01"
+	codexPath := addCodexWorktree(t, repoDir, codexHome, opaqueID)
+	runGit(t, repoDir, "sparse-checkout", "set", "--cone", "docs")
+
+	if err := os.MkdirAll(filepath.Join(codexPath, "tools"), 0o755); err != nil {
+		t.Fatalf("mkdir tools: %v", err)
+	}
+	writeFile(t, filepath.Join(codexPath, "tools"), "build.sh", "echo build\n")
+	writeFile(t, filepath.Join(codexPath, "docs"), "new.md", "new\n")
+
+	_, stderr, err := runCLIWithErr(t, repoDir, "", "--nocolor", "--mode", "codex", "apply", opaqueID, "--dry-run")
+	if err != nil {
+		t.Fatalf("apply --dry-run failed: %v\nstderr: %s", err, stderr)
+	}
+	if !strings.Contains(stderr, "outside the sparse checkout") || !strings.Contains(stderr, "tools/build.sh") || strings.Contains(stderr, "docs/new.md") {
+		t.Fatalf("expected a sparse warning for tools/build.sh only, got: %s", stderr)
+	}
+}
+
+func TestIntegrationSubmodulesCreateStatusCleanup(t *testing.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+	sub := initRepo(t, true)
+	repo := initRepo(t, true)
+	runGit(t, repo, "submodule", "add", sub, "lib")
+	runGit(t, repo, "commit", "-m", "add submodule")
+
+	output := runCLI(t, repo, "", "create", "plain")
+	if !strings.Contains(output, "worktree ready") {
+		t.Fatalf("expected create output, got: %s", output)
+	}
+	plainPath := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_plain")
+	if _, err := os.Stat(filepath.Join(plainPath, "lib", "README.md")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected submodule to stay uninitialized, stat error: %v", err)
+	}
+	statusOutput := runCLI(t, repo, "", "status", "plain", "--output", "json")
+	if !strings.Contains(statusOutput, "lib (uninitialized)") {
+		t.Fatalf("expected uninitialized submodule in status, got: %s", statusOutput)
+	}
+
+	path := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_deps")
+	runCLI(t, repo, "", "create", "deps", "--recurse-submodules")
+	if _, err := os.Stat(filepath.Join(path, "lib", "README.md")); err != nil {
+		t.Fatalf("expected submodule to be checked out: %v", err)
+	}
+
+	writeFile(t, filepath.Join(path, "lib"), "README.md", "changed\n")
+	statusOutput = runCLI(t, repo, "", "status", "deps", "--output", "json")
+	if !strings.Contains(statusOutput, "lib (dirty)") {
+		t.Fatalf("expected dirty submodule in status, got: %s", statusOutput)
+	}
+	if _, err := runCLIError(t, repo, "", "cleanup", "deps", "--yes"); err == nil || !strings.Contains(err.Error(), "submodule lib has uncommitted changes") {
+		t.Fatalf("expected cleanup to refuse a dirty submodule, got %v", err)
+	}
+
+	runGit(t, filepath.Join(path, "lib"), "-c", "user.name=Test User", "-c", "user.email=test@example.com", "commit", "-am", "local only")
+	if _, err := runCLIError(t, repo, "", "cleanup", "deps", "--yes"); err == nil || !strings.Contains(err.Error(), "not pushed") {
+		t.Fatalf("expected cleanup to refuse unpushed submodule commits, got %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("refused cleanup removed the worktree: %v", err)
+	}
+
+	cleanPath := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_clean")
+	runCLI(t, repo, "", "create", "clean", "--recurse-submodules")
+	runCLI(t, repo, "", "cleanup", "clean", "--yes")
+	if _, err := os.Stat(cleanPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected clean worktree with submodules removed, stat error: %v", err)
+	}
+
+	runCLI(t, repo, "", "cleanup", "deps", "--yes", "--force", "--force-branch")
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected worktree removed, stat error: %v", err)
+	}
+}
+
+func TestIntegrationDiskUsage(t *testing.T) {
+	repo := initRepo(t, true)
+	bigPath := addClassicWorktree(t, repo, "big")
+	smallPath := addClassicWorktree(t, repo, "small")
+	if err := os.MkdirAll(filepath.Join(bigPath, "node_modules"), 0o755); err != nil {
+		t.Fatalf("mkdir node_modules: %v", err)
+	}
+	writeFile(t, filepath.Join(bigPath, "node_modules"), "blob.bin", strings.Repeat("x", 64<<10))
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	for _, path := range []string{smallPath, filepath.Join(smallPath, "README.md")} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	var rows []struct {
+		Task         string `json:"task"`
+		SizeBytes    int64  `json:"size_bytes"`
+		LastActivity string `json:"last_activity"`
+	}
+	output := runCLI(t, repo, "", "du", "-o", "json")
+	if err := json.Unmarshal([]byte(output), &rows); err != nil {
+		t.Fatalf("parse du json: %v\n%s", err, output)
+	}
+	if len(rows) != 2 || rows[0].Task != "big" || rows[0].SizeBytes < 64<<10 || rows[1].SizeBytes >= 64<<10 {
+		t.Fatalf("expected big first and without the shared object store, got: %+v", rows)
+	}
+	if rows[0].LastActivity == "" {
+		t.Fatalf("expected last_activity, got: %+v", rows[0])
+	}
+
+	output = runCLI(t, repo, "", "du", "--larger-than", "32K", "-o", "raw")
+	if strings.TrimSpace(output) != "big" {
+		t.Fatalf("expected only big above 32K, got: %s", output)
+	}
+
+	statusOutput := runCLI(t, repo, "", "status", "big", "--size", "-o", "json")
+	if !strings.Contains(statusOutput, `"size_bytes"`) || !strings.Contains(statusOutput, `"last_activity"`) {
+		t.Fatalf("expected status --size columns, got: %s", statusOutput)
+	}
+	if _, err := runCLIError(t, repo, "", "du", "--older-than", "soon"); err == nil {
+		t.Fatalf("expected error for an invalid age")
+	}
+}
+
+func TestIntegrationExecAcrossTasks(t *testing.T) {
+	repo := initRepo(t, true)
+	alphaPath := addClassicWorktree(t, repo, "alpha")
+	addClassicWorktree(t, repo, "beta")
+	writeFile(t, alphaPath, "wip.txt", "wip\n")
+
+	if _, err := runCLIError(t, repo, "", "exec", "--", "true"); err == nil {
+		t.Fatalf("expected exec without a selector to fail")
+	}
+
+	stdout, _, err := runCLIWithErr(t, repo, "", "exec", "--all", "-o", "json", "--", "sh", "-c", `echo "in $GWTT_TASK"; test "$GWTT_TASK" = alpha`)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 command(s) failed") {
+		t.Fatalf("expected aggregated failure, got %v", err)
+	}
+	var results []struct {
+		Task     string `json:"task"`
+		ExitCode int    `json:"exit_code"`
+		Output   string `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("parse exec json: %v\n%s", err, stdout)
+	}
+	if len(results) != 2 || results[0].Task != "alpha" || results[0].ExitCode != 0 || results[1].ExitCode != 1 || results[1].Output != "in beta\n" {
+		t.Fatalf("unexpected exec results: %+v", results)
+	}
+
+	output := runCLI(t, repo, "", "--nocolor", "exec", "--dirty", "--", "ls")
+	if !strings.Contains(output, "==> alpha") || strings.Contains(output, "==> beta") || !strings.Contains(output, "wip.txt") || !strings.Contains(output, "DURATION") {
+		t.Fatalf("expected grouped output for the dirty task only, got: %s", output)
+	}
+
+	output = runCLI(t, repo, "", "--nocolor", "exec", "--task", "beta", "--prefix", "-j", "1", "--", "echo", "hello")
+	if !strings.Contains(output, "[beta] hello") {
+		t.Fatalf("expected prefixed output, got: %s", output)
+	}
+}
+
+func TestIntegrationSyncTasksOntoTarget(t *testing.T) {
+	repo := initRepo(t, true)
+	cleanPath := addClassicWorktree(t, repo, "clean")
+	dirtyPath := addClassicWorktree(t, repo, "dirty")
+	conflictPath := addClassicWorktree(t, repo, "conflict")
+	addClassicWorktree(t, repo, "current")
+
+	writeFile(t, cleanPath, "clean.txt", "clean\n")
+	runGit(t, cleanPath, "add", "clean.txt")
+	runGit(t, cleanPath, "commit", "-m", "clean work")
+	writeFile(t, conflictPath, "README.md", "task\n")
+	runGit(t, conflictPath, "commit", "-am", "conflicting work")
+	writeFile(t, dirtyPath, "wip.txt", "wip\n")
+
+	writeFile(t, repo, "README.md", "main\n")
+	runGit(t, repo, "commit", "-am", "main moves on")
+
+	if _, err := runCLIError(t, repo, "", "sync"); err == nil {
+		t.Fatalf("expected sync without tasks or --all to fail")
+	}
+
+	stdout, _, err := runCLIWithErr(t, repo, "", "sync", "--all", "-o", "json")
+	if err == nil || !strings.Contains(err.Error(), "1 of 4 task(s)") {
+		t.Fatalf("expected one task to fail, got %v", err)
+	}
+	var results []struct {
+		Task   string `json:"task"`
+		Result string `json:"result"`
+		Detail string `json:"detail"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("parse sync json: %v\n%s", err, stdout)
+	}
+	got := map[string]string{}
+	for _, result := range results {
+		got[result.Task] = result.Result
+	}
+	want := map[string]string{"clean": "updated", "dirty": "skipped", "conflict": "conflict", "current": "updated"}
+	for task, result := range want {
+		if got[task] != result {
+			t.Fatalf("task %s: expected %s, got %+v", task, result, results)
+		}
+	}
+
+	mainHead := runGit(t, repo, "rev-parse", "main")
+	if base := runGit(t, cleanPath, "merge-base", "HEAD", "main"); base != mainHead {
+		t.Fatalf("expected clean task rebased onto main")
+	}
+	if status := runGit(t, conflictPath, "status", "--porcelain"); status != "" {
+		t.Fatalf("expected the conflicting rebase to be aborted, got status %q", status)
+	}
+	if _, err := os.Stat(filepath.Join(dirtyPath, "wip.txt")); err != nil {
+		t.Fatalf("expected dirty worktree untouched: %v", err)
+	}
+
+	output := runCLI(t, repo, "", "--nocolor", "sync", "clean", "--strategy", "merge")
+	if !strings.Contains(output, "up-to-date") {
+		t.Fatalf("expected clean task to be up to date, got: %s", output)
+	}
+}
+
+func TestIntegrationStackedTasksRestack(t *testing.T) {
+	repo := initRepo(t, true)
+	runCLI(t, repo, "", "create", "api")
+	apiPath := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_api")
+	writeFile(t, apiPath, "api.txt", "api\n")
+	runGit(t, apiPath, "add", "api.txt")
+	runGit(t, apiPath, "commit", "-m", "api work")
+
+	if _, err := runCLIError(t, repo, "", "create", "ui", "--on", "missing"); err == nil {
+		t.Fatalf("expected create --on an unknown task to fail")
+	}
+	runCLI(t, repo, "", "create", "ui", "--on", "api")
+	uiPath := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_ui")
+	if parent := runGit(t, repo, "config", "branch.ui.gwttParent"); parent != "api" {
+		t.Fatalf("expected ui stacked on api, got %q", parent)
+	}
+	writeFile(t, uiPath, "ui.txt", "ui\n")
+	runGit(t, uiPath, "add", "ui.txt")
+	runGit(t, uiPath, "commit", "-m", "ui work")
+
+	output := runCLI(t, repo, "", "--nocolor", "status", "--tree")
+	if !strings.Contains(output, "└─ ui") {
+		t.Fatalf("expected ui nested under api, got: %s", output)
+	}
+
+	writeFile(t, apiPath, "api2.txt", "more\n")
+	runGit(t, apiPath, "add", "api2.txt")
+	runGit(t, apiPath, "commit", "-m", "more api work")
+	runCLI(t, repo, "", "restack")
+	if _, err := os.Stat(filepath.Join(uiPath, "api2.txt")); err != nil {
+		t.Fatalf("expected ui restacked onto the new api commit: %v", err)
+	}
+	if output := runCLI(t, repo, "", "--nocolor", "restack", "api"); !strings.Contains(output, "up-to-date") {
+		t.Fatalf("expected a second restack to be a no-op, got: %s", output)
+	}
+
+	_, stderr, err := runCLIWithErr(t, repo, "", "finish", "api", "--cleanup", "--yes")
+	if err != nil {
+		t.Fatalf("finish api: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stderr, "dependent tasks: ui") {
+		t.Fatalf("expected a warning about dependent tasks, got: %s", stderr)
+	}
+	if parent := runGit(t, repo, "config", "branch.ui.gwttParent"); parent != "main" {
+		t.Fatalf("expected ui retargeted onto main, got %q", parent)
+	}
+
+	writeFile(t, repo, "main.txt", "main\n")
+	runGit(t, repo, "add", "main.txt")
+	runGit(t, repo, "commit", "-m", "main moves on")
+	runCLI(t, repo, "", "restack")
+	if _, err := os.Stat(filepath.Join(uiPath, "main.txt")); err != nil {
+		t.Fatalf("expected ui rebased onto main: %v", err)
+	}
+	if count := runGit(t, uiPath, "rev-list", "--count", "main..HEAD"); count != "1" {
+		t.Fatalf("expected only the ui commit on top of main, got %s", count)
+	}
+}
+
+func TestIntegrationConflictsPrediction(t *testing.T) {
+	repo := initRepo(t, true)
+	writeFile(t, repo, "notes.txt", "notes\n")
+	runGit(t, repo, "add", "notes.txt")
+	runGit(t, repo, "commit", "-m", "add notes")
+	for task, change := range map[string][2]string{
+		"alpha": {"README.md", "alpha\n"},
+		"beta":  {"README.md", "beta\n"},
+		"gamma": {"notes.txt", "gamma\n"},
+	} {
+		path := addClassicWorktree(t, repo, task)
+		writeFile(t, path, change[0], change[1])
+		runGit(t, path, "commit", "-am", task+" work")
+	}
+	writeFile(t, repo, "notes.txt", "main\n")
+	runGit(t, repo, "commit", "-am", "main work")
+	head := runGit(t, repo, "rev-parse", "HEAD")
+
+	stdout := runCLI(t, repo, "", "conflicts", "-o", "json")
+	var pairs []struct {
+		Left  string   `json:"left"`
+		Right string   `json:"right"`
+		Files []string `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &pairs); err != nil {
+		t.Fatalf("parse conflicts json: %v\n%s", err, stdout)
+	}
+	if len(pairs) != 6 {
+		t.Fatalf("expected 3 target and 3 pairwise merges, got %+v", pairs)
+	}
+	conflicting := map[string][]string{}
+	for _, pair := range pairs {
+		if len(pair.Files) > 0 {
+			conflicting[pair.Left+"/"+pair.Right] = pair.Files
+		}
+	}
+	if len(conflicting) != 2 || len(conflicting["alpha/beta"]) != 1 || conflicting["gamma/main"][0] != "notes.txt" {
+		t.Fatalf("unexpected conflicts: %v", conflicting)
+	}
+	if after := runGit(t, repo, "rev-parse", "HEAD"); after != head || runGit(t, repo, "status", "--porcelain") != "" {
+		t.Fatalf("expected conflicts to leave the checkout untouched")
+	}
+
+	output := runCLI(t, repo, "", "--nocolor", "conflicts", "-o", "matrix")
+	if !strings.Contains(output, "gamma") || !strings.Contains(output, "-") {
+		t.Fatalf("expected a conflict matrix, got: %s", output)
+	}
+
+	stdout = runCLI(t, repo, "", "status", "--conflicts", "-o", "json")
+	var rows []struct {
+		Task      string   `json:"task"`
+		Conflicts []string `json:"conflicts"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &rows); err != nil {
+		t.Fatalf("parse status json: %v\n%s", err, stdout)
+	}
+	for _, row := range rows {
+		if (row.Task == "gamma") != (len(row.Conflicts) == 1) {
+			t.Fatalf("unexpected status conflicts: %+v", rows)
+		}
+	}
+	if output := runCLI(t, repo, "", "--nocolor", "status", "--conflicts"); !strings.Contains(output, "CONFLICTS") {
+		t.Fatalf("expected a CONFLICTS column, got: %s", output)
+	}
+}
+
+func TestIntegrationFinishQueue(t *testing.T) {
+	repo := initRepo(t, true)
+	for task, file := range map[string]string{"alpha": "README.md", "beta": "beta.txt", "gamma": "gamma.txt"} {
+		path := addClassicWorktree(t, repo, task)
+		writeFile(t, path, file, task+"\n")
+		runGit(t, path, "add", file)
+		runGit(t, path, "commit", "-m", task+" work")
+	}
+	writeFile(t, repo, "README.md", "main\n")
+	runGit(t, repo, "commit", "-am", "main work")
+
+	output, err := runCLIError(t, repo, "", "--nocolor", "finish", "alpha", "beta", "gamma", "--cleanup", "--yes", "--check", `test "$GWTT_TASK" != gamma`)
+	if err == nil || !strings.Contains(err.Error(), "check") || !strings.Contains(err.Error(), "1 task(s) left (alpha)") {
+		t.Fatalf("expected the check to stop the queue, got %v", err)
+	}
+	if !strings.Contains(output, "==> finish beta (1/3)") || !strings.Contains(output, "==> finish gamma (2/3)") {
+		t.Fatalf("expected the conflicting task to be queued last, got: %s", output)
+	}
+	for _, name := range []string{"beta.txt", "gamma.txt"} {
+		if _, err := os.Stat(filepath.Join(repo, name)); err != nil {
+			t.Fatalf("expected %s merged into main: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(repo), "repo_beta")); !os.IsNotExist(err) {
+		t.Fatalf("expected --cleanup to remove the beta worktree, got %v", err)
+	}
+
+	if _, err := runCLIError(t, repo, "", "finish", "alpha"); err == nil || !strings.Contains(err.Error(), "finish queue is in progress") {
+		t.Fatalf("expected a new finish to be refused while a queue is saved, got %v", err)
+	}
+	if _, err := runCLIError(t, repo, "", "--nocolor", "finish", "--continue"); err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("expected the resumed conflicting merge to roll back, got %v", err)
+	}
+	if output := runCLI(t, repo, "", "--nocolor", "finish", "--abort"); !strings.Contains(output, "alpha") {
+		t.Fatalf("expected the queue to be discarded, got: %s", output)
+	}
+	if _, err := runCLIError(t, repo, "", "finish", "--continue"); err == nil {
+		t.Fatalf("expected --continue without a queue to fail")
+	}
+	if output := runCLI(t, repo, "", "--nocolor", "finish", "--all-ready", "--yes"); !strings.Contains(output, "no tasks are ready") {
+		t.Fatalf("expected no ready tasks, got: %s", output)
+	}
+}
+
+func TestIntegrationFinishQueueContinueKeepsTarget(t *testing.T) {
+	repo := initRepo(t, true)
+	for _, task := range []string{"alpha", "beta"} {
+		path := addClassicWorktree(t, repo, task)
+		writeFile(t, path, task+".txt", task+"\n")
+		runGit(t, path, "add", task+".txt")
+		runGit(t, path, "commit", "-m", task+" work")
+	}
+
+	if _, err := runCLIError(t, repo, "", "--nocolor", "finish", "alpha", "beta", "--yes", "--check", `test "$GWTT_TASK" != alpha`); err == nil || !strings.Contains(err.Error(), "1 task(s) left (beta)") {
+		t.Fatalf("expected the check to stop the queue, got %v", err)
+	}
+	runGit(t, repo, "checkout", "-q", "-b", "other")
+	runCLI(t, repo, "", "--nocolor", "finish", "--continue")
+
+	if err := runGitCmd(repo, "cat-file", "-e", "main:beta.txt"); err != nil {
+		t.Fatalf("expected beta merged into main: %v", err)
+	}
+	if err := runGitCmd(repo, "cat-file", "-e", "other:beta.txt"); err == nil {
+		t.Fatalf("expected other to stay untouched")
+	}
+}
+
+func TestIntegrationFinishChecks(t *testing.T) {
+	repo := initRepo(t, true)
+	writeFile(t, repo, "gwtt.config.toml", `
+[[finish.checks]]
+name = "lint"
+run = "echo linting $GWTT_TASK; test -f lint.ok || { echo lint.ok missing >&2; exit 3; }"
+timeout = "30s"
+
+[[finish.checks]]
+name = "unit"
+run = "test \"$SUITE\" = unit"
+dir = "src"
+env = { SUITE = "unit" }
+`)
+	runGit(t, repo, "add", "gwtt.config.toml")
+	runGit(t, repo, "commit", "-m", "add finish checks")
+	path := addClassicWorktree(t, repo, "gated")
+	if err := os.Mkdir(filepath.Join(path, "src"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFile(t, path, filepath.Join("src", "main.txt"), "work\n")
+	runGit(t, path, "add", "src")
+	runGit(t, path, "commit", "-m", "gated work")
+
+	output := runCLI(t, repo, "", "--nocolor", "finish", "gated", "--dry-run", "--yes")
+	if !strings.Contains(output, "check lint: sh -c") || !strings.Contains(output, "check unit: sh -c") || !strings.Contains(output, "(in src)") {
+		t.Fatalf("expected the dry-run plan to list the checks, got: %s", output)
+	}
+
+	output, err := runCLIError(t, repo, "", "--nocolor", "finish", "gated", "--yes")
+	if err == nil || !strings.Contains(err.Error(), `finish check "lint" failed with exit code 3`) || !strings.Contains(err.Error(), "lint.ok missing") {
+		t.Fatalf("expected the failing check to block the merge, got %v", err)
+	}
+	if !strings.Contains(output, "check lint failed") {
+		t.Fatalf("expected the check result line, got: %s", output)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "src", "main.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected the blocked task to stay unmerged, got %v", err)
+	}
+
+	if _, err := runCLIError(t, repo, "", "finish", "gated", "--skip-checks", " ", "--yes"); err == nil || !strings.Contains(err.Error(), "needs a reason") {
+		t.Fatalf("expected --skip-checks without a reason to fail, got %v", err)
+	}
+
+	type finishJSON struct {
+		Merged        bool   `json:"merged"`
+		ChecksSkipped string `json:"checks_skipped"`
+		Checks        []struct {
+			Status string `json:"status"`
+			Output string `json:"output"`
+		} `json:"checks"`
+	}
+	writeFile(t, path, "lint.ok", "")
+	output = runCLI(t, repo, "", "finish", "gated", "--yes", "-o", "json")
+	var report finishJSON
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("expected a JSON finish report: %v\n%s", err, output)
+	}
+	if !report.Merged || len(report.Checks) != 2 || report.Checks[0].Status != "passed" || report.Checks[1].Status != "passed" {
+		t.Fatalf("expected both checks to pass and the task to merge, got %+v", report)
+	}
+	if !strings.Contains(report.Checks[0].Output, "linting gated") {
+		t.Fatalf("expected the captured check output, got %q", report.Checks[0].Output)
+	}
+
+	other := addClassicWorktree(t, repo, "skipped")
+	writeFile(t, other, "skipped.txt", "work\n")
+	runGit(t, other, "add", "skipped.txt")
+	runGit(t, other, "commit", "-m", "skipped work")
+	output = runCLI(t, repo, "", "finish", "skipped", "--yes", "--skip-checks", "hotfix", "-o", "json")
+	report = finishJSON{}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("expected a JSON finish report: %v\n%s", err, output)
+	}
+	if !report.Merged || report.ChecksSkipped != "hotfix" || report.Checks[0].Status != "skipped" {
+		t.Fatalf("expected skipped checks to be reported, got %+v", report)
+	}
+}
+
+func TestIntegrationFinishPolicy(t *testing.T) {
+	repo := initRepo(t, true)
+	writeFile(t, repo, "gwtt.config.toml", `
+[finish.policy]
+protected = ["release/*"]
+require_up_to_date = true
+require_clean = true
+
+[finish.policy.merge_modes]
+main = ["no-ff", "squash"]
+`)
+	runGit(t, repo, "add", "gwtt.config.toml")
+	runGit(t, repo, "commit", "-m", "add finish policy")
+	runGit(t, repo, "branch", "release/1")
+	path := addClassicWorktree(t, repo, "feature")
+	writeFile(t, path, "feature.txt", "work\n")
+	runGit(t, path, "add", "feature.txt")
+	runGit(t, path, "commit", "-m", "feature work")
+	writeFile(t, path, "scratch.txt", "wip\n")
+	writeFile(t, repo, "main.txt", "main\n")
+	runGit(t, repo, "add", "main.txt")
+	runGit(t, repo, "commit", "-m", "main work")
+	head := runGit(t, repo, "rev-parse", "HEAD")
+
+	_, err := runCLIError(t, repo, "", "--nocolor", "finish", "feature", "--yes")
+	if err == nil {
+		t.Fatalf("expected the policy to block the merge")
+	}
+	for _, want := range []string{"merge mode ff is not allowed into main", "has uncommitted changes", "1 commit(s) behind main", "--override-policy"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in the policy error, got %v", want, err)
+		}
+	}
+	if got := runGit(t, repo, "rev-parse", "HEAD"); got != head {
+		t.Fatalf("expected main to stay at %s, got %s", head, got)
+	}
+	if _, err := runCLIError(t, repo, "", "finish", "feature", "--target", "release/1", "--no-ff", "--yes"); err == nil || !strings.Contains(err.Error(), `release/1 is protected (matches "release/*")`) {
+		t.Fatalf("expected the protected target to be refused, got %v", err)
+	}
+
+	// The feature branch is checked out in its worktree, so the rebase
+	// cannot start and nothing is merged.
+	if _, err := runCLIError(t, repo, "", "finish", "feature", "--rebase", "--yes", "--override-policy", "failed attempt"); err == nil {
+		t.Fatalf("expected the rebase finish to fail")
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".git", "gwtt", "finish-policy", "overrides.jsonl")); !os.IsNotExist(err) {
+		t.Fatalf("expected no override logged for a failed merge, got %v", err)
+	}
+
+	output := runCLI(t, repo, "", "finish", "feature", "--no-ff", "--yes", "--override-policy", "release blocker", "-o", "json")
+	var report struct {
+		Merged bool `json:"merged"`
+		Policy struct {
+			Reason     string   `json:"reason"`
+			Violations []string `json:"violations"`
+		} `json:"policy_override"`
+	}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("expected a JSON finish report: %v\n%s", err, output)
+	}
+	if !report.Merged || report.Policy.Reason != "release blocker" || len(report.Policy.Violations) != 2 {
+		t.Fatalf("expected the override to be reported, got %+v", report)
+	}
+	logData, err := os.ReadFile(filepath.Join(repo, ".git", "gwtt", "finish-policy", "overrides.jsonl"))
+	if err != nil || !strings.Contains(string(logData), `"reason":"release blocker"`) {
+		t.Fatalf("expected the override to be logged, got %q (%v)", logData, err)
+	}
+}
+
+func TestIntegrationCreateCarry(t *testing.T) {
+	repo := initRepo(t, true)
+	if _, err := runCLIError(t, repo, "", "create", "empty", "--carry"); err == nil || !strings.Contains(err.Error(), "nothing to carry") {
+		t.Fatalf("expected a clean checkout to have nothing to carry, got %v", err)
+	}
+	runGit(t, repo, "branch", "old")
+	writeFile(t, repo, "README.md", "hello\nworld\n")
+	runGit(t, repo, "commit", "-am", "extend readme")
+
+	writeFile(t, repo, "README.md", "hello\nworld\nagain\n")
+	writeFile(t, repo, "staged.txt", "staged\n")
+	runGit(t, repo, "add", "staged.txt")
+	if err := os.MkdirAll(filepath.Join(repo, "notes", "empty"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFile(t, repo, filepath.Join("notes", "todo.txt"), "todo\n")
+	before := runGit(t, repo, "status", "--porcelain")
+
+	output := runCLI(t, repo, "", "--nocolor", "create", "carried", "--carry", "--dry-run")
+	for _, want := range []string{"worktree add", "apply --check", "reset -q --hard HEAD", "remove "} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in the dry-run plan, got: %s", want, output)
+		}
+	}
+	if got := runGit(t, repo, "status", "--porcelain"); got != before {
+		t.Fatalf("dry-run changed the checkout: %s", got)
+	}
+
+	if _, err := runCLIError(t, repo, "", "create", "stale", "--carry", "--base", "old"); err == nil || !strings.Contains(err.Error(), "the new worktree was removed") {
+		t.Fatalf("expected the carry onto an old base to roll back, got %v", err)
+	}
+	if branchExists(t, repo, "stale") {
+		t.Fatalf("expected the rolled back branch to be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(repo), "repo_stale")); !os.IsNotExist(err) {
+		t.Fatalf("expected the rolled back worktree to be removed, got %v", err)
+	}
+	if got := runGit(t, repo, "status", "--porcelain"); got != before {
+		t.Fatalf("the failed carry changed the checkout: %s", got)
+	}
+
+	output = runCLI(t, repo, "", "--nocolor", "create", "carried", "--carry")
+	if !strings.Contains(output, "carried 2 tracked and 1 untracked file(s)") {
+		t.Fatalf("expected a carry summary, got: %s", output)
+	}
+	if got := runGit(t, repo, "status", "--porcelain"); got != "" {
+		t.Fatalf("expected the origin checkout to be clean, got: %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "notes")); !os.IsNotExist(err) {
+		t.Fatalf("expected the carried directory to be removed from the origin, got %v", err)
+	}
+	path := filepath.Join(filepath.Dir(repo), "repo_carried")
+	for file, want := range map[string]string{"README.md": "hello\nworld\nagain\n", "staged.txt": "staged\n", filepath.Join("notes", "todo.txt"): "todo\n"} {
+		data, err := os.ReadFile(filepath.Join(path, file))
+		if err != nil || string(data) != want {
+			t.Fatalf("expected %s carried into the task, got %q (%v)", file, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(path, "notes", "empty")); err != nil {
+		t.Fatalf("expected the empty directory to be carried: %v", err)
+	}
+}
+
+func TestIntegrationParkAndResume(t *testing.T) {
+	repo := initRepo(t, true)
+	path := addClassicWorktree(t, repo, "paused")
+	writeFile(t, path, "committed.txt", "done\n")
+	runGit(t, path, "add", "committed.txt")
+	runGit(t, path, "commit", "-m", "paused work")
+	writeFile(t, path, "README.md", "hello\nstaged\n")
+	runGit(t, path, "add", "README.md")
+	writeFile(t, path, "README.md", "hello\nstaged\nunstaged\n")
+	if err := os.MkdirAll(filepath.Join(path, "notes"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFile(t, path, filepath.Join("notes", "idea.txt"), "idea\n")
+	before := runGit(t, path, "status", "--porcelain")
+
+	output := runCLI(t, repo, "", "park", "paused", "--dry-run")
+	if !strings.Contains(output, "stash push --include-untracked") || !strings.Contains(output, "worktree remove") {
+		t.Fatalf("expected the park plan, got: %s", output)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("dry-run removed the worktree: %v", err)
+	}
+
+	output = runCLI(t, repo, "", "--nocolor", "park", "paused")
+	if !strings.Contains(output, "parked paused") {
+		t.Fatalf("expected a park summary, got: %s", output)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the worktree to be removed, got %v", err)
+	}
+	if !branchExists(t, repo, "paused") {
+		t.Fatalf("expected the branch to be kept")
+	}
+	if stashes := runGit(t, repo, "stash", "list"); stashes != "" {
+		t.Fatalf("expected no stash entry to be left, got: %s", stashes)
+	}
+	if _, err := runCLIError(t, repo, "", "park", "paused"); err == nil {
+		t.Fatalf("expected parking a parked task to fail")
+	}
+	if _, err := runCLIError(t, repo, "", "create", "paused"); err == nil || !strings.Contains(err.Error(), "gwtt resume paused") {
+		t.Fatalf("expected create to point at resume, got %v", err)
+	}
+
+	output = runCLI(t, repo, "", "--nocolor", "list")
+	if !strings.Contains(output, "PARKED") {
+		t.Fatalf("expected list to show the parked task, got: %s", output)
+	}
+	output = runCLI(t, repo, "", "status", "-o", "json")
+	var rows []struct {
+		Task   string `json:"task"`
+		Ahead  int    `json:"ahead"`
+		Parked bool   `json:"parked"`
+	}
+	if err := json.Unmarshal([]byte(output), &rows); err != nil {
+		t.Fatalf("expected status JSON: %v\n%s", err, output)
+	}
+	found := false
+	for _, row := range rows {
+		if row.Task == "paused" {
+			found = row.Parked && row.Ahead == 1
+		}
+	}
+	if !found {
+		t.Fatalf("expected status to report the parked task one commit ahead, got: %s", output)
+	}
+
+	output = runCLI(t, repo, "", "--nocolor", "resume", "paused")
+	if !strings.Contains(output, "resumed") {
+		t.Fatalf("expected a resume summary, got: %s", output)
+	}
+	if got := runGit(t, path, "status", "--porcelain"); got != before {
+		t.Fatalf("expected the parked state restored exactly, got %q want %q", got, before)
+	}
+	if staged := runGit(t, path, "diff", "--cached", "--name-only"); staged != "README.md" {
+		t.Fatalf("expected README.md staged again, got %q", staged)
+	}
+	if refs := runGit(t, repo, "for-each-ref", "refs/gwtt/parked/"); refs != "" {
+		t.Fatalf("expected the parked ref to be removed, got: %s", refs)
+	}
+
+	runGit(t, path, "reset", "-q", "--hard")
+	runGit(t, path, "clean", "-fdq")
+	runCLI(t, repo, "", "park", "paused")
+	runCLI(t, repo, "", "resume", "paused")
+	if got := runGit(t, path, "status", "--porcelain"); got != "" {
+		t.Fatalf("expected a clean task to resume clean, got: %s", got)
+	}
+}
+
+func TestIntegrationParkWithSubmodules(t *testing.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+	sub := initRepo(t, true)
+	repo := initRepo(t, true)
+	runGit(t, repo, "submodule", "add", sub, "lib")
+	runGit(t, repo, "commit", "-m", "add submodule")
+	path := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_deps")
+	runCLI(t, repo, "", "create", "deps", "--recurse-submodules")
+
+	writeFile(t, filepath.Join(path, "lib"), "README.md", "changed\n")
+	if _, err := runCLIError(t, repo, "", "park", "deps"); err == nil || !strings.Contains(err.Error(), "submodule lib has uncommitted changes") {
+		t.Fatalf("expected park to refuse a dirty submodule, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "lib", "README.md")); err != nil {
+		t.Fatalf("refused park removed the worktree: %v", err)
+	}
+	runGit(t, filepath.Join(path, "lib"), "checkout", "--", "README.md")
+
+	writeFile(t, path, "notes.txt", "wip\n")
+	runCLI(t, repo, "", "park", "deps")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the worktree to be removed, got %v", err)
+	}
+	runCLI(t, repo, "", "resume", "deps")
+	if got := runGit(t, path, "status", "--porcelain", "--ignore-submodules=all"); got != "?? notes.txt" {
+		t.Fatalf("expected the top-level work restored, got %q", got)
+	}
+}
+
+func TestIntegrationParkedBranchMovedAndCleanup(t *testing.T) {
+	repo := initRepo(t, true)
+	path := addClassicWorktree(t, repo, "paused")
+	writeFile(t, path, "README.md", "hello\nwip\n")
+	runCLI(t, repo, "", "park", "paused")
+
+	writeFile(t, repo, "moved.txt", "moved\n")
+	runGit(t, repo, "add", "moved.txt")
+	runGit(t, repo, "commit", "-m", "main work")
+	runGit(t, repo, "branch", "-f", "paused", "main")
+	if _, err := runCLIError(t, repo, "", "resume", "paused"); err == nil || !strings.Contains(err.Error(), "--onto-new-tip") {
+		t.Fatalf("expected resume to refuse a moved branch, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("refused resume created the worktree: %v", err)
+	}
+	runCLI(t, repo, "", "resume", "paused", "--onto-new-tip")
+	if got := runGit(t, path, "status", "--porcelain"); got != "M README.md" {
+		t.Fatalf("expected the parked work on the new tip, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(path, "moved.txt")); err != nil {
+		t.Fatalf("expected the worktree on the new tip: %v", err)
+	}
+
+	runCLI(t, repo, "", "park", "paused")
+	wip := runGit(t, repo, "rev-parse", "refs/gwtt/parked/paused")
+	output := runCLI(t, repo, "", "--nocolor", "cleanup", "paused", "--force-branch", "--yes")
+	if !strings.Contains(output, "gwtt undo ") {
+		t.Fatalf("expected an undo hint, got: %s", output)
+	}
+	if refs := runGit(t, repo, "for-each-ref", "refs/gwtt/parked/"); refs != "" {
+		t.Fatalf("expected cleanup to drop the parked ref, got: %s", refs)
+	}
+	if history := runCLI(t, repo, "", "history", "-o", "json"); !strings.Contains(history, `"parked": "`+wip+`"`) {
+		t.Fatalf("expected the dropped parked ref in the operations log, got: %s", history)
+	}
+
+	runCLI(t, repo, "", "undo")
+	if got := runGit(t, repo, "rev-parse", "refs/gwtt/parked/paused"); got != wip {
+		t.Fatalf("expected undo to restore the parked ref at %s, got %s", wip, got)
+	}
+	runCLI(t, repo, "", "cleanup", "paused", "--force-branch", "--yes")
+	runCLI(t, repo, "", "create", "paused")
+	if !branchExists(t, repo, "paused") {
+		t.Fatalf("expected create to reuse the cleaned-up parked name")
+	}
+
+	// Without a branch to delete there is nothing undo could bring the
+	// parked work back with, so the parked ref stays.
+	orphan := addClassicWorktree(t, repo, "orphan")
+	writeFile(t, orphan, "README.md", "orphan wip\n")
+	runCLI(t, repo, "", "park", "orphan")
+	runGit(t, repo, "branch", "-D", "orphan")
+	runCLI(t, repo, "", "cleanup", "orphan", "--yes")
+	if refs := runGit(t, repo, "for-each-ref", "--format=%(refname)", "refs/gwtt/parked/"); refs != "refs/gwtt/parked/orphan" {
+		t.Fatalf("expected the parked ref to stay without a branch deletion, got: %q", refs)
+	}
+}
+
+func TestIntegrationUndoCleanup(t *testing.T) {
+	repo := initRepo(t, true)
+	path := addClassicWorktree(t, repo, "scrapped")
+	writeFile(t, path, "draft.txt", "draft\n")
+	runGit(t, path, "add", "draft.txt")
+	runGit(t, path, "commit", "-m", "unmerged draft")
+	tip := runGit(t, repo, "rev-parse", "scrapped")
+	runGit(t, repo, "config", "branch.scrapped.gwttTask", "scrapped")
+
+	output := runCLI(t, repo, "", "--nocolor", "cleanup", "scrapped", "--force-branch", "--yes")
+	if !strings.Contains(output, "gwtt undo ") {
+		t.Fatalf("expected an undo hint, got: %s", output)
+	}
+	if branchExists(t, repo, "scrapped") {
+		t.Fatalf("expected the branch to be deleted")
+	}
+
+	output = runCLI(t, repo, "", "history", "-o", "json")
+	var ops []struct {
+		ID       string `json:"id"`
+		Command  string `json:"command"`
+		Task     string `json:"task"`
+		Outcome  string `json:"outcome"`
+		UndoneBy string `json:"undone_by"`
+		Branches []struct {
+			Branch   string            `json:"branch"`
+			Tip      string            `json:"tip"`
+			Worktree string            `json:"worktree"`
+			Config   map[string]string `json:"config"`
+		} `json:"branches"`
+	}
+	if err := json.Unmarshal([]byte(output), &ops); err != nil {
+		t.Fatalf("expected history JSON: %v\n%s", err, output)
+	}
+	if len(ops) != 1 || ops[0].Command != "cleanup" || ops[0].Outcome != "ok" || len(ops[0].Branches) != 1 {
+		t.Fatalf("expected one recorded cleanup, got: %s", output)
+	}
+	deleted := ops[0].Branches[0]
+	if deleted.Tip != tip || deleted.Worktree != path || deleted.Config["gwttTask"] != "scrapped" {
+		t.Fatalf("unexpected deleted branch record: %+v", deleted)
+	}
+
+	output = runCLI(t, repo, "", "undo", "--dry-run")
+	if !strings.Contains(output, "branch scrapped "+tip) || !strings.Contains(output, "worktree add") {
+		t.Fatalf("expected the undo plan, got: %s", output)
+	}
+	if branchExists(t, repo, "scrapped") {
+		t.Fatalf("dry-run recreated the branch")
+	}
+
+	output = runCLI(t, repo, "", "--nocolor", "undo", ops[0].ID)
+	if !strings.Contains(output, "undid "+ops[0].ID) {
+		t.Fatalf("expected an undo summary, got: %s", output)
+	}
+	if got := runGit(t, repo, "rev-parse", "scrapped"); got != tip {
+		t.Fatalf("expected scrapped at %s, got %s", tip, got)
+	}
+	if got := runGit(t, repo, "config", "branch.scrapped.gwttTask"); got != "scrapped" {
+		t.Fatalf("expected the task config to be restored, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(path, "draft.txt")); err != nil {
+		t.Fatalf("expected the worktree to be restored: %v", err)
+	}
+	if _, err := runCLIError(t, repo, "", "undo", ops[0].ID); err == nil || !strings.Contains(err.Error(), "already undone") {
+		t.Fatalf("expected a second undo to fail, got %v", err)
+	}
+
+	runCLI(t, repo, "", "--nocolor", "finish", "scrapped", "--cleanup", "--yes")
+	output = runCLI(t, repo, "", "--nocolor", "history")
+	for _, want := range []string{"finish", "cleanup", "undo", "undone by", "scrapped@"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected history to contain %q, got: %s", want, output)
+		}
+	}
+}
+
+func TestIntegrationCleanupFailedDeleteIsNotLogged(t *testing.T) {
+	repo := initRepo(t, true)
+	path := addClassicWorktree(t, repo, "draft")
+	writeFile(t, path, "draft.txt", "draft\n")
+	runGit(t, path, "add", "draft.txt")
+	runGit(t, path, "commit", "-m", "unmerged draft")
+
+	if _, err := runCLIError(t, repo, "", "--nocolor", "cleanup", "draft", "--yes", "--no-rollback"); err == nil {
+		t.Fatalf("expected git branch -d to refuse the unmerged branch")
+	}
+	if !branchExists(t, repo, "draft") {
+		t.Fatalf("expected the branch to survive the refused delete")
+	}
+
+	output := runCLI(t, repo, "", "history", "-o", "json")
+	var ops []struct {
+		Outcome  string            `json:"outcome"`
+		Branches []json.RawMessage `json:"branches"`
+	}
+	if err := json.Unmarshal([]byte(output), &ops); err != nil {
+		t.Fatalf("expected history JSON: %v\n%s", err, output)
+	}
+	if len(ops) != 1 || ops[0].Outcome != "stopped" || len(ops[0].Branches) != 0 {
+		t.Fatalf("expected a stopped cleanup without deleted branches, got: %s", output)
+	}
+	if _, err := runCLIError(t, repo, "", "undo"); err == nil {
+		t.Fatalf("expected undo to find nothing to recreate")
+	}
+}
+
+func TestIntegrationMoveRenamesTask(t *testing.T) {
+	repo := initRepo(t, true)
+	oldPath := addClassicWorktree(t, repo, "old-task")
+	newPath := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_new-task")
+
+	output := runCLI(t, repo, "", "mv", "old-task", "new-task", "--dry-run")
+	if !strings.Contains(output, "worktree move") || !strings.Contains(output, "branch -m old-task new-task") {
+		t.Fatalf("expected dry-run move commands, got: %s", output)
+	}
+	if _, err := os.Stat(oldPath); err != nil {
+		t.Fatalf("dry-run moved the worktree: %v", err)
+	}
+
+	output = runCLI(t, oldPath, "", "mv", "old-task", "new-task")
+	if !strings.Contains(output, "moved") {
+		t.Fatalf("expected move summary, got: %s", output)
+	}
+	if _, err := os.Stat(newPath); err != nil {
+		t.Fatalf("expected worktree at %s: %v", newPath, err)
+	}
+	if branchExists(t, repo, "old-task") || !branchExists(t, repo, "new-task") {
+		t.Fatalf("expected branch to be renamed")
+	}
+	if branch := runGit(t, newPath, "rev-parse", "--abbrev-ref", "HEAD"); branch != "new-task" {
+		t.Fatalf("moved worktree is on %q", branch)
+	}
+
+	output = runCLI(t, repo, "", "list", "-o", "raw", "--strict", "new-task")
+	if filepath.Base(strings.TrimSpace(output)) != filepath.Base(newPath) {
+		t.Fatalf("expected list to find the renamed task, got: %s", output)
+	}
+
+	if _, err := runCLIError(t, repo, "", "mv", "missing", "other"); err == nil {
+		t.Fatalf("expected error for unknown task")
+	}
+}
+
+func TestIntegrationMoveStackedParentThenRestack(t *testing.T) {
+	repo := initRepo(t, true)
+	runCLI(t, repo, "", "create", "api")
+	apiPath := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_api")
+	writeFile(t, apiPath, "api.txt", "api\n")
+	runGit(t, apiPath, "add", "api.txt")
+	runGit(t, apiPath, "commit", "-m", "api work")
+	runCLI(t, repo, "", "create", "ui", "--on", "api")
+	uiPath := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_ui")
+	writeFile(t, uiPath, "ui.txt", "ui\n")
+	runGit(t, uiPath, "add", "ui.txt")
+	runGit(t, uiPath, "commit", "-m", "ui work")
+
+	output := runCLI(t, repo, "", "mv", "api", "backend", "--dry-run")
+	if !strings.Contains(output, "config branch.ui.gwttParent backend") {
+		t.Fatalf("expected the dry-run to retarget ui, got: %s", output)
+	}
+	runCLI(t, repo, "", "mv", "api", "backend")
+	if parent := runGit(t, repo, "config", "branch.ui.gwttParent"); parent != "backend" {
+		t.Fatalf("expected ui stacked on the renamed parent, got %q", parent)
+	}
+
+	backendPath := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_backend")
+	writeFile(t, backendPath, "api2.txt", "more\n")
+	runGit(t, backendPath, "add", "api2.txt")
+	runGit(t, backendPath, "commit", "-m", "more api work")
+	runCLI(t, repo, "", "restack")
+	if parent := runGit(t, repo, "config", "branch.ui.gwttParent"); parent != "backend" {
+		t.Fatalf("expected restack to keep ui on backend, got %q", parent)
+	}
+	for _, name := range []string{"api.txt", "api2.txt", "ui.txt"} {
+		if _, err := os.Stat(filepath.Join(uiPath, name)); err != nil {
+			t.Fatalf("expected %s in ui after restack: %v", name, err)
+		}
+	}
+
+	// A parent deleted before its commits reached the target is not
+	// silently dropped from the child.
+	runGit(t, repo, "config", "branch.ui.gwttParent", "gone")
+	output = runCLI(t, repo, "", "--nocolor", "restack", "--target", "main")
+	if !strings.Contains(output, "not in main") {
+		t.Fatalf("expected restack to refuse an unmerged missing parent, got: %s", output)
+	}
+	if parent := runGit(t, repo, "config", "branch.ui.gwttParent"); parent != "gone" {
+		t.Fatalf("expected ui to stay on its recorded parent, got %q", parent)
+	}
+	if _, err := os.Stat(filepath.Join(uiPath, "api.txt")); err != nil {
+		t.Fatalf("expected ui to keep the parent commits: %v", err)
+	}
+}
+
+func TestIntegrationMoveRelayout(t *testing.T) {
+	repo := initRepo(t, true)
+	addClassicWorktree(t, repo, "alpha")
+	addClassicWorktree(t, repo, "beta")
+	writeFile(t, repo, "gwtt.config.toml", "[create.path]\nroot = \"../trees\"\nformat = \"{task}\"\n")
+	treesRoot := filepath.Join(filepath.Dir(repo), "trees")
+
+	output := runCLI(t, repo, "", "mv", "--relayout", "--dry-run")
+	if !strings.Contains(output, "mkdir") || strings.Count(output, "worktree move") != 2 {
+		t.Fatalf("expected relayout plan, got: %s", output)
+	}
+	if _, err := os.Stat(treesRoot); err == nil {
+		t.Fatalf("dry-run created the layout root")
+	}
+
+	runCLI(t, repo, "", "mv", "--relayout")
+	for _, task := range []string{"alpha", "beta"} {
+		if _, err := os.Stat(filepath.Join(treesRoot, task, "README.md")); err != nil {
+			t.Fatalf("expected %s under the new layout: %v", task, err)
+		}
+	}
+	if !branchExists(t, repo, "alpha") {
+		t.Fatalf("relayout must keep branch names")
+	}
+
+	output = runCLI(t, repo, "", "mv", "--relayout")
+	if !strings.Contains(output, "already follow") {
+		t.Fatalf("expected second relayout to be a no-op, got: %s", output)
+	}
+}
+
+func TestIntegrationCreatePathConfig(t *testing.T) {
+	repo := initRepo(t, true)
+	parent := filepath.Dir(repo)
+
+	// [create.path] set to its defaults keeps the fixed <repo>_<task> layout.
+	writeFile(t, repo, "gwtt.config.toml", "[create.path]\nroot = \"../\"\nformat = \"{repo}_{task}\"\n")
+	runCLI(t, repo, "", "create", "alpha")
+	if _, err := os.Stat(filepath.Join(parent, filepath.Base(repo)+"_alpha", "README.md")); err != nil {
+		t.Fatalf("expected alpha at the default path: %v", err)
+	}
+
+	writeFile(t, repo, "gwtt.config.toml", "[create.path]\nroot = \"../trees\"\nformat = \"{repo}_{task}\"\n")
+	runCLI(t, repo, "", "create", "beta")
+	betaPath := filepath.Join(parent, "trees", filepath.Base(repo)+"_beta")
+	if _, err := os.Stat(filepath.Join(betaPath, "README.md")); err != nil {
+		t.Fatalf("expected beta under the configured root: %v", err)
+	}
+	runCLI(t, repo, "", "cleanup", "beta", "--yes")
+	if _, err := os.Stat(betaPath); !os.IsNotExist(err) {
+		t.Fatalf("expected cleanup to find beta under the configured root, got %v", err)
+	}
+}
+
+func TestIntegrationRepairAfterMainMoved(t *testing.T) {
+	repo := initRepo(t, true)
+	worktreePath := addClassicWorktree(t, repo, "alpha")
+	moved := filepath.Join(filepath.Dir(repo), "renamed")
+	if err := os.Rename(repo, moved); err != nil {
+		t.Fatalf("move main checkout: %v", err)
+	}
+	if err := runGitCmd(worktreePath, "status"); err == nil {
+		t.Fatalf("expected worktree to be broken after moving the main checkout")
+	}
+
+	output := runCLI(t, moved, "", "repair", "--dry-run")
+	if !strings.Contains(output, "worktree repair") {
+		t.Fatalf("expected repair command in dry-run, got: %s", output)
+	}
+
+	output = runCLI(t, moved, "", "repair")
+	if !strings.Contains(output, "relinked") || !strings.Contains(output, "gwtt repair --relayout") {
+		t.Fatalf("expected relink and relayout hint, got: %s", output)
+	}
+	if branch := runGit(t, worktreePath, "rev-parse", "--abbrev-ref", "HEAD"); branch != "alpha" {
+		t.Fatalf("relinked worktree is on %q", branch)
+	}
+
+	runCLI(t, moved, "", "repair", "--relayout")
+	relaid := filepath.Join(filepath.Dir(moved), "renamed_alpha")
+	if _, err := os.Stat(filepath.Join(relaid, "README.md")); err != nil {
+		t.Fatalf("expected worktree at %s: %v", relaid, err)
+	}
+	output = runCLI(t, moved, "", "repair")
+	if !strings.Contains(output, "all worktrees are linked") {
+		t.Fatalf("expected clean repair, got: %s", output)
+	}
+}
+
+func TestIntegrationRepairRecordsTaskWithoutRelayout(t *testing.T) {
+	repo := initRepo(t, true)
+	worktreePath := filepath.Join(filepath.Dir(repo), filepath.Base(repo)+"_login")
+	runGit(t, repo, "worktree", "add", "-b", "feat/login", worktreePath)
+	moved := filepath.Join(filepath.Dir(repo), "renamed")
+	if err := os.Rename(repo, moved); err != nil {
+		t.Fatalf("move main checkout: %v", err)
+	}
+
+	runCLI(t, moved, "", "repair")
+	if task := runGit(t, moved, "config", "branch.feat/login.gwttTask"); task != "login" {
+		t.Fatalf("expected gwttTask login, got %q", task)
+	}
+	output := runCLI(t, moved, "", "list", "-o", "json")
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(output), &rows); err != nil {
+		t.Fatalf("parse json: %v\n%s", err, output)
+	}
+	found := false
+	for _, row := range rows {
+		if row["branch"] == "feat/login" {
+			found = row["task"] == "login"
+		}
+	}
+	if !found {
+		t.Fatalf("expected task login for feat/login, got: %s", output)
+	}
+}
+
+func TestIntegrationWorkspaceAcrossRepos(t *testing.T) {
+	wsDir := t.TempDir()
+	if resolved, err := filepath.EvalSymlinks(wsDir); err == nil {
+		wsDir = resolved
+	}
+	backend := filepath.Join(wsDir, "backend")
+	frontend := filepath.Join(wsDir, "frontend")
+	for _, target := range []string{backend, frontend} {
+		if err := os.Rename(initRepo(t, true), target); err != nil {
+			t.Fatalf("move repo: %v", err)
+		}
+	}
+	writeFile(t, wsDir, "gwtt.workspace.toml", "repos = [\"backend\", \"frontend\"]\n")
+
+	output := runCLI(t, wsDir, "", "ws", "create", "feature")
+	if !strings.Contains(output, "[backend]") || !strings.Contains(output, "[frontend]") {
+		t.Fatalf("expected per-repo headers, got: %s", output)
+	}
+	for _, repo := range []string{backend, frontend} {
+		if _, err := os.Stat(repo + "_feature"); err != nil {
+			t.Fatalf("expected worktree next to %s: %v", repo, err)
+		}
+	}
+
+	output = runCLI(t, wsDir, "", "ws", "status", "-o", "json")
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(output), &rows); err != nil {
+		t.Fatalf("parse json: %v\n%s", err, output)
+	}
+	repos := map[any]bool{}
+	for _, row := range rows {
+		if row["task"] == "feature" {
+			repos[row["repo"]] = true
+		}
+	}
+	if !repos["backend"] || !repos["frontend"] {
+		t.Fatalf("expected feature rows from both repos, got: %s", output)
+	}
+
+	writeFile(t, backend+"_feature", "api.txt", "api\n")
+	runGit(t, backend+"_feature", "add", "api.txt")
+	runGit(t, backend+"_feature", "commit", "-m", "api")
+	writeFile(t, frontend, "README.md", "dirty\n")
+	if _, err := runCLIError(t, wsDir, "", "ws", "finish", "feature", "--yes"); err == nil {
+		t.Fatalf("expected preflight to fail on the dirty frontend checkout")
+	}
+	if _, err := os.Stat(filepath.Join(backend, "api.txt")); err == nil {
+		t.Fatalf("backend was merged although preflight failed")
+	}
+
+	runGit(t, frontend, "checkout", "README.md")
+	runCLI(t, wsDir, "", "ws", "finish", "feature", "--yes")
+	if _, err := os.Stat(filepath.Join(backend, "api.txt")); err != nil {
+		t.Fatalf("expected backend merge: %v", err)
+	}
+
+	runCLI(t, wsDir, "yes\n", "ws", "cleanup", "feature")
+	for _, repo := range []string{backend, frontend} {
+		if branchExists(t, repo, "feature") {
+			t.Fatalf("expected feature branch removed in %s", repo)
+		}
+	}
+}
+
+func TestIntegrationWorkspacePresetBranchPrefix(t *testing.T) {
+	wsDir := t.TempDir()
+	if resolved, err := filepath.EvalSymlinks(wsDir); err == nil {
+		wsDir = resolved
+	}
+	backend := filepath.Join(wsDir, "backend")
+	frontend := filepath.Join(wsDir, "frontend")
+	for _, target := range []string{backend, frontend} {
+		if err := os.Rename(initRepo(t, true), target); err != nil {
+			t.Fatalf("move repo: %v", err)
+		}
+		writeFile(t, target, "gwtt.config.toml", "[presets.hotfix]\nbranch_prefix = \"hotfix/\"\n")
+		runGit(t, target, "add", "gwtt.config.toml")
+		runGit(t, target, "commit", "-m", "add hotfix preset")
+		runCLI(t, target, "", "create", "login", "--preset", "hotfix")
+	}
+	writeFile(t, wsDir, "gwtt.workspace.toml", "repos = [\"backend\", \"frontend\"]\n")
+
+	fixPath := backend + "_login"
+	writeFile(t, fixPath, "fix.txt", "fix\n")
+	runGit(t, fixPath, "add", "fix.txt")
+	runGit(t, fixPath, "commit", "-m", "fix")
+
+	output := runCLI(t, wsDir, "", "ws", "finish", "login", "--yes", "--dry-run")
+	if !strings.Contains(output, "merge hotfix/login") {
+		t.Fatalf("expected the prefixed branch in the plan, got: %s", output)
+	}
+	runCLI(t, wsDir, "", "ws", "finish", "login", "--yes")
+	if _, err := os.Stat(filepath.Join(backend, "fix.txt")); err != nil {
+		t.Fatalf("expected backend merge: %v", err)
+	}
+	runCLI(t, wsDir, "", "ws", "cleanup", "login", "--yes")
+	for _, repo := range []string{backend, frontend} {
+		if branchExists(t, repo, "hotfix/login") {
+			t.Fatalf("expected hotfix/login removed in %s", repo)
+		}
+	}
+}
+
+func TestIntegrationListAllRepos(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	first := initRepo(t, true)
+	addClassicWorktree(t, first, "alpha")
+	second := initRepo(t, true)
+	addClassicWorktree(t, second, "beta")
+	discovered := initRepo(t, true)
+	addClassicWorktree(t, discovered, "gamma")
+
+	runCLI(t, first, "", "list")
+	runCLI(t, second, "", "status")
+
+	outside := t.TempDir()
+	writeFile(t, outside, "gwtt.config.toml", "[registry]\nroots = [\""+filepath.Dir(discovered)+"\"]\n")
+	output := runCLI(t, outside, "", "list", "--all-repos", "-o", "json")
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(output), &rows); err != nil {
+		t.Fatalf("parse json: %v\n%s", err, output)
+	}
+	tasks := map[any]bool{}
+	for _, row := range rows {
+		tasks[row["task"]] = true
+	}
+	for _, task := range []string{"alpha", "beta", "gamma"} {
+		if !tasks[task] {
+			t.Fatalf("expected task %s across repos, got: %s", task, output)
+		}
+	}
+
+	output = runCLI(t, outside, "", "status", "--all-repos", "-o", "json", "--strict", "beta")
+	if !strings.Contains(output, `"task": "beta"`) || strings.Contains(output, `"task": "alpha"`) {
+		t.Fatalf("expected filtered status across repos, got: %s", output)
+	}
+
+	if err := os.RemoveAll(filepath.Dir(second)); err != nil {
+		t.Fatalf("remove repo: %v", err)
+	}
+	output = runCLI(t, outside, "", "list", "--all-repos", "-o", "json")
+	if strings.Contains(output, "beta") || !strings.Contains(output, "alpha") {
+		t.Fatalf("expected removed repo to be pruned, got: %s", output)
+	}
+	state, err := os.ReadFile(filepath.Join(os.Getenv("XDG_STATE_HOME"), "gwtt", "repos.json"))
+	if err != nil {
+		t.Fatalf("read registry: %v", err)
+	}
+	if strings.Contains(string(state), second) {
+		t.Fatalf("expected registry to drop %s, got: %s", second, state)
+	}
+}
+
 func initRepo(t *testing.T, withCommit bool) string {
 	t.Helper()
 	root := t.TempDir()
@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+const journalRetries = 2
+
+// journalRetryDelay is a variable so tests can skip the wait.
+var journalRetryDelay = 200 * time.Millisecond
+
+// journalStep is one git command of a multi-step operation. snapshot runs
+// right before the step and returns the commands that restore the pre-state
+// once the step has completed; abort cleans up after the step itself failed
+// half-way (a conflicted merge or rebase). done runs once the step has
+// completed.
+type journalStep struct {
+	args       []string
+	idempotent bool
+	snapshot   func(ctx context.Context, runner git.Runner) ([][]string, error)
+	abort      [][]string
+	gate       func() error
+	done       func()
+
+	undo [][]string
+}
+
+// opJournal runs steps in order and remembers how to undo each completed
+// one. The same step list renders --dry-run output, so the plan and the
+// execution cannot drift apart.
+type opJournal struct {
+	op    string
+	steps []*journalStep
+}
+
+func newOpJournal(op string) *opJournal {
+	return &opJournal{op: op}
+}
+
+func (j *opJournal) add(step *journalStep) {
+	j.steps = append(j.steps, step)
+}
+
+// git appends a plain git step with no pre-state to restore.
+func (j *opJournal) git(args ...string) *journalStep {
+	step := &journalStep{args: args}
+	j.add(step)
+	return step
+}
+
+// confirm appends a gate that runs before the following steps, also during
+// --dry-run, matching the prompts the commands have always shown.
+func (j *opJournal) confirm(gate func() error) {
+	j.add(&journalStep{gate: gate})
+}
+
+func (j *opJournal) commands() int {
+	count := 0
+	for _, step := range j.steps {
+		if step.gate == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// run executes the journal. On failure it rolls back the completed steps in
+// reverse order, or with rollback disabled prints the exact commands that
+// resume the operation.
+func (j *opJournal) run(ctx context.Context, cmd *cobra.Command, runner git.Runner, dryRun, rollback bool) error {
+	maskPaths := shouldMaskSensitivePaths(ctx)
+	out := cmd.OutOrStdout()
+	var completed []*journalStep
+	index := 0
+	for pos, step := range j.steps {
+		if step.gate != nil {
+			if err := step.gate(); err != nil {
+				return err
+			}
+			continue
+		}
+		index++
+		if dryRun {
+			if _, err := fmt.Fprintln(out, formatGitCommandForDryRun(step.args, maskPaths)); err != nil {
+				return err
+			}
+			continue
+		}
+		if step.snapshot != nil {
+			undo, err := step.snapshot(ctx, runner)
+			if err != nil {
+				return j.fail(ctx, out, runner, completed, pos, index, nil, fmt.Errorf("record pre-state: %w", err), rollback, maskPaths)
+			}
+			step.undo = undo
+		}
+		if err := runJournalCommand(ctx, runner, step.args, step.idempotent); err != nil {
+			return j.fail(ctx, out, runner, completed, pos, index, step, err, rollback, maskPaths)
+		}
+		completed = append(completed, step)
+		if step.done != nil {
+			step.done()
+		}
+	}
+	return nil
+}
+
+// plan checks the gates and returns the commands run would execute, for
+// machine-readable dry-run output.
+func (j *opJournal) plan(maskPaths bool) ([]string, error) {
+	var commands []string
+	for _, step := range j.steps {
+		if step.gate != nil {
+			if err := step.gate(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		commands = append(commands, formatGitCommandForDryRun(step.args, maskPaths))
+	}
+	return commands, nil
+}
+
+func (j *opJournal) fail(ctx context.Context, out io.Writer, runner git.Runner, completed []*journalStep, pos, index int, failed *journalStep, cause error, rollback bool, maskPaths bool) error {
+	if _, err := fmt.Fprintf(out, "%s\n", ui.WarningStyle.Render(fmt.Sprintf("%s failed at step %d/%d: %s", j.op, index, j.commands(), formatGitCommandForDryRun(j.steps[pos].args, maskPaths)))); err != nil {
+		return err
+	}
+
+	if !rollback {
+		if _, err := fmt.Fprintln(out, ui.WarningStyle.Render("resume after fixing the cause with:")); err != nil {
+			return err
+		}
+		for _, step := range j.steps[pos:] {
+			if step.gate != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(out, "  %s\n", formatGitCommandForDryRun(step.args, maskPaths)); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("%s stopped: %w", j.op, cause)
+	}
+
+	var undo [][]string
+	if failed != nil {
+		undo = append(undo, failed.abort...)
+	}
+	for i := len(completed) - 1; i >= 0; i-- {
+		undo = append(undo, completed[i].undo...)
+	}
+	for idx, args := range undo {
+		if err := runJournalCommand(ctx, runner, args, false); err != nil {
+			if _, werr := fmt.Fprintln(out, ui.WarningStyle.Render("rollback failed; restore manually with:")); werr != nil {
+				return werr
+			}
+			for _, rest := range undo[idx:] {
+				if _, werr := fmt.Fprintf(out, "  %s\n", formatGitCommandForDryRun(rest, maskPaths)); werr != nil {
+					return werr
+				}
+			}
+			return fmt.Errorf("%s failed: %w (rollback failed: %v)", j.op, cause, err)
+		}
+		if _, err := fmt.Fprintf(out, "%s %s\n", ui.MutedStyle.Render("rolled back:"), formatGitCommandForDryRun(args, maskPaths)); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%s failed and was rolled back: %w", j.op, cause)
+}
+
+func runJournalCommand(ctx context.Context, runner git.Runner, args []string, retry bool) error {
+	attempts := 1
+	if retry {
+		attempts += journalRetries
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(journalRetryDelay)
+		}
+		var stderr string
+		_, stderr, err = runner.Run(ctx, args...)
+		if err == nil {
+			return nil
+		}
+		if stderr != "" {
+			err = fmt.Errorf("%s: %w: %s", formatGitCommand(args), err, stderr)
+		} else {
+			err = fmt.Errorf("%s: %w", formatGitCommand(args), err)
+		}
+		if !isTransientGitError(stderr) {
+			return err
+		}
+	}
+	return err
+}
+
+// isTransientGitError reports lock contention from a concurrent git process,
+// the one failure worth retrying unchanged.
+func isTransientGitError(stderr string) bool {
+	return strings.Contains(stderr, ".lock") && strings.Contains(stderr, "File exists")
+}
+
+// checkoutStep switches repoRoot to ref; undo returns to the branch (or
+// detached commit) that was checked out before.
+func checkoutStep(repoRoot, ref string) *journalStep {
+	return &journalStep{
+		args:       []string{"-C", repoRoot, "checkout", ref},
+		idempotent: true,
+		snapshot: func(ctx context.Context, runner git.Runner) ([][]string, error) {
+			previous, err := currentRef(ctx, runner, repoRoot)
+			if err != nil {
+				return nil, err
+			}
+			return [][]string{{"-C", repoRoot, "checkout", previous}}, nil
+		},
+	}
+}
+
+// headMovingStep wraps merge/rebase style commands that move HEAD of
+// repoRoot; undo resets to the recorded commit, keeping unrelated local
+// changes like `git merge --abort` does.
+func headMovingStep(repoRoot string, args []string, abort [][]string) *journalStep {
+	step := &journalStep{args: args, abort: abort}
+	step.snapshot = func(ctx context.Context, runner git.Runner) ([][]string, error) {
+		head, err := git.RevParse(ctx, runner, repoRoot, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		reset := []string{"-C", repoRoot, "reset", "--merge", head}
+		if step.abort == nil {
+			step.abort = [][]string{reset}
+		}
+		return [][]string{reset}, nil
+	}
+	return step
+}
+
+// removeWorktreeStep removes path; undo re-adds it on the branch (or
+// commit) it had checked out. A prunable worktree whose directory is
+// already gone has nothing to restore. force is needed for worktrees with
+// checked-out submodules.
+func removeWorktreeStep(repoRoot, path string, force bool) *journalStep {
+	args := []string{"-C", repoRoot, "worktree", "remove"}
+	if force {
+		args = append(args, "--force")
+	}
+	return &journalStep{
+		args: append(args, path),
+		snapshot: func(ctx context.Context, runner git.Runner) ([][]string, error) {
+			worktrees, err := worktree.List(ctx, runner, repoRoot)
+			if err != nil {
+				return nil, err
+			}
+			target, err := worktree.NormalizePath(repoRoot, path)
+			if err != nil {
+				return nil, err
+			}
+			for _, wt := range worktrees {
+				wtPath, err := worktree.NormalizePath(repoRoot, wt.Path)
+				if err != nil {
+					return nil, err
+				}
+				if wtPath != target || wt.Prunable || wt.Head == "" {
+					continue
+				}
+				if branch := strings.TrimPrefix(wt.Branch, "refs/heads/"); branch != "" {
+					return [][]string{{"-C", repoRoot, "worktree", "add", path, branch}}, nil
+				}
+				return [][]string{{"-C", repoRoot, "worktree", "add", "--detach", path, wt.Head}}, nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+// deleteBranchStep deletes branch; undo recreates it at its recorded tip.
+func deleteBranchStep(repoRoot, branch string, force bool) *journalStep {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+	return &journalStep{
+		args: []string{"-C", repoRoot, "branch", flag, branch},
+		snapshot: func(ctx context.Context, runner git.Runner) ([][]string, error) {
+			tip, err := git.RevParse(ctx, runner, repoRoot, "refs/heads/"+branch)
+			if err != nil {
+				return nil, err
+			}
+			return [][]string{{"-C", repoRoot, "branch", branch, tip}}, nil
+		},
+	}
+}
+
+func currentRef(ctx context.Context, runner git.Runner, repoRoot string) (string, error) {
+	branch, _, err := runner.Run(ctx, "-C", repoRoot, "symbolic-ref", "-q", "--short", "HEAD")
+	if err == nil && strings.TrimSpace(branch) != "" {
+		return strings.TrimSpace(branch), nil
+	}
+	return git.RevParse(ctx, runner, repoRoot, "HEAD")
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+type scriptedRunner struct {
+	responses map[string][]fakeResponse
+	calls     []string
+}
+
+func (r *scriptedRunner) Run(_ context.Context, args ...string) (string, string, error) {
+	key := strings.Join(args, " ")
+	r.calls = append(r.calls, key)
+	queue := r.responses[key]
+	if len(queue) == 0 {
+		return "", "", fmt.Errorf("unexpected args: %s", key)
+	}
+	resp := queue[0]
+	if len(queue) > 1 {
+		r.responses[key] = queue[1:]
+	}
+	return resp.stdout, resp.stderr, resp.err
+}
+
+func newJournalTestCommand() (*cobra.Command, *bytes.Buffer) {
+	cmd := &cobra.Command{}
+	out := &bytes.Buffer{}
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	return cmd, out
+}
+
+func TestOpJournalRollsBackInReverse(t *testing.T) {
+	runner := &scriptedRunner{responses: map[string][]fakeResponse{
+		"-C /repo symbolic-ref -q --short HEAD":    {{stdout: "feature"}},
+		"-C /repo checkout main":                   {{}},
+		"-C /repo rev-parse --verify HEAD":         {{stdout: "abc123"}},
+		"-C /repo merge feature":                   {{}},
+		"-C /repo rev-parse --verify refs/heads/x": {{stdout: "def456"}},
+		"-C /repo branch -d x":                     {{err: fmt.Errorf("exit status 1"), stderr: "error: not fully merged"}},
+		"-C /repo reset --merge abc123":            {{}},
+		"-C /repo checkout feature":                {{}},
+	}}
+	journal := newOpJournal("finish")
+	journal.add(checkoutStep("/repo", "main"))
+	journal.add(headMovingStep("/repo", []string{"-C", "/repo", "merge", "feature"}, nil))
+	journal.add(deleteBranchStep("/repo", "x", false))
+
+	cmd, out := newJournalTestCommand()
+	err := journal.run(context.Background(), cmd, runner, false, true)
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("expected rolled back error, got %v", err)
+	}
+	got := strings.Join(runner.calls[len(runner.calls)-2:], "|")
+	if got != "-C /repo reset --merge abc123|-C /repo checkout feature" {
+		t.Fatalf("unexpected rollback order: %s", got)
+	}
+	if !strings.Contains(out.String(), "finish failed at step 3/3") {
+		t.Fatalf("expected failure summary, got:\n%s", out.String())
+	}
+}
+
+func TestOpJournalNoRollbackPrintsResume(t *testing.T) {
+	runner := &scriptedRunner{responses: map[string][]fakeResponse{
+		"-C /repo worktree remove /wt": {{err: fmt.Errorf("exit status 128"), stderr: "fatal: contains modified files"}},
+	}}
+	journal := newOpJournal("cleanup")
+	journal.git("-C", "/repo", "worktree", "remove", "/wt")
+	journal.git("-C", "/repo", "worktree", "prune")
+
+	cmd, out := newJournalTestCommand()
+	err := journal.run(context.Background(), cmd, runner, false, false)
+	if err == nil || !strings.Contains(err.Error(), "cleanup stopped") {
+		t.Fatalf("expected stopped error, got %v", err)
+	}
+	for _, want := range []string{"resume after fixing the cause with:", "  git -C /repo worktree remove /wt", "  git -C /repo worktree prune"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestOpJournalRetriesTransientIdempotentSteps(t *testing.T) {
+	previous := journalRetryDelay
+	journalRetryDelay = 0
+	defer func() { journalRetryDelay = previous }()
+
+	lockErr := fakeResponse{err: fmt.Errorf("exit status 128"), stderr: "fatal: Unable to create '/repo/.git/index.lock': File exists."}
+	runner := &scriptedRunner{responses: map[string][]fakeResponse{
+		"-C /repo worktree prune": {lockErr, {}},
+	}}
+	journal := newOpJournal("cleanup")
+	journal.git("-C", "/repo", "worktree", "prune").idempotent = true
+
+	cmd, _ := newJournalTestCommand()
+	if err := journal.run(context.Background(), cmd, runner, false, true); err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+	if len(runner.calls) != 2 {
+		t.Fatalf("expected 2 attempts, got %v", runner.calls)
+	}
+}
+
+func TestOpJournalDryRunRendersSteps(t *testing.T) {
+	journal := newOpJournal("finish")
+	journal.add(checkoutStep("/repo", "main"))
+	journal.add(deleteBranchStep("/repo", "x", true))
+
+	cmd, out := newJournalTestCommand()
+	if err := journal.run(context.Background(), cmd, &scriptedRunner{}, true, true); err != nil {
+		t.Fatalf("dry-run error = %v", err)
+	}
+	if out.String() != "git -C /repo checkout main\ngit -C /repo branch -D x\n" {
+		t.Fatalf("unexpected dry-run output:\n%s", out.String())
+	}
+}
@@ -17,12 +17,13 @@ import (
 )
 
 type listOptions struct {
-	output string
-	branch string
-	field  string
-	abs    bool
-	grid   bool
-	strict bool
+	output   string
+	branch   string
+	field    string
+	abs      bool
+	grid     bool
+	strict   bool
+	allRepos bool
 }
 
 type listRow struct {
@@ -31,6 +32,7 @@ type listRow struct {
 	Path    string `json:"path"`
 	Present bool   `json:"present"`
 	Head    string `json:"head"`
+	Parked  bool   `json:"parked,omitempty"`
 }
 
 func newListCommand() *cobra.Command {
@@ -42,7 +44,7 @@ func newListCommand() *cobra.Command {
 		Args:    cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			runner := defaultRunner()
+			runner := defaultRunner(ctx)
 			modeCtx, err := resolveModeContext(cmd, true)
 			if err != nil {
 				return err
@@ -69,10 +71,15 @@ func newListCommand() *cobra.Command {
 				}
 			}
 			if mode == modeCodex && opts.output == "raw" && opts.field == "path" && !opts.abs {
-				if cwd, err := os.Getwd(); err == nil {
+				if dir := workDir(ctx); dir != "" {
+					rawBase = dir
+				} else if cwd, err := os.Getwd(); err == nil {
 					rawBase = cwd
 				}
 			}
+			if opts.allRepos {
+				return listAllRepos(cmd, args, opts.output, opts.grid)
+			}
 			repoRoot, err := repoRoot(ctx, runner)
 			if err != nil {
 				return err
@@ -117,6 +124,10 @@ func newListCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			recorded, err := git.BranchConfigValues(ctx, runner, repoRoot, branchTaskKey)
+			if err != nil {
+				return err
+			}
 			shortHashLen, err := worktree.ShortHashLength(ctx, runner, repoRoot)
 			if err != nil {
 				return err
@@ -153,7 +164,7 @@ func newListCommand() *cobra.Command {
 							continue
 						}
 					}
-					task, err = deriveClassicTask(repoRoot, mainWorktree, repo, wt)
+					task, err = deriveClassicTask(repoRoot, mainWorktree, repo, recorded, wt)
 					if err != nil {
 						return err
 					}
@@ -205,6 +216,32 @@ func newListCommand() *cobra.Command {
 				}
 			}
 
+			if mode != modeCodex && opts.output != "raw" && (query == "" || opts.strict || len(rows) == 0) {
+				parked, err := listParkedTasks(ctx, runner, repoRoot)
+				if err != nil {
+					return err
+				}
+				for _, pt := range parked {
+					if query != "" && !matchesTask(pt.task, query, opts.strict) {
+						continue
+					}
+					if opts.branch != "" && pt.branch != opts.branch {
+						continue
+					}
+					head := ""
+					if tip, _, err := runner.Run(ctx, "-C", repoRoot, "rev-parse", "--verify", "--quiet", "refs/heads/"+pt.branch); err == nil {
+						head = worktree.ShortHash(strings.TrimSpace(tip), shortHashLen)
+					}
+					rows = append(rows, listRow{
+						Task:   pt.task,
+						Branch: pt.branch,
+						Path:   displayPath(repoRoot, pt.path, opts.abs),
+						Head:   head,
+						Parked: true,
+					})
+				}
+			}
+
 			if mode != modeCodex && opts.output == "raw" && len(rows) == 0 {
 				fallbackBranch := opts.branch
 				if fallbackBranch == "" {
@@ -239,6 +276,7 @@ func newListCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&opts.abs, "abs", false, "alias for --absolute-path")
 	cmd.Flags().BoolVar(&opts.grid, "grid", false, "render table with grid borders")
 	cmd.Flags().BoolVar(&opts.strict, "strict", false, "require exact task match (after trimming and slugifying)")
+	cmd.Flags().BoolVar(&opts.allRepos, "all-repos", false, "show tasks of every registered or discovered repository")
 
 	return cmd
 }
@@ -246,29 +284,11 @@ func newListCommand() *cobra.Command {
 func renderList(cmd *cobra.Command, format, field string, rows []listRow, grid bool) error {
 	switch format {
 	case "table":
-		columns := []tableColumn{
-			{Header: "TASK", MinWidth: 6},
-			{Header: "BRANCH", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.AccentStyle }},
-			{Header: "PATH", MinWidth: 16, Flexible: true, Truncate: true},
-			{Header: "PRESENT", MinWidth: 7, Style: func(value string) lipgloss.Style {
-				if value == "true" {
-					return ui.SuccessStyle
-				}
-				return ui.ErrorStyle
-			}},
-			{Header: "HEAD", MinWidth: 7, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
-		}
 		tableRows := make([][]string, 0, len(rows))
 		for _, row := range rows {
-			tableRows = append(tableRows, []string{
-				row.Task,
-				row.Branch,
-				row.Path,
-				strconv.FormatBool(row.Present),
-				row.Head,
-			})
+			tableRows = append(tableRows, listRowValues(row))
 		}
-		renderTable(cmd, columns, tableRows, grid)
+		renderTable(cmd, listColumns(), tableRows, grid)
 		return nil
 	case "json":
 		payload, err := json.MarshalIndent(rows, "", "  ")
@@ -285,13 +305,7 @@ func renderList(cmd *cobra.Command, format, field string, rows []listRow, grid b
 			return err
 		}
 		for _, row := range rows {
-			if err := writer.Write([]string{
-				row.Task,
-				row.Branch,
-				row.Path,
-				strconv.FormatBool(row.Present),
-				row.Head,
-			}); err != nil {
+			if err := writer.Write(listRowValues(row)); err != nil {
 				return err
 			}
 		}
@@ -310,6 +324,38 @@ func renderList(cmd *cobra.Command, format, field string, rows []listRow, grid b
 	}
 }
 
+func listColumns() []tableColumn {
+	return []tableColumn{
+		{Header: "TASK", MinWidth: 6},
+		{Header: "BRANCH", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.AccentStyle }},
+		{Header: "PATH", MinWidth: 16, Flexible: true, Truncate: true},
+		{Header: "PRESENT", MinWidth: 7, Style: func(value string) lipgloss.Style {
+			switch value {
+			case "true":
+				return ui.SuccessStyle
+			case parkedState:
+				return ui.WarningStyle
+			}
+			return ui.ErrorStyle
+		}},
+		{Header: "HEAD", MinWidth: 7, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+	}
+}
+
+func listRowValues(row listRow) []string {
+	present := strconv.FormatBool(row.Present)
+	if row.Parked {
+		present = parkedState
+	}
+	return []string{
+		row.Task,
+		row.Branch,
+		row.Path,
+		present,
+		row.Head,
+	}
+}
+
 func normalizeListField(value string) (string, error) {
 	value = strings.TrimSpace(strings.ToLower(value))
 	if value == "" {
@@ -0,0 +1,369 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/backup"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+type moveOptions struct {
+	relayout   bool
+	dryRun     bool
+	noRollback bool
+}
+
+// taskMove relocates one task worktree. branch and newBranch are equal when
+// the branch keeps its name. recorded marks branches whose task name is kept
+// in branch config by `create --preset`.
+type taskMove struct {
+	task      string
+	newTask   string
+	branch    string
+	newBranch string
+	from      string
+	to        string
+	recorded  bool
+}
+
+func newMoveCommand() *cobra.Command {
+	opts := &moveOptions{}
+	cmd := &cobra.Command{
+		Use:     "mv <task> <new-task>",
+		Aliases: []string{"move"},
+		Short:   "Rename a task: move its worktree and rename its branch",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if opts.relayout {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			modeCtx, err := resolveModeContext(cmd, true)
+			if err != nil {
+				return err
+			}
+			if modeCtx.mode == modeCodex {
+				return fmt.Errorf("mv is not supported in --mode=codex (Codex manages its worktree paths)")
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			repo, err := git.RepoBaseName(ctx, runner)
+			if err != nil {
+				return err
+			}
+			// Steps run against the main worktree: the task worktree itself
+			// may be the one being moved.
+			mainWorktree, err := mainWorktreePath(ctx, runner, repoRoot)
+			if err != nil {
+				return err
+			}
+			worktrees, err := worktree.List(ctx, runner, mainWorktree)
+			if err != nil {
+				return err
+			}
+
+			var moves []taskMove
+			if opts.relayout {
+				moves, err = planRelayout(ctx, cmd, runner, mainWorktree, repo, modeCtx.codexWorktrees, worktrees)
+			} else {
+				var move taskMove
+				move, err = planTaskRename(ctx, runner, mainWorktree, repo, worktrees, args[0], args[1])
+				moves = []taskMove{move}
+			}
+			if err != nil {
+				return err
+			}
+			if len(moves) == 0 {
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render("all worktrees already follow the configured layout"))
+				return err
+			}
+			if err := checkMoveTargets(moves); err != nil {
+				return err
+			}
+
+			return runTaskMoves(ctx, cmd, runner, mainWorktree, moves, opts.dryRun, !opts.noRollback)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.relayout, "relayout", false, "move every task worktree to the configured [create.path] layout")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
+	cmd.Flags().BoolVar(&opts.noRollback, "no-rollback", false, "on failure, stop and print the commands that resume the operation")
+	return cmd
+}
+
+// runTaskMoves moves the worktrees and renames the branches as one journal,
+// then points safety backups at the new paths.
+func runTaskMoves(ctx context.Context, cmd *cobra.Command, runner git.Runner, mainWorktree string, moves []taskMove, dryRun, rollback bool) error {
+	maskPaths := shouldMaskSensitivePaths(ctx)
+	journal := newOpJournal("mv")
+	for _, move := range moves {
+		parent := filepath.Dir(move.to)
+		if _, err := os.Stat(parent); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			if dryRun {
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "mkdir %s\n", maskPathForDryRun(parent, maskPaths)); err != nil {
+					return err
+				}
+			} else if err := os.MkdirAll(parent, 0o755); err != nil {
+				return err
+			}
+		}
+		journal.add(&journalStep{
+			args: []string{"-C", mainWorktree, "worktree", "move", move.from, move.to},
+			undo: [][]string{{"-C", mainWorktree, "worktree", "move", move.to, move.from}},
+		})
+		if move.branch != move.newBranch {
+			journal.add(&journalStep{
+				args: []string{"-C", mainWorktree, "branch", "-m", move.branch, move.newBranch},
+				undo: [][]string{{"-C", mainWorktree, "branch", "-m", move.newBranch, move.branch}},
+			})
+		}
+		if move.recorded && move.task != move.newTask {
+			key := "branch." + move.newBranch + "." + branchTaskKey
+			journal.add(&journalStep{
+				args: []string{"-C", mainWorktree, "config", key, move.newTask},
+				undo: [][]string{{"-C", mainWorktree, "config", key, move.task}},
+			})
+		}
+	}
+	if err := restackRenamedParents(ctx, runner, mainWorktree, journal, moves); err != nil {
+		return err
+	}
+	if err := journal.run(ctx, cmd, runner, dryRun, rollback); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	for _, move := range moves {
+		if err := printTaskMove(cmd, mainWorktree, move); err != nil {
+			return err
+		}
+	}
+	return relocateBackups(ctx, cmd, runner, mainWorktree, moves)
+}
+
+// restackRenamedParents points the tasks stacked on a renamed branch at its
+// new name. The steps go last, so a child renamed in the same run is
+// addressed by its new name.
+func restackRenamedParents(ctx context.Context, runner git.Runner, mainWorktree string, journal *opJournal, moves []taskMove) error {
+	renamed := map[string]string{}
+	for _, move := range moves {
+		if move.branch != move.newBranch {
+			renamed[move.branch] = move.newBranch
+		}
+	}
+	if len(renamed) == 0 {
+		return nil
+	}
+	stack, err := loadTaskStack(ctx, runner, mainWorktree)
+	if err != nil {
+		return err
+	}
+	for _, move := range moves {
+		if move.branch == move.newBranch {
+			continue
+		}
+		for _, child := range stack.children(move.branch) {
+			if newChild, ok := renamed[child]; ok {
+				child = newChild
+			}
+			journal.add(retargetStep(mainWorktree, child, move.branch, move.newBranch))
+		}
+	}
+	return nil
+}
+
+func planTaskRename(ctx context.Context, runner git.Runner, mainWorktree, repo string, worktrees []worktree.Worktree, rawTask, rawNewTask string) (taskMove, error) {
+	task := worktree.SlugifyTask(rawTask)
+	newTask := worktree.SlugifyTask(rawNewTask)
+	if task == newTask {
+		return taskMove{}, fmt.Errorf("task is already named %q", task)
+	}
+	ref, err := resolveTaskRef(ctx, runner, mainWorktree, task)
+	if err != nil {
+		return taskMove{}, err
+	}
+
+	var match *worktree.Worktree
+	for idx, wt := range worktrees {
+		if idx == 0 || wt.Bare || wt.Prunable {
+			continue
+		}
+		if strings.TrimPrefix(wt.Branch, "refs/heads/") == ref.branch {
+			match = &worktrees[idx]
+			break
+		}
+		derived, err := deriveClassicTask(mainWorktree, mainWorktree, repo, nil, wt)
+		if err != nil {
+			return taskMove{}, err
+		}
+		if derived == task && match == nil {
+			match = &worktrees[idx]
+		}
+	}
+	if match == nil {
+		return taskMove{}, fmt.Errorf("no worktree found for task %q", task)
+	}
+	if match.Locked {
+		return taskMove{}, fmt.Errorf("worktree %s is locked; unlock it before moving", displayPath(mainWorktree, match.Path, false))
+	}
+
+	from, err := worktree.NormalizePath(mainWorktree, match.Path)
+	if err != nil {
+		return taskMove{}, err
+	}
+	move := taskMove{
+		task:    task,
+		newTask: newTask,
+		from:    from,
+		to:      taskRefWorktreePath(ctx, mainWorktree, repo, newTask, ref),
+	}
+	move.branch = strings.TrimPrefix(match.Branch, "refs/heads/")
+	move.newBranch = move.branch
+	move.recorded = move.branch == ref.branch && ref.preset != ""
+	// Rename branches named after the task, keeping a preset branch prefix.
+	if prefix, ok := strings.CutSuffix(move.branch, task); ok && (prefix == "" || move.recorded) {
+		exists, err := git.BranchExists(ctx, runner, mainWorktree, prefix+newTask)
+		if err != nil {
+			return taskMove{}, err
+		}
+		if exists {
+			return taskMove{}, fmt.Errorf("branch %q already exists", prefix+newTask)
+		}
+		move.newBranch = prefix + newTask
+	}
+	return move, nil
+}
+
+// planRelayout lists the task worktrees whose path no longer matches the
+// configured layout, or the layout of the preset they were created with.
+// Locked worktrees are left in place with a warning.
+func planRelayout(ctx context.Context, cmd *cobra.Command, runner git.Runner, mainWorktree, repo, codexWorktrees string, worktrees []worktree.Worktree) ([]taskMove, error) {
+	tasks, err := git.BranchConfigValues(ctx, runner, mainWorktree, branchTaskKey)
+	if err != nil {
+		return nil, err
+	}
+	presets, err := git.BranchConfigValues(ctx, runner, mainWorktree, branchPresetKey)
+	if err != nil {
+		return nil, err
+	}
+	var moves []taskMove
+	for idx, wt := range worktrees {
+		if idx == 0 || wt.Bare || wt.Prunable {
+			continue
+		}
+		from, err := worktree.NormalizePath(mainWorktree, wt.Path)
+		if err != nil {
+			return nil, err
+		}
+		if codexWorktrees != "" {
+			if _, _, ok := codexWorktreeInfo(codexWorktrees, from); ok {
+				continue
+			}
+		}
+		branch := strings.TrimPrefix(wt.Branch, "refs/heads/")
+		task, ok := tasks[branch]
+		if !ok {
+			task, err = deriveClassicTask(mainWorktree, mainWorktree, repo, nil, wt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if task == "" {
+			continue
+		}
+		to := taskRefWorktreePath(ctx, mainWorktree, repo, task, taskRef{branch: branch, preset: presets[branch]})
+		if from == to {
+			continue
+		}
+		if wt.Locked {
+			if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "warning: skipping locked worktree %s\n", displayPath(mainWorktree, from, false)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		moves = append(moves, taskMove{task: task, newTask: task, branch: branch, newBranch: branch, from: from, to: to})
+	}
+	return moves, nil
+}
+
+// checkMoveTargets rejects moves onto existing paths, or several tasks onto
+// one path, before anything is touched.
+func checkMoveTargets(moves []taskMove) error {
+	seen := map[string]string{}
+	for _, move := range moves {
+		if other, ok := seen[move.to]; ok {
+			return fmt.Errorf("tasks %q and %q would both move to %s", other, move.task, move.to)
+		}
+		seen[move.to] = move.task
+		if _, err := os.Lstat(move.to); err == nil {
+			return fmt.Errorf("worktree path already exists: %s", move.to)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func printTaskMove(cmd *cobra.Command, repoRoot string, move taskMove) error {
+	line := fmt.Sprintf("%s: %s -> %s",
+		ui.SuccessStyle.Render("moved"),
+		ui.AccentStyle.Render(displayPath(repoRoot, move.from, false)),
+		ui.AccentStyle.Render(displayPath(repoRoot, move.to, false)),
+	)
+	if move.branch != move.newBranch {
+		line += fmt.Sprintf(" (branch: %s -> %s)", ui.AccentStyle.Render(move.branch), ui.AccentStyle.Render(move.newBranch))
+	}
+	_, err := fmt.Fprintln(cmd.OutOrStdout(), line)
+	return err
+}
+
+// relocateBackups points safety backups taken in a moved worktree at its new
+// path, task and branch so `gwtt restore` keeps working.
+func relocateBackups(ctx context.Context, cmd *cobra.Command, runner git.Runner, mainWorktree string, moves []taskMove) error {
+	commonDir, err := git.CommonDirAt(ctx, runner, mainWorktree)
+	if err != nil {
+		return err
+	}
+	updated, err := backup.Update(commonDir, func(b *backup.Backup) bool {
+		for _, move := range moves {
+			if filepath.Clean(b.Path) != filepath.Clean(move.from) {
+				continue
+			}
+			b.Path = move.to
+			if b.Task == move.task {
+				b.Task = move.newTask
+			}
+			if b.Branch == move.branch {
+				b.Branch = move.newBranch
+			}
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return fmt.Errorf("update backup metadata: %w", err)
+	}
+	if updated > 0 {
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render(fmt.Sprintf("updated %d backup(s) to the new path", updated)))
+	}
+	return err
+}
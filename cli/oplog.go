@@ -0,0 +1,482 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+const (
+	opLogDir  = "gwtt/operations"
+	opLogFile = "log.jsonl"
+)
+
+// Operation outcomes recorded in the log.
+const (
+	opSucceeded  = "ok"
+	opRolledBack = "rolled-back"
+	opStopped    = "stopped"
+)
+
+// deletedBranch is what `undo` needs to bring back a branch an operation
+// deleted: its tip, the worktree it was checked out in and its gwtt branch
+// config (task, preset, stack parent).
+type deletedBranch struct {
+	Branch   string            `json:"branch"`
+	Tip      string            `json:"tip"`
+	Worktree string            `json:"worktree,omitempty"`
+	Config   map[string]string `json:"config,omitempty"`
+	// Parked is the WIP commit of a parked task whose parked ref went with
+	// the branch.
+	Parked string `json:"parked,omitempty"`
+}
+
+// opRecord is one line of the operations log. An undo is logged as its own
+// record pointing at the operation it reverted.
+type opRecord struct {
+	ID       string          `json:"id"`
+	Time     string          `json:"time"`
+	Command  string          `json:"command"`
+	Task     string          `json:"task,omitempty"`
+	Outcome  string          `json:"outcome"`
+	Error    string          `json:"error,omitempty"`
+	Undoes   string          `json:"undoes,omitempty"`
+	Branches []deletedBranch `json:"branches,omitempty"`
+	// UndoneBy is filled in when the log is read, not stored.
+	UndoneBy string `json:"undone_by,omitempty"`
+}
+
+func opLogPath(ctx context.Context, runner git.Runner, repoRoot string) (string, error) {
+	commonDir, err := git.CommonDirAt(ctx, runner, repoRoot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(commonDir, filepath.FromSlash(opLogDir), opLogFile), nil
+}
+
+func newOpID() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// captureDeletedBranch records branch before an operation deletes it.
+func captureDeletedBranch(ctx context.Context, runner git.Runner, repoRoot, branch, worktreePath string) (deletedBranch, error) {
+	tip, err := git.RevParse(ctx, runner, repoRoot, "refs/heads/"+branch)
+	if err != nil {
+		return deletedBranch{}, err
+	}
+	deleted := deletedBranch{Branch: branch, Tip: tip, Worktree: worktreePath}
+	for _, key := range []string{branchTaskKey, branchPresetKey, branchParentKey, branchParentBaseKey} {
+		values, err := git.BranchConfigValues(ctx, runner, repoRoot, key)
+		if err != nil {
+			return deletedBranch{}, err
+		}
+		if value, ok := values[branch]; ok {
+			if deleted.Config == nil {
+				deleted.Config = map[string]string{}
+			}
+			deleted.Config[key] = value
+		}
+	}
+	return deleted, nil
+}
+
+// recordBranchDeletion makes step, a deleteBranchStep, capture the branch
+// right before it runs, so a finish --rebase records the tip that was
+// actually deleted. The record reaches deleted only once the branch is gone.
+func recordBranchDeletion(step *journalStep, repoRoot, branch, worktreePath string, deleted *[]deletedBranch) *journalStep {
+	snapshot := step.snapshot
+	var record deletedBranch
+	step.snapshot = func(ctx context.Context, runner git.Runner) ([][]string, error) {
+		var err error
+		record, err = captureDeletedBranch(ctx, runner, repoRoot, branch, worktreePath)
+		if err != nil {
+			return nil, err
+		}
+		return snapshot(ctx, runner)
+	}
+	step.done = func() {
+		*deleted = append(*deleted, record)
+	}
+	return step
+}
+
+// journalOutcome maps the result of opJournal.run to a logged outcome. A
+// canceled prompt changed nothing and is not logged.
+func journalOutcome(err error, rollback bool) (string, bool) {
+	switch {
+	case err == nil:
+		return opSucceeded, true
+	case errors.Is(err, errCanceled):
+		return "", false
+	case rollback:
+		return opRolledBack, true
+	default:
+		return opStopped, true
+	}
+}
+
+// logOperation appends an operation with the outcome of runErr to the log
+// and returns its ID. Failing to log only warns: the operation itself
+// already happened.
+func logOperation(cmd *cobra.Command, runner git.Runner, repoRoot, command, task string, branches []deletedBranch, runErr error, rollback bool) string {
+	outcome, ok := journalOutcome(runErr, rollback)
+	if !ok {
+		return ""
+	}
+	record := opRecord{
+		ID:       newOpID(),
+		Time:     time.Now().UTC().Format(time.RFC3339),
+		Command:  command,
+		Task:     task,
+		Outcome:  outcome,
+		Branches: branches,
+	}
+	if runErr != nil {
+		record.Error = runErr.Error()
+	}
+	if err := appendOperation(cmd.Context(), runner, repoRoot, record); err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s failed to record the operation: %v\n", ui.WarningStyle.Render("warning:"), err)
+		return ""
+	}
+	return record.ID
+}
+
+func appendOperation(ctx context.Context, runner git.Runner, repoRoot string, record opRecord) error {
+	path, err := opLogPath(ctx, runner, repoRoot)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// loadOperations reads the log oldest first and marks undone operations.
+func loadOperations(ctx context.Context, runner git.Runner, repoRoot string) ([]opRecord, error) {
+	path, err := opLogPath(ctx, runner, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []opRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record opRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("read operations log %s line %d: %w", path, line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	byID := map[string]int{}
+	for idx, record := range records {
+		byID[record.ID] = idx
+	}
+	for _, record := range records {
+		if record.Undoes == "" || record.Outcome != opSucceeded {
+			continue
+		}
+		if idx, ok := byID[record.Undoes]; ok {
+			records[idx].UndoneBy = record.ID
+		}
+	}
+	return records, nil
+}
+
+type historyOptions struct {
+	limit  int
+	output string
+	grid   bool
+}
+
+func newHistoryCommand() *cobra.Command {
+	opts := &historyOptions{limit: 20, output: "table"}
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List recent gwtt operations and their outcomes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if opts.output != "table" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format: %s", opts.output)
+			}
+			if cfg, ok := configFromContext(ctx); ok && !cmd.Flags().Changed("grid") {
+				opts.grid = cfg.Status.Grid
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			records, err := loadOperations(ctx, runner, repoRoot)
+			if err != nil {
+				return err
+			}
+			if opts.limit > 0 && len(records) > opts.limit {
+				records = records[len(records)-opts.limit:]
+			}
+			// Newest first.
+			for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+				records[i], records[j] = records[j], records[i]
+			}
+
+			if opts.output == "json" {
+				if records == nil {
+					records = []opRecord{}
+				}
+				payload, err := json.MarshalIndent(records, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+				return err
+			}
+			if len(records) == 0 {
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render("no gwtt operations recorded"))
+				return err
+			}
+			rows := make([][]string, 0, len(records))
+			for _, record := range records {
+				outcome := record.Outcome
+				if record.UndoneBy != "" {
+					outcome = "undone by " + record.UndoneBy
+				}
+				target := record.Task
+				if record.Undoes != "" {
+					target = record.Undoes
+				}
+				branches := make([]string, 0, len(record.Branches))
+				for _, deleted := range record.Branches {
+					branches = append(branches, deleted.Branch+"@"+worktree.ShortHash(deleted.Tip, 7))
+				}
+				rows = append(rows, []string{record.ID, record.Time, record.Command, target, outcome, strings.Join(branches, ", ")})
+			}
+			renderTable(cmd, []tableColumn{
+				{Header: "ID", MinWidth: 8},
+				{Header: "TIME", MinWidth: 20, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+				{Header: "COMMAND", MinWidth: 7, Style: func(value string) lipgloss.Style { return ui.AccentStyle }},
+				{Header: "TASK", MinWidth: 6, Flexible: true, Truncate: true},
+				{Header: "OUTCOME", MinWidth: 7, Style: func(value string) lipgloss.Style {
+					switch {
+					case value == opSucceeded:
+						return ui.SuccessStyle
+					case strings.HasPrefix(value, "undone"):
+						return ui.MutedStyle
+					}
+					return ui.ErrorStyle
+				}},
+				{Header: "DELETED_BRANCHES", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+			}, rows, opts.grid)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&opts.limit, "limit", "n", opts.limit, "number of operations to show (0 for all)")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "output format: table or json")
+	cmd.Flags().BoolVar(&opts.grid, "grid", false, "render table with grid borders")
+
+	return cmd
+}
+
+type undoOptions struct {
+	dryRun     bool
+	noRollback bool
+}
+
+func newUndoCommand() *cobra.Command {
+	opts := &undoOptions{}
+	cmd := &cobra.Command{
+		Use:   "undo [op-id]",
+		Short: "Recreate the branches and worktrees a cleanup or finish deleted",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			records, err := loadOperations(ctx, runner, repoRoot)
+			if err != nil {
+				return err
+			}
+			record, err := selectUndoable(records, args)
+			if err != nil {
+				return err
+			}
+
+			journal := newOpJournal("undo")
+			restored := 0
+			// Reverse order, so a stack comes back parents first.
+			for idx := len(record.Branches) - 1; idx >= 0; idx-- {
+				deleted := record.Branches[idx]
+				steps, err := restoreBranchSteps(ctx, runner, repoRoot, record.Task, deleted)
+				if err != nil {
+					return err
+				}
+				if len(steps) == 0 {
+					if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "%s %s already exists at %s; left as it is\n", ui.WarningStyle.Render("warning:"), deleted.Branch, worktree.ShortHash(deleted.Tip, 7)); err != nil {
+						return err
+					}
+					continue
+				}
+				for _, step := range steps {
+					journal.add(step)
+				}
+				restored++
+			}
+			if restored == 0 {
+				return fmt.Errorf("nothing to undo for %s: every deleted branch already exists", record.ID)
+			}
+
+			runErr := journal.run(ctx, cmd, runner, opts.dryRun, !opts.noRollback)
+			if opts.dryRun {
+				return runErr
+			}
+			outcome, _ := journalOutcome(runErr, !opts.noRollback)
+			undo := opRecord{
+				ID:      newOpID(),
+				Time:    time.Now().UTC().Format(time.RFC3339),
+				Command: "undo",
+				Outcome: outcome,
+				Undoes:  record.ID,
+			}
+			if runErr != nil {
+				undo.Error = runErr.Error()
+			}
+			if err := appendOperation(ctx, runner, repoRoot, undo); err != nil {
+				if runErr != nil {
+					return runErr
+				}
+				return fmt.Errorf("record undo: %w", err)
+			}
+			if runErr != nil {
+				return runErr
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s %s %s\n",
+				ui.SuccessStyle.Render("undid"),
+				ui.AccentStyle.Render(record.ID),
+				ui.MutedStyle.Render(fmt.Sprintf("(%s %s: restored %d branch(es))", record.Command, record.Task, restored)),
+			)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
+	cmd.Flags().BoolVar(&opts.noRollback, "no-rollback", false, "on failure, stop and print resume commands instead of rolling back")
+
+	return cmd
+}
+
+// selectUndoable returns the operation args names, or the newest one that
+// deleted branches and has not been undone.
+func selectUndoable(records []opRecord, args []string) (opRecord, error) {
+	if len(args) == 1 {
+		for _, record := range records {
+			if record.ID != args[0] {
+				continue
+			}
+			switch {
+			case len(record.Branches) == 0:
+				return opRecord{}, fmt.Errorf("operation %s (%s) deleted no branches; nothing to undo", record.ID, record.Command)
+			case record.UndoneBy != "":
+				return opRecord{}, fmt.Errorf("operation %s was already undone by %s", record.ID, record.UndoneBy)
+			case record.Outcome == opRolledBack:
+				return opRecord{}, fmt.Errorf("operation %s was rolled back; nothing to undo", record.ID)
+			}
+			return record, nil
+		}
+		return opRecord{}, fmt.Errorf("no operation %q in the log (see gwtt history)", args[0])
+	}
+	for idx := len(records) - 1; idx >= 0; idx-- {
+		record := records[idx]
+		if len(record.Branches) > 0 && record.UndoneBy == "" && record.Outcome != opRolledBack {
+			return record, nil
+		}
+	}
+	return opRecord{}, fmt.Errorf("no operation to undo (see gwtt history)")
+}
+
+// restoreBranchSteps recreates deleted at its recorded tip with its branch
+// config, its parked work and its worktree. It returns nothing when the
+// branch exists again.
+func restoreBranchSteps(ctx context.Context, runner git.Runner, repoRoot, task string, deleted deletedBranch) ([]*journalStep, error) {
+	exists, err := git.BranchExists(ctx, runner, repoRoot, deleted.Branch)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, nil
+	}
+	steps := []*journalStep{{
+		args: []string{"-C", repoRoot, "branch", deleted.Branch, deleted.Tip},
+		undo: [][]string{{"-C", repoRoot, "branch", "-D", deleted.Branch}},
+	}}
+	for _, key := range []string{branchTaskKey, branchPresetKey, branchParentKey, branchParentBaseKey} {
+		if value, ok := deleted.Config[key]; ok {
+			steps = append(steps, &journalStep{args: []string{"-C", repoRoot, "config", "branch." + deleted.Branch + "." + key, value}})
+		}
+	}
+	if deleted.Parked != "" {
+		parkedRef := parkedRefPrefix + task
+		steps = append(steps, &journalStep{
+			args: []string{"-C", repoRoot, "update-ref", parkedRef, deleted.Parked},
+			undo: [][]string{{"-C", repoRoot, "update-ref", "-d", parkedRef}},
+		})
+	}
+	if deleted.Worktree != "" {
+		if _, err := os.Stat(deleted.Worktree); err == nil {
+			return nil, fmt.Errorf("cannot restore the worktree of %s: %s already exists", deleted.Branch, deleted.Worktree)
+		}
+		steps = append(steps, &journalStep{
+			args: []string{"-C", repoRoot, "worktree", "add", deleted.Worktree, deleted.Branch},
+			undo: [][]string{{"-C", repoRoot, "worktree", "remove", "--force", deleted.Worktree}},
+		})
+	}
+	return steps, nil
+}
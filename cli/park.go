@@ -0,0 +1,332 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+// parkedRefPrefix holds one WIP commit per parked task. With uncommitted
+// changes it is a stash commit (HEAD, index and untracked parents); a clean
+// task gets a commit with HEAD as its only parent.
+const parkedRefPrefix = "refs/gwtt/parked/"
+
+// parkedState is shown by list and status for parked tasks.
+const parkedState = "PARKED"
+
+type parkOptions struct {
+	dryRun bool
+}
+
+// parkedTask is a task whose worktree was removed by `gwtt park`.
+type parkedTask struct {
+	task   string
+	branch string
+	wip    string
+	path   string
+}
+
+func newParkCommand() *cobra.Command {
+	opts := &parkOptions{}
+	cmd := &cobra.Command{
+		Use:   "park <task>",
+		Short: "Save a task's uncommitted work and remove its worktree, keeping the branch",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if cfg, ok := configFromContext(ctx); ok && cfg.Mode == modeCodex {
+				return fmt.Errorf("park is not supported in --mode=codex")
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			task := worktree.SlugifyTask(args[0])
+			ref, err := resolveTaskRef(ctx, runner, repoRoot, task)
+			if err != nil {
+				return err
+			}
+			branch := ref.branch
+			path, found, err := taskBranchWorktree(ctx, runner, repoRoot, branch)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("task %q has no worktree to park", task)
+			}
+			current, err := worktree.NormalizePath(repoRoot, repoRoot)
+			if err != nil {
+				return err
+			}
+			if current == path {
+				return fmt.Errorf("cannot park the worktree you are in; run gwtt park from another checkout")
+			}
+			if _, parked, err := parkedCommit(ctx, runner, repoRoot, task); err != nil {
+				return err
+			} else if parked {
+				return fmt.Errorf("task %q is already parked", task)
+			}
+			forceRemove, err := checkParkRemoval(ctx, runner, path)
+			if err != nil {
+				return fmt.Errorf("%w (park does not save work inside submodules; commit and push it first)", err)
+			}
+			removeArgs := []string{"-C", repoRoot, "worktree", "remove"}
+			if forceRemove {
+				removeArgs = append(removeArgs, "--force")
+			}
+			removeArgs = append(removeArgs, path)
+
+			parkedRef := parkedRefPrefix + task
+			message := "gwtt park " + task
+			if opts.dryRun {
+				for _, args := range [][]string{
+					{"-C", path, "stash", "push", "--include-untracked", "-m", message},
+					{"-C", repoRoot, "update-ref", parkedRef, "refs/stash"},
+					{"-C", path, "stash", "drop", "--quiet"},
+					removeArgs,
+				} {
+					if err := runGit(ctx, cmd, true, runner, args...); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			dirty, err := isDirty(ctx, runner, path)
+			if err != nil {
+				return err
+			}
+			wip, err := saveParkedWork(ctx, runner, repoRoot, path, parkedRef, message, dirty)
+			if err != nil {
+				return err
+			}
+			if _, stderr, err := runner.Run(ctx, removeArgs...); err != nil {
+				cause := fmt.Errorf("%s: %w: %s", formatGitCommand(removeArgs), err, stderr)
+				if dirty {
+					if _, stderr, err := runner.Run(ctx, "-C", path, "stash", "apply", "--index", wip); err != nil {
+						return fmt.Errorf("%w (restoring the work failed: %v: %s; it is kept at %s)", cause, err, stderr, parkedRef)
+					}
+				}
+				if _, stderr, err := runner.Run(ctx, "-C", repoRoot, "update-ref", "-d", parkedRef, wip); err != nil {
+					return fmt.Errorf("%w (removing %s failed: %v: %s)", cause, parkedRef, err, stderr)
+				}
+				return fmt.Errorf("%w (the task was left as it was)", cause)
+			}
+
+			state := "clean"
+			if dirty {
+				state = "uncommitted work saved"
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s %s %s\n",
+				ui.SuccessStyle.Render("parked"),
+				ui.AccentStyle.Render(task),
+				ui.MutedStyle.Render(fmt.Sprintf("(branch: %s; %s at %s)", branch, state, parkedRef)),
+			)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
+
+	return cmd
+}
+
+// saveParkedWork records the worktree state at parkedRef and returns the WIP
+// commit. Dirty work is stashed with untracked files, then the stash entry
+// is dropped so only the parked ref keeps it.
+func saveParkedWork(ctx context.Context, runner git.Runner, repoRoot, path, parkedRef, message string, dirty bool) (string, error) {
+	var wip string
+	if dirty {
+		if _, stderr, err := runner.Run(ctx, "-C", path, "stash", "push", "--include-untracked", "-m", message); err != nil {
+			return "", fmt.Errorf("stash task changes: %w: %s", err, stderr)
+		}
+		stdout, stderr, err := runner.Run(ctx, "-C", path, "rev-parse", "refs/stash")
+		if err != nil {
+			return "", fmt.Errorf("read stash: %w: %s", err, stderr)
+		}
+		wip = strings.TrimSpace(stdout)
+		if _, stderr, err := runner.Run(ctx, "-C", repoRoot, "update-ref", parkedRef, wip); err != nil {
+			return "", fmt.Errorf("record %s: %w: %s (the changes are in stash@{0})", parkedRef, err, stderr)
+		}
+		if _, stderr, err := runner.Run(ctx, "-C", path, "stash", "drop", "--quiet"); err != nil {
+			return "", fmt.Errorf("drop stash entry: %w: %s (the changes are also at %s)", err, stderr, parkedRef)
+		}
+		return wip, nil
+	}
+	stdout, stderr, err := runner.Run(ctx, "-C", path, "commit-tree", "HEAD^{tree}", "-p", "HEAD", "-m", message+" (clean)")
+	if err != nil {
+		return "", fmt.Errorf("record parked HEAD: %w: %s", err, stderr)
+	}
+	wip = strings.TrimSpace(stdout)
+	if _, stderr, err := runner.Run(ctx, "-C", repoRoot, "update-ref", parkedRef, wip); err != nil {
+		return "", fmt.Errorf("record %s: %w: %s", parkedRef, err, stderr)
+	}
+	return wip, nil
+}
+
+func newResumeCommand() *cobra.Command {
+	var dryRun, ontoNewTip bool
+	cmd := &cobra.Command{
+		Use:   "resume <task>",
+		Short: "Recreate a parked task's worktree and restore its uncommitted work",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if cfg, ok := configFromContext(ctx); ok && cfg.Mode == modeCodex {
+				return fmt.Errorf("resume is not supported in --mode=codex")
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			repo, err := git.RepoBaseName(ctx, runner)
+			if err != nil {
+				return err
+			}
+			task := worktree.SlugifyTask(args[0])
+			wip, parked, err := parkedCommit(ctx, runner, repoRoot, task)
+			if err != nil {
+				return err
+			}
+			if !parked {
+				return fmt.Errorf("task %q is not parked", task)
+			}
+			ref, err := resolveTaskRef(ctx, runner, repoRoot, task)
+			if err != nil {
+				return err
+			}
+			branch := ref.branch
+			exists, err := git.BranchExists(ctx, runner, repoRoot, branch)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("branch %s of parked task %q no longer exists (its work is at %s)", branch, task, parkedRefPrefix+task)
+			}
+			if other, found, err := taskBranchWorktree(ctx, runner, repoRoot, branch); err != nil {
+				return err
+			} else if found {
+				return fmt.Errorf("branch %s is checked out at %s; remove that worktree first", branch, other)
+			}
+			path := taskRefWorktreePath(ctx, repoRoot, repo, task, ref)
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("worktree path already exists: %s", path)
+			}
+
+			stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "rev-list", "--parents", "-n", "1", wip)
+			if err != nil {
+				return fmt.Errorf("read parked work: %w: %s", err, stderr)
+			}
+			parents := strings.Fields(stdout)[1:]
+			tip, stderr, err := runner.Run(ctx, "-C", repoRoot, "rev-parse", "refs/heads/"+branch)
+			if err != nil {
+				return fmt.Errorf("resolve %s: %w: %s", branch, err, stderr)
+			}
+			// Uncommitted work only comes back exactly on the commit it was
+			// parked on; applying it elsewhere is opt-in.
+			if len(parents) > 1 && strings.TrimSpace(tip) != parents[0] {
+				if !ontoNewTip {
+					return fmt.Errorf("%s moved since %q was parked (%s -> %s); rerun with --onto-new-tip to apply the parked work onto the new tip", branch, task, worktree.ShortHash(parents[0], 7), worktree.ShortHash(strings.TrimSpace(tip), 7))
+				}
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "%s %s moved since it was parked; applying the parked work onto its new tip\n", ui.WarningStyle.Render("warning:"), branch); err != nil {
+					return err
+				}
+			}
+			addArgs := buildCreateWorktreeArgs(repoRoot, path, branch, "", true, false)
+			applyArgs := []string{"-C", path, "stash", "apply", "--index", wip}
+			dropArgs := []string{"-C", repoRoot, "update-ref", "-d", parkedRefPrefix + task, wip}
+			if dryRun {
+				steps := [][]string{addArgs}
+				if len(parents) > 1 {
+					steps = append(steps, applyArgs)
+				}
+				for _, args := range append(steps, dropArgs) {
+					if err := runGit(ctx, cmd, true, runner, args...); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			if _, stderr, err := runner.Run(ctx, addArgs...); err != nil {
+				return fmt.Errorf("create worktree: %w: %s", err, stderr)
+			}
+			if len(parents) > 1 {
+				if _, stderr, err := runner.Run(ctx, applyArgs...); err != nil {
+					cause := fmt.Errorf("restore parked work: %w: %s", err, stderr)
+					if _, stderr, err := runner.Run(ctx, "-C", repoRoot, "worktree", "remove", "--force", path); err != nil {
+						return fmt.Errorf("%w (removing the new worktree failed: %v: %s)", cause, err, stderr)
+					}
+					return fmt.Errorf("%w (the task is still parked)", cause)
+				}
+			}
+			if _, stderr, err := runner.Run(ctx, dropArgs...); err != nil {
+				return fmt.Errorf("remove %s: %w: %s", parkedRefPrefix+task, err, stderr)
+			}
+
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s: %s (branch: %s)\n",
+				ui.SuccessStyle.Render("resumed"),
+				ui.AccentStyle.Render(displayPath(repoRoot, path, false)),
+				ui.AccentStyle.Render(branch),
+			)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show git commands without executing")
+	cmd.Flags().BoolVar(&ontoNewTip, "onto-new-tip", false, "apply the parked work even if the branch moved since it was parked")
+
+	return cmd
+}
+
+// parkedCommit returns the WIP commit of a parked task.
+func parkedCommit(ctx context.Context, runner git.Runner, repoRoot, task string) (string, bool, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "rev-parse", "--verify", "--quiet", parkedRefPrefix+task+"^{commit}")
+	if err != nil {
+		// --quiet makes a missing ref fail without output.
+		if strings.TrimSpace(stderr) == "" {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("read %s: %w: %s", parkedRefPrefix+task, err, stderr)
+	}
+	return strings.TrimSpace(stdout), true, nil
+}
+
+// listParkedTasks returns the parked tasks with the path resume would use.
+func listParkedTasks(ctx context.Context, runner git.Runner, repoRoot string) ([]parkedTask, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "for-each-ref", "--format=%(refname) %(objectname)", parkedRefPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("list parked tasks: %w: %s", err, stderr)
+	}
+	if strings.TrimSpace(stdout) == "" {
+		return nil, nil
+	}
+	repo, err := git.RepoBaseName(ctx, runner)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []parkedTask
+	for _, line := range strings.Split(stdout, "\n") {
+		name, wip, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		task := strings.TrimPrefix(name, parkedRefPrefix)
+		ref, err := resolveTaskRef(ctx, runner, repoRoot, task)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, parkedTask{task: task, branch: ref.branch, wip: wip, path: taskRefWorktreePath(ctx, repoRoot, repo, task, ref)})
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].task < tasks[j].task })
+	return tasks, nil
+}
@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/config"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+// Branch config keys recorded by `create --preset`. Git moves them with
+// `branch -m` and drops them with the branch.
+const (
+	branchTaskKey   = "gwttTask"
+	branchPresetKey = "gwttPreset"
+)
+
+// taskRef is the branch of a task and the preset it was created with.
+type taskRef struct {
+	branch string
+	preset string
+}
+
+func lookupPreset(ctx context.Context, name string) (config.PresetConfig, error) {
+	cfg, _ := configFromContext(ctx)
+	if cfg != nil {
+		if preset, ok := cfg.Presets[name]; ok {
+			return preset, nil
+		}
+	}
+	var names []string
+	if cfg != nil {
+		for known := range cfg.Presets {
+			names = append(names, known)
+		}
+	}
+	if len(names) == 0 {
+		return config.PresetConfig{}, fmt.Errorf("unknown preset %q (no [presets] are configured)", name)
+	}
+	sort.Strings(names)
+	return config.PresetConfig{}, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// taskPreset returns the preset a task was created with, if it is still
+// configured.
+func taskPreset(ctx context.Context, ref taskRef) (config.PresetConfig, bool) {
+	if ref.preset == "" {
+		return config.PresetConfig{}, false
+	}
+	cfg, ok := configFromContext(ctx)
+	if !ok {
+		return config.PresetConfig{}, false
+	}
+	preset, ok := cfg.Presets[ref.preset]
+	return preset, ok
+}
+
+// presetWorktreePath renders the preset layout for task; unset fields fall
+// back to [create.path].
+func presetWorktreePath(ctx context.Context, repoRoot, repo, task string, preset config.PresetConfig) string {
+	root, format := preset.Path.Root, preset.Path.Format
+	if cfg, ok := configFromContext(ctx); ok {
+		if root == "" {
+			root = cfg.Create.Path.Root
+		}
+		if format == "" {
+			format = cfg.Create.Path.Format
+		}
+	}
+	return worktree.TemplatePath(repoRoot, root, format, repo, task)
+}
+
+// taskRefWorktreePath is where create puts the worktree for task, honoring
+// the preset it was created with.
+func taskRefWorktreePath(ctx context.Context, repoRoot, repo, task string, ref taskRef) string {
+	if preset, ok := taskPreset(ctx, ref); ok {
+		return presetWorktreePath(ctx, repoRoot, repo, task, preset)
+	}
+	return taskWorktreePath(ctx, repoRoot, repo, task)
+}
+
+// resolveTaskRef finds the branch recorded for task by `create --preset`.
+// Other tasks use the task name as their branch.
+func resolveTaskRef(ctx context.Context, runner git.Runner, repoRoot, task string) (taskRef, error) {
+	tasks, err := git.BranchConfigValues(ctx, runner, repoRoot, branchTaskKey)
+	if err != nil {
+		return taskRef{}, err
+	}
+	var branches []string
+	for branch, recorded := range tasks {
+		if recorded == task {
+			branches = append(branches, branch)
+		}
+	}
+	switch len(branches) {
+	case 0:
+		return taskRef{branch: task}, nil
+	case 1:
+	default:
+		sort.Strings(branches)
+		return taskRef{}, fmt.Errorf("task %q is recorded on several branches: %s", task, strings.Join(branches, ", "))
+	}
+	presets, err := git.BranchConfigValues(ctx, runner, repoRoot, branchPresetKey)
+	if err != nil {
+		return taskRef{}, err
+	}
+	return taskRef{branch: branches[0], preset: presets[branches[0]]}, nil
+}
+
+// taskBranchWorktree returns the linked worktree that has branch checked out.
+func taskBranchWorktree(ctx context.Context, runner git.Runner, repoRoot, branch string) (string, bool, error) {
+	worktrees, err := worktree.List(ctx, runner, repoRoot)
+	if err != nil {
+		return "", false, err
+	}
+	for idx, wt := range worktrees {
+		if idx == 0 || wt.Branch != "refs/heads/"+branch {
+			continue
+		}
+		path, err := worktree.NormalizePath(repoRoot, wt.Path)
+		if err != nil {
+			return "", false, err
+		}
+		return path, true, nil
+	}
+	return "", false, nil
+}
+
+func recordTaskPreset(ctx context.Context, cmd *cobra.Command, runner git.Runner, repoRoot, branch, task, preset string, dryRun bool) error {
+	if err := runGit(ctx, cmd, dryRun, runner, "-C", repoRoot, "config", "branch."+branch+"."+branchTaskKey, task); err != nil {
+		return err
+	}
+	return runGit(ctx, cmd, dryRun, runner, "-C", repoRoot, "config", "branch."+branch+"."+branchPresetKey, preset)
+}
+
+// copyPresetFiles copies the files matching the preset's copy globs from
+// the checkout create ran in into the new worktree. Files already checked out there
+// are left alone.
+func copyPresetFiles(ctx context.Context, cmd *cobra.Command, srcRoot, dstRoot string, patterns []string, dryRun bool) error {
+	maskPaths := shouldMaskSensitivePaths(ctx)
+	copied := 0
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(srcRoot, pattern))
+		if err != nil {
+			return fmt.Errorf("copy pattern %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if entry.IsDir() {
+					if entry.Name() == ".git" {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				rel, err := filepath.Rel(srcRoot, path)
+				if err != nil {
+					return err
+				}
+				if _, err := os.Lstat(filepath.Join(dstRoot, rel)); err == nil {
+					return nil
+				} else if !errors.Is(err, fs.ErrNotExist) {
+					return err
+				}
+				if err := copyFile(srcRoot, dstRoot, rel, dryRun, cmd.OutOrStdout(), maskPaths); err != nil {
+					if errors.Is(err, errUnsupportedFileType) {
+						return nil
+					}
+					return err
+				}
+				copied++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if copied > 0 && !dryRun {
+		_, err := fmt.Fprintln(cmd.ErrOrStderr(), ui.MutedStyle.Render(fmt.Sprintf("copied %d file(s) into the worktree", copied)))
+		return err
+	}
+	return nil
+}
+
+// runPostCreateHooks runs the preset's post_create commands with sh in the
+// new worktree. Their output goes to stderr so raw output stays a path.
+func runPostCreateHooks(ctx context.Context, cmd *cobra.Command, dir string, hooks []string, env []string, dryRun bool) error {
+	for _, hook := range hooks {
+		if dryRun {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "sh -c %q\n", hook); err != nil {
+				return err
+			}
+			continue
+		}
+		shell := exec.CommandContext(ctx, "sh", "-c", hook)
+		shell.Dir = dir
+		shell.Env = append(os.Environ(), env...)
+		shell.Stdout = cmd.ErrOrStderr()
+		shell.Stderr = cmd.ErrOrStderr()
+		if err := shell.Run(); err != nil {
+			return fmt.Errorf("post_create hook %q: %w (the worktree was kept)", hook, err)
+		}
+	}
+	return nil
+}
+
+// setUpPresetWorktree records the preset on the new branch, then copies the
+// preset files and runs its hooks in the worktree.
+func setUpPresetWorktree(ctx context.Context, cmd *cobra.Command, runner git.Runner, repoRoot, path, branch, task, name string, preset config.PresetConfig, dryRun bool) error {
+	if err := recordTaskPreset(ctx, cmd, runner, repoRoot, branch, task, name, dryRun); err != nil {
+		return err
+	}
+	if err := copyPresetFiles(ctx, cmd, repoRoot, path, preset.Copy, dryRun); err != nil {
+		return fmt.Errorf("copy preset files: %w", err)
+	}
+	env := []string{
+		"GWTT_TASK=" + task,
+		"GWTT_BRANCH=" + branch,
+		"GWTT_PRESET=" + name,
+		"GWTT_WORKTREE=" + path,
+		"GWTT_REPO_ROOT=" + repoRoot,
+	}
+	return runPostCreateHooks(ctx, cmd, path, preset.PostCreate, env, dryRun)
+}
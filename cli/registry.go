@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pi2pie/git-worktree-tasks/internal/config"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/registry"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// knownRepo is a repository from the registry or the discovery roots.
+type knownRepo struct {
+	Name string
+	Path string
+}
+
+type repoListRow struct {
+	Repo     string `json:"repo"`
+	RepoPath string `json:"repo_path"`
+	listRow
+}
+
+type repoStatusRow struct {
+	Repo     string `json:"repo"`
+	RepoPath string `json:"repo_path"`
+	statusRow
+}
+
+// recordRepo adds the current repository to the user-level registry used by
+// --all-repos. It is best-effort: a failure never affects the command.
+func recordRepo(ctx context.Context, cfg config.Config) {
+	if !cfg.Registry.Enabled {
+		return
+	}
+	commonDir, err := git.CommonDirAt(ctx, defaultRunner(ctx), ".")
+	if err != nil || filepath.Base(commonDir) != ".git" {
+		return
+	}
+	path, err := registry.DefaultPath()
+	if err != nil {
+		return
+	}
+	r, err := registry.Load(path)
+	if err != nil {
+		return
+	}
+	if r.Touch(filepath.Dir(commonDir), time.Now()) {
+		_ = registry.Save(path, r)
+	}
+}
+
+// knownRepos returns the registered and discovered repositories. Registered
+// repos that no longer exist are dropped from the registry.
+func knownRepos(cmd *cobra.Command) ([]knownRepo, error) {
+	cfg, _ := configFromContext(cmd.Context())
+	path, err := registry.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	r, err := registry.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if removed := r.Prune(); len(removed) > 0 {
+		if err := registry.Save(path, r); err != nil {
+			return nil, err
+		}
+		for _, entry := range removed {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s %s\n", ui.MutedStyle.Render("pruned missing repo:"), entry.Path)
+		}
+	}
+
+	paths := map[string]bool{}
+	for _, entry := range r.Repos {
+		paths[entry.Path] = true
+	}
+	if cfg != nil && len(cfg.Registry.Roots) > 0 {
+		discovered, err := registry.Discover(cfg.Registry.Roots, cfg.Registry.MaxDepth)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range discovered {
+			paths[repo] = true
+		}
+	}
+
+	repos := make([]knownRepo, 0, len(paths))
+	for repoPath := range paths {
+		repos = append(repos, knownRepo{Name: filepath.Base(repoPath), Path: repoPath})
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if repos[i].Name == repos[j].Name {
+			return repos[i].Path < repos[j].Path
+		}
+		return repos[i].Name < repos[j].Name
+	})
+	return repos, nil
+}
+
+// forwardedFlags renders the flags set on cmd, except skipped ones, so the
+// same filters apply when the command is rerun in another repository.
+func forwardedFlags(cmd *cobra.Command, skip ...string) []string {
+	skipped := map[string]bool{}
+	for _, name := range skip {
+		skipped[name] = true
+	}
+	var args []string
+	cmd.LocalFlags().Visit(func(flag *pflag.Flag) {
+		if !skipped[flag.Name] {
+			args = append(args, "--"+flag.Name+"="+flag.Value.String())
+		}
+	})
+	return args
+}
+
+// collectAcrossRepos runs a JSON listing command in every known repository
+// and hands each repository's output to decode.
+func collectAcrossRepos(cmd *cobra.Command, args []string, decode func(repo knownRepo, stdout string) error) error {
+	repos, err := knownRepos(cmd)
+	if err != nil {
+		return err
+	}
+	for _, repo := range repos {
+		stdout, stderr, err := runInRepo(cmd, repo.Path, args, nil)
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s: %v\n", repo.Path, childError(err, stderr))
+			continue
+		}
+		if err := decode(repo, stdout); err != nil {
+			return fmt.Errorf("%s: %w", repo.Path, err)
+		}
+	}
+	return nil
+}
+
+func validateAllReposOutput(output string) error {
+	if output != "table" && output != "json" {
+		return fmt.Errorf("--all-repos supports table and json output, not %s", output)
+	}
+	return nil
+}
+
+func repoColumn() tableColumn {
+	return tableColumn{Header: "REPO", MinWidth: 6, Style: func(value string) lipgloss.Style { return ui.AccentStyle }}
+}
+
+func listAllRepos(cmd *cobra.Command, args []string, output string, grid bool) error {
+	if err := validateAllReposOutput(output); err != nil {
+		return err
+	}
+	childArgs := append([]string{"list", "-o", "json", "--abs"}, args...)
+	childArgs = append(childArgs, forwardedFlags(cmd, "all-repos", "output", "grid", "abs", "absolute-path", "field")...)
+	rows := []repoListRow{}
+	if err := collectAcrossRepos(cmd, childArgs, func(repo knownRepo, stdout string) error {
+		var repoRows []listRow
+		if err := json.Unmarshal([]byte(stdout), &repoRows); err != nil {
+			return err
+		}
+		for _, row := range repoRows {
+			rows = append(rows, repoListRow{Repo: repo.Name, RepoPath: repo.Path, listRow: row})
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if output == "json" {
+		return writeJSON(cmd, rows)
+	}
+	tableRows := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		tableRows = append(tableRows, append([]string{row.Repo}, listRowValues(row.listRow)...))
+	}
+	renderTable(cmd, append([]tableColumn{repoColumn()}, listColumns()...), tableRows, grid)
+	return nil
+}
+
+func statusAllRepos(cmd *cobra.Command, args []string, output string, grid bool) error {
+	if err := validateAllReposOutput(output); err != nil {
+		return err
+	}
+	childArgs := append([]string{"status", "-o", "json", "--abs"}, args...)
+	childArgs = append(childArgs, forwardedFlags(cmd, "all-repos", "output", "grid", "abs", "absolute-path")...)
+	rows := []repoStatusRow{}
+	if err := collectAcrossRepos(cmd, childArgs, func(repo knownRepo, stdout string) error {
+		var repoRows []statusRow
+		if err := json.Unmarshal([]byte(stdout), &repoRows); err != nil {
+			return err
+		}
+		for _, row := range repoRows {
+			rows = append(rows, repoStatusRow{Repo: repo.Name, RepoPath: repo.Path, statusRow: row})
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if output == "json" {
+		return writeJSON(cmd, rows)
+	}
+	tableRows := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		tableRows = append(tableRows, append([]string{row.Repo}, statusRowValues(row.statusRow)...))
+	}
+	renderTable(cmd, append([]tableColumn{repoColumn()}, statusColumns()...), tableRows, grid)
+	return nil
+}
+
+func writeJSON(cmd *cobra.Command, value any) error {
+	payload, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+	return err
+}
@@ -0,0 +1,318 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/backup"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+type repairOptions struct {
+	relayout bool
+	dryRun   bool
+}
+
+// repairTarget is a linked worktree whose .git file or admin entry no longer
+// points at the other side. oldMain is the main checkout path the worktree
+// still referenced, when it differs from the current one.
+type repairTarget struct {
+	path    string
+	oldMain string
+}
+
+func newRepairCommand() *cobra.Command {
+	opts := &repairOptions{}
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Re-link task worktrees after the main checkout or worktrees moved",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if cfg, ok := configFromContext(ctx); ok && cfg.Mode == modeCodex {
+				return fmt.Errorf("repair is not supported in --mode=codex (use --mode=classic)")
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return fmt.Errorf("%w (run repair from the main checkout)", err)
+			}
+			mainWorktree, err := mainWorktreePath(ctx, runner, repoRoot)
+			if err != nil {
+				return err
+			}
+			commonDir, err := git.CommonDirAt(ctx, runner, mainWorktree)
+			if err != nil {
+				return err
+			}
+			repo, err := git.RepoBaseName(ctx, runner)
+			if err != nil {
+				return err
+			}
+
+			scanDirs := []string{filepath.Dir(mainWorktree), filepath.Dir(taskWorktreePath(ctx, mainWorktree, repo, "task"))}
+			targets, err := findRepairTargets(commonDir, scanDirs)
+			if err != nil {
+				return err
+			}
+
+			if len(targets) > 0 {
+				repairArgs := []string{"-C", mainWorktree, "worktree", "repair"}
+				for _, target := range targets {
+					repairArgs = append(repairArgs, target.path)
+				}
+				if err := runGit(ctx, cmd, opts.dryRun, runner, repairArgs...); err != nil {
+					return err
+				}
+				if !opts.dryRun {
+					for _, target := range targets {
+						if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", ui.SuccessStyle.Render("relinked"), ui.AccentStyle.Render(displayPath(mainWorktree, target.path, false))); err != nil {
+							return err
+						}
+					}
+					if err := relinkBackups(cmd, commonDir, mainWorktree, targets); err != nil {
+						return err
+					}
+				}
+			}
+
+			oldRepos := map[string]bool{}
+			for _, target := range targets {
+				if target.oldMain != "" {
+					oldRepos[filepath.Base(target.oldMain)] = true
+				}
+			}
+			moves, err := planRepairRelayout(ctx, runner, mainWorktree, repo, oldRepos, targets, opts.dryRun)
+			if err != nil {
+				return err
+			}
+			if opts.relayout && len(moves) > 0 {
+				return runTaskMoves(ctx, cmd, runner, mainWorktree, moves, opts.dryRun, true)
+			}
+			if len(moves) > 0 {
+				if err := recordRepairedTasks(ctx, cmd, runner, mainWorktree, moves, opts.dryRun); err != nil {
+					return err
+				}
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), ui.WarningStyle.Render(fmt.Sprintf("%d worktree(s) do not follow the layout for %q; run `gwtt repair --relayout` to move them", len(moves), repo)))
+				return err
+			}
+			if len(targets) == 0 {
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render("all worktrees are linked"))
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.relayout, "relayout", false, "also move worktrees to the configured layout for the current repo name")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
+	return cmd
+}
+
+// findRepairTargets matches the admin entries under <commonDir>/worktrees
+// with worktree directories, both the recorded ones and those found directly
+// under scanDirs, and returns the pairs whose links disagree.
+func findRepairTargets(commonDir string, scanDirs []string) ([]repairTarget, error) {
+	adminRoot := filepath.Join(commonDir, "worktrees")
+	entries, err := os.ReadDir(adminRoot)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	recorded := map[string]string{}
+	candidates := map[string]bool{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(adminRoot, entry.Name(), "gitdir"))
+		if err != nil {
+			continue
+		}
+		gitFile := filepath.Clean(strings.TrimSpace(string(data)))
+		recorded[entry.Name()] = gitFile
+		candidates[filepath.Dir(gitFile)] = true
+	}
+	for _, dir := range scanDirs {
+		children, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, child := range children {
+			if child.IsDir() {
+				candidates[filepath.Join(dir, child.Name())] = true
+			}
+		}
+	}
+
+	var targets []repairTarget
+	for path := range candidates {
+		gitdir, err := readGitdirLink(path)
+		if err != nil {
+			continue
+		}
+		name := filepath.Base(gitdir)
+		gitFile, ok := recorded[name]
+		if !ok || filepath.Base(filepath.Dir(gitdir)) != "worktrees" {
+			continue
+		}
+		ownGitFile := filepath.Join(path, ".git")
+		linkedHere := filepath.Clean(gitdir) == filepath.Join(adminRoot, name)
+		if linkedHere && gitFile == ownGitFile {
+			continue
+		}
+		if !linkedHere {
+			// The .git file points at another repository's admin dir unless
+			// that dir is gone and our entry does not belong elsewhere.
+			if _, err := os.Stat(gitdir); err == nil {
+				continue
+			}
+			if gitFile != ownGitFile {
+				if _, err := os.Stat(gitFile); err == nil {
+					continue
+				}
+			}
+		}
+		target := repairTarget{path: path}
+		if !linkedHere {
+			oldCommon := filepath.Dir(filepath.Dir(gitdir))
+			if filepath.Base(oldCommon) == ".git" {
+				target.oldMain = filepath.Dir(oldCommon)
+			}
+		}
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].path < targets[j].path })
+	return targets, nil
+}
+
+// planRepairRelayout finds linked worktrees whose directory name still
+// carries an old repo prefix, deriving their task with that old name, or
+// from the branch once the old name is no longer known.
+func planRepairRelayout(ctx context.Context, runner git.Runner, mainWorktree, repo string, oldRepos map[string]bool, targets []repairTarget, dryRun bool) ([]taskMove, error) {
+	listed, err := worktree.List(ctx, runner, mainWorktree)
+	if err != nil {
+		return nil, err
+	}
+	var worktrees []worktree.Worktree
+	known := map[string]bool{}
+	for idx, wt := range listed {
+		if idx == 0 || (dryRun && wt.Prunable) {
+			continue
+		}
+		worktrees = append(worktrees, wt)
+		known[filepath.Clean(wt.Path)] = true
+	}
+	if dryRun {
+		// Moved worktrees are still listed at their old path until the
+		// repair runs; plan with the path they will be listed at.
+		for _, target := range targets {
+			if !known[target.path] {
+				worktrees = append(worktrees, worktree.Worktree{Path: target.path})
+			}
+		}
+	}
+	mainAbs, err := worktree.NormalizePath(mainWorktree, mainWorktree)
+	if err != nil {
+		return nil, err
+	}
+
+	var moves []taskMove
+	for _, wt := range worktrees {
+		if wt.Bare || wt.Prunable || wt.Locked {
+			continue
+		}
+		from, err := worktree.NormalizePath(mainWorktree, wt.Path)
+		if err != nil {
+			return nil, err
+		}
+		if from == mainAbs {
+			continue
+		}
+		if _, ok := worktree.TaskFromPath(repo, from); ok {
+			continue
+		}
+		branch := strings.TrimPrefix(wt.Branch, "refs/heads/")
+		task := ""
+		for old := range oldRepos {
+			if derived, ok := worktree.TaskFromPath(old, from); ok && derived != "" {
+				task = derived
+				break
+			}
+		}
+		// Once relinked, the old name is only visible in the directory
+		// name: <old-repo>_<task> where the task is the branch.
+		if slug := worktree.SlugifyTask(branch); task == "" && branch != "" && strings.HasSuffix(filepath.Base(from), "_"+slug) {
+			task = slug
+		}
+		if task == "" {
+			continue
+		}
+		to := taskWorktreePath(ctx, mainWorktree, repo, task)
+		if to == from {
+			continue
+		}
+		moves = append(moves, taskMove{task: task, newTask: task, branch: branch, newBranch: branch, from: from, to: to})
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].from < moves[j].from })
+	return moves, checkMoveTargets(moves)
+}
+
+// recordRepairedTasks records gwttTask for worktrees left at their old
+// layout, so list and status keep the task name derived from the old repo.
+func recordRepairedTasks(ctx context.Context, cmd *cobra.Command, runner git.Runner, mainWorktree string, moves []taskMove, dryRun bool) error {
+	recorded, err := git.BranchConfigValues(ctx, runner, mainWorktree, branchTaskKey)
+	if err != nil {
+		return err
+	}
+	for _, move := range moves {
+		if move.branch == "" || recorded[move.branch] == move.task {
+			continue
+		}
+		if err := runGit(ctx, cmd, dryRun, runner, "-C", mainWorktree, "config", "branch."+move.branch+"."+branchTaskKey, move.task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relinkBackups points backups of the old main checkout at its new path.
+func relinkBackups(cmd *cobra.Command, commonDir, mainWorktree string, targets []repairTarget) error {
+	oldMains := map[string]bool{}
+	for _, target := range targets {
+		if target.oldMain != "" {
+			oldMains[target.oldMain] = true
+		}
+	}
+	if len(oldMains) == 0 {
+		return nil
+	}
+	updated, err := backup.Update(commonDir, func(b *backup.Backup) bool {
+		if !oldMains[filepath.Clean(b.Path)] {
+			return false
+		}
+		b.Path = mainWorktree
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("update backup metadata: %w", err)
+	}
+	if updated > 0 {
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render(fmt.Sprintf("updated %d backup(s) to the new path", updated)))
+	}
+	return err
+}
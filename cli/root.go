@@ -14,9 +14,10 @@ import (
 var Version = "0.1.3"
 
 var (
-	errCanceled     = errors.New("git worktree task process canceled")
-	errThemesListed = errors.New("themes listed")
-	errApplyBlocked = errors.New("apply aborted due to conflicts")
+	errCanceled        = errors.New("git worktree task process canceled")
+	errThemesListed    = errors.New("themes listed")
+	errApplyBlocked    = errors.New("apply aborted due to conflicts")
+	errApplyConflicted = errors.New("apply stopped with conflicts")
 )
 
 func Execute() int {
@@ -29,7 +30,7 @@ func Execute() int {
 			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), ui.WarningStyle.Render("git worktree task process canceled"))
 			return 3
 		}
-		if errors.Is(err, errApplyBlocked) {
+		if errors.Is(err, errApplyBlocked) || errors.Is(err, errApplyConflicted) {
 			return 2
 		}
 		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), ui.ErrorStyle.Render(err.Error()))
@@ -94,9 +95,13 @@ func gitWorkTreeCommand() (*cobra.Command, *runState) {
 			}
 			return errThemesListed
 		}
-		cfg, err := config.Load()
+		lenient := cmd.Annotations[annotationLenientConfig] != ""
+		cfg, err := config.LoadAt(workDir(cmd.Context()))
 		if err != nil {
-			return err
+			if !lenient {
+				return err
+			}
+			cfg = config.DefaultConfig()
 		}
 		mode := cfg.Mode
 		if cmd.Flags().Changed("mode") {
@@ -104,7 +109,10 @@ func gitWorkTreeCommand() (*cobra.Command, *runState) {
 		}
 		mode, err = normalizeMode(mode)
 		if err != nil {
-			return err
+			if !lenient {
+				return err
+			}
+			mode = modeClassic
 		}
 		cfg.Mode = mode
 		if cmd.Flags().Changed("mask-sensitive-paths") && cmd.Flags().Changed("no-mask-sensitive-paths") {
@@ -124,13 +132,17 @@ func gitWorkTreeCommand() (*cobra.Command, *runState) {
 			}
 		}
 		if err := ui.SetTheme(themeName); err != nil {
-			return err
+			if !lenient {
+				return err
+			}
+			_ = ui.SetTheme("")
 		}
 		colorEnabled := cfg.UI.ColorEnabled
 		if cmd.Flags().Changed("nocolor") {
 			colorEnabled = !state.noColor
 		}
 		ui.SetColorEnabled(colorEnabled)
+		recordRepo(cmd.Context(), cfg)
 		return nil
 	}
 
@@ -138,10 +150,26 @@ func gitWorkTreeCommand() (*cobra.Command, *runState) {
 		newCreateCommand(),
 		newFinishCommand(),
 		newCleanupCommand(),
+		newMoveCommand(),
+		newRepairCommand(),
+		newSparseCommand(),
+		newWorkspaceCommand(),
 		newListCommand(),
 		newStatusCommand(),
+		newDuCommand(),
+		newExecCommand(),
+		newSyncCommand(),
+		newRestackCommand(),
+		newParkCommand(),
+		newResumeCommand(),
+		newHistoryCommand(),
+		newUndoCommand(),
+		newConflictsCommand(),
 		newApplyCommand(),
 		newOverwriteCommand(),
+		newBackupsCommand(),
+		newRestoreCommand(),
+		newDoctorCommand(),
 		newTUICommand(),
 	)
 
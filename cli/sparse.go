@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+const (
+	sparseAdd    = "add"
+	sparseRemove = "remove"
+	sparseList   = "list"
+)
+
+type sparseOptions struct {
+	dryRun bool
+}
+
+func newSparseCommand() *cobra.Command {
+	opts := &sparseOptions{}
+	cmd := &cobra.Command{
+		Use:   "sparse <task> add|remove|list [dir...]",
+		Short: "Show or change the sparse-checkout directories of a task",
+		Args:  sparseArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if cfg, ok := configFromContext(ctx); ok && cfg.Mode == modeCodex {
+				return fmt.Errorf("sparse is not supported in --mode=codex (use --mode=classic)")
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			task := worktree.SlugifyTask(args[0])
+			ref, err := resolveTaskRef(ctx, runner, repoRoot, task)
+			if err != nil {
+				return err
+			}
+			path, found, err := taskBranchWorktree(ctx, runner, repoRoot, ref.branch)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("no worktree found for task %q", task)
+			}
+			sparse, err := worktree.Sparse(ctx, runner, path)
+			if err != nil {
+				return err
+			}
+
+			action := args[1]
+			if action == sparseList {
+				if !sparse.Enabled {
+					_, err := fmt.Fprintln(cmd.OutOrStdout(), ui.MutedStyle.Render(fmt.Sprintf("%s has a full checkout", task)))
+					return err
+				}
+				for _, dir := range sparse.Dirs {
+					if _, err := fmt.Fprintln(cmd.OutOrStdout(), dir); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			if !sparse.Enabled {
+				return fmt.Errorf("%s has a full checkout (create the task with --sparse)", task)
+			}
+			if !sparse.Cone {
+				return fmt.Errorf("%s does not use cone-mode sparse checkout; edit it with git sparse-checkout", task)
+			}
+			dirs, err := normalizeSparseDirs(args[2:])
+			if err != nil {
+				return err
+			}
+			gitArgs := []string{"-C", path, "sparse-checkout", "add"}
+			if action == sparseRemove {
+				remaining, err := removeSparseDirs(sparse.Dirs, dirs)
+				if err != nil {
+					return err
+				}
+				gitArgs = append([]string{"-C", path, "sparse-checkout", "set", "--cone"}, remaining...)
+			} else {
+				gitArgs = append(gitArgs, dirs...)
+			}
+			if err := runGit(ctx, cmd, opts.dryRun, runner, gitArgs...); err != nil {
+				return err
+			}
+			if opts.dryRun {
+				return nil
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", ui.SuccessStyle.Render("sparse checkout updated"), ui.AccentStyle.Render(displayPath(repoRoot, path, false)))
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
+
+	return cmd
+}
+
+func sparseArgs(cmd *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("requires a task and an action (add, remove or list)")
+	}
+	switch args[1] {
+	case sparseList:
+		if len(args) > 2 {
+			return fmt.Errorf("list takes no directories")
+		}
+	case sparseAdd, sparseRemove:
+		if len(args) < 3 {
+			return fmt.Errorf("%s requires at least one directory", args[1])
+		}
+	default:
+		return fmt.Errorf("unknown sparse action %q (use add, remove or list)", args[1])
+	}
+	return nil
+}
+
+// normalizeSparseDirs turns user input into cone-mode directories relative
+// to the worktree root.
+func normalizeSparseDirs(dirs []string) ([]string, error) {
+	normalized := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		trimmed := strings.TrimSpace(dir)
+		if trimmed == "" {
+			continue
+		}
+		if filepath.IsAbs(trimmed) {
+			return nil, fmt.Errorf("sparse directory %q must be relative to the repository root", dir)
+		}
+		clean := path.Clean(filepath.ToSlash(trimmed))
+		if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+			return nil, fmt.Errorf("sparse directory %q must be inside the repository", dir)
+		}
+		normalized = append(normalized, clean)
+	}
+	return normalized, nil
+}
+
+func removeSparseDirs(current, remove []string) ([]string, error) {
+	drop := map[string]bool{}
+	for _, dir := range remove {
+		drop[dir] = true
+	}
+	var remaining []string
+	for _, dir := range current {
+		if drop[dir] {
+			delete(drop, dir)
+			continue
+		}
+		remaining = append(remaining, dir)
+	}
+	for _, dir := range remove {
+		if drop[dir] {
+			return nil, fmt.Errorf("%s is not in the sparse checkout", dir)
+		}
+	}
+	return remaining, nil
+}
+
+// setUpSparseCheckout restricts a worktree added with --no-checkout to dirs,
+// then checks out its branch.
+func setUpSparseCheckout(ctx context.Context, cmd *cobra.Command, runner git.Runner, path string, dirs []string, dryRun bool) error {
+	setArgs := append([]string{"-C", path, "sparse-checkout", "set", "--cone"}, dirs...)
+	if err := runGit(ctx, cmd, dryRun, runner, setArgs...); err != nil {
+		return err
+	}
+	return runGit(ctx, cmd, dryRun, runner, "-C", path, "checkout")
+}
+
+// warnOutsideSparse warns when files are missing from the destination
+// because its sparse checkout excludes them.
+func warnOutsideSparse(ctx context.Context, cmd *cobra.Command, runner git.Runner, destinationRoot, destinationName string, files []string) error {
+	sparse, err := worktree.Sparse(ctx, runner, destinationRoot)
+	if err != nil {
+		return err
+	}
+	var outside []string
+	seen := map[string]bool{}
+	for _, file := range files {
+		if seen[file] || sparse.Includes(file) {
+			continue
+		}
+		seen[file] = true
+		outside = append(outside, file)
+	}
+	if len(outside) == 0 {
+		return nil
+	}
+	_, err = fmt.Fprintf(cmd.ErrOrStderr(), "warning: %d path(s) are outside the sparse checkout of the %s: %s\n", len(outside), destinationName, strings.Join(outside, ", "))
+	return err
+}
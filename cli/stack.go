@@ -0,0 +1,409 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+// Branch config keys recorded by `create --on`: the parent branch of a
+// stacked task and the parent commit it was last rebased onto.
+const (
+	branchParentKey     = "gwttParent"
+	branchParentBaseKey = "gwttParentBase"
+)
+
+// taskStack is the parent of every stacked branch and the parent commit the
+// branch is based on.
+type taskStack struct {
+	parents map[string]string
+	bases   map[string]string
+}
+
+func loadTaskStack(ctx context.Context, runner git.Runner, repoRoot string) (taskStack, error) {
+	parents, err := git.BranchConfigValues(ctx, runner, repoRoot, branchParentKey)
+	if err != nil {
+		return taskStack{}, err
+	}
+	bases, err := git.BranchConfigValues(ctx, runner, repoRoot, branchParentBaseKey)
+	if err != nil {
+		return taskStack{}, err
+	}
+	return taskStack{parents: parents, bases: bases}, nil
+}
+
+// children returns the branches stacked directly on branch, sorted.
+func (s taskStack) children(branch string) []string {
+	var children []string
+	for child, parent := range s.parents {
+		if parent == branch {
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// descendants returns the branches stacked on branch, parents before their
+// children.
+func (s taskStack) descendants(branch string) []string {
+	var ordered []string
+	for _, child := range s.children(branch) {
+		ordered = append(ordered, child)
+		ordered = append(ordered, s.descendants(child)...)
+	}
+	return ordered
+}
+
+// order returns every stacked branch, parents before their children.
+func (s taskStack) order() []string {
+	var ordered []string
+	roots := map[string]bool{}
+	for _, parent := range s.parents {
+		if _, stacked := s.parents[parent]; !stacked {
+			roots[parent] = true
+		}
+	}
+	names := make([]string, 0, len(roots))
+	for root := range roots {
+		names = append(names, root)
+	}
+	sort.Strings(names)
+	for _, root := range names {
+		ordered = append(ordered, s.descendants(root)...)
+	}
+	return ordered
+}
+
+// stackTree orders branches depth-first under their parents and returns the
+// tree prefix of each, e.g. "└─ ". Branches whose parent is not listed are
+// roots; the order of branches is kept among siblings.
+func stackTree(branches []string, parents map[string]string) ([]int, []string) {
+	listed := map[string]bool{}
+	for _, branch := range branches {
+		listed[branch] = true
+	}
+	kids := map[string][]int{}
+	var roots []int
+	for idx, branch := range branches {
+		parent, ok := parents[branch]
+		if ok && listed[parent] && parent != branch {
+			kids[parent] = append(kids[parent], idx)
+			continue
+		}
+		roots = append(roots, idx)
+	}
+
+	var order []int
+	var prefixes []string
+	visited := map[int]bool{}
+	var walk func(idx int, indent, prefix string)
+	walk = func(idx int, indent, prefix string) {
+		if visited[idx] {
+			return
+		}
+		visited[idx] = true
+		order = append(order, idx)
+		prefixes = append(prefixes, prefix)
+		children := kids[branches[idx]]
+		for pos, child := range children {
+			if pos == len(children)-1 {
+				walk(child, indent+"   ", indent+"└─ ")
+			} else {
+				walk(child, indent+"│  ", indent+"├─ ")
+			}
+		}
+	}
+	for _, idx := range roots {
+		walk(idx, "", "")
+	}
+	// Cycles have no root; list them flat rather than dropping them.
+	for idx := range branches {
+		walk(idx, "", "")
+	}
+	return order, prefixes
+}
+
+// stackStatusRows orders status rows as a tree of stacked tasks. With
+// indent, task names carry the tree prefix for table output.
+func stackStatusRows(rows []statusRow, indent bool) []statusRow {
+	branches := make([]string, 0, len(rows))
+	parents := map[string]string{}
+	for _, row := range rows {
+		branches = append(branches, row.Branch)
+		if row.Parent != "" {
+			parents[row.Branch] = row.Parent
+		}
+	}
+	order, prefixes := stackTree(branches, parents)
+	ordered := make([]statusRow, 0, len(rows))
+	for pos, idx := range order {
+		row := rows[idx]
+		if indent {
+			row.Task = prefixes[pos] + row.Task
+		}
+		ordered = append(ordered, row)
+	}
+	return ordered
+}
+
+// recordTaskParent stacks branch on parent at the parent commit base.
+func recordTaskParent(ctx context.Context, cmd *cobra.Command, runner git.Runner, repoRoot, branch, parent, base string, dryRun bool) error {
+	if err := runGit(ctx, cmd, dryRun, runner, "-C", repoRoot, "config", "branch."+branch+"."+branchParentKey, parent); err != nil {
+		return err
+	}
+	return runGit(ctx, cmd, dryRun, runner, "-C", repoRoot, "config", "branch."+branch+"."+branchParentBaseKey, base)
+}
+
+// retargetStep moves a stacked child from parent to target, restoring the
+// old parent on rollback. The recorded base is kept so restack still replays
+// only the child's own commits.
+func retargetStep(repoRoot, child, parent, target string) *journalStep {
+	key := "branch." + child + "." + branchParentKey
+	return &journalStep{
+		args: []string{"-C", repoRoot, "config", key, target},
+		snapshot: func(ctx context.Context, runner git.Runner) ([][]string, error) {
+			return [][]string{{"-C", repoRoot, "config", key, parent}}, nil
+		},
+	}
+}
+
+// resolveParentTask returns the branch of the task a new task is stacked on.
+func resolveParentTask(ctx context.Context, runner git.Runner, repoRoot, raw string) (string, error) {
+	task := worktree.SlugifyTask(raw)
+	if task == "" {
+		return "", fmt.Errorf("parent task cannot be empty")
+	}
+	ref, err := resolveTaskRef(ctx, runner, repoRoot, task)
+	if err != nil {
+		return "", err
+	}
+	exists, err := git.BranchExists(ctx, runner, repoRoot, ref.branch)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("parent task %q has no branch %s", raw, ref.branch)
+	}
+	return ref.branch, nil
+}
+
+// resolveParentBase returns the commit a new task stacked on parent starts
+// from: the parent tip, or the merge base when branch already exists.
+func resolveParentBase(ctx context.Context, runner git.Runner, repoRoot, branch, parent string, branchExists bool) (string, error) {
+	args := []string{"-C", repoRoot, "rev-parse", parent}
+	if branchExists {
+		args = []string{"-C", repoRoot, "merge-base", branch, parent}
+	}
+	stdout, stderr, err := runner.Run(ctx, args...)
+	if err != nil {
+		return "", fmt.Errorf("resolve base of %s: %w: %s", parent, err, stderr)
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// parentMerged reports whether the parent commits a child was stacked on
+// reached target, so retargeting the child cannot drop them.
+func parentMerged(ctx context.Context, runner git.Runner, repoRoot, base, target string) (bool, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "rev-list", "--count", target+".."+base)
+	if err != nil {
+		return false, fmt.Errorf("check parent commits in %s: %w: %s", target, err, stderr)
+	}
+	return strings.TrimSpace(stdout) == "0", nil
+}
+
+type restackOptions struct {
+	target string
+	dryRun bool
+	output string
+	grid   bool
+}
+
+func newRestackCommand() *cobra.Command {
+	opts := &restackOptions{output: "table"}
+	cmd := &cobra.Command{
+		Use:   "restack [task]",
+		Short: "Rebase stacked tasks onto their parents",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if cfg, ok := configFromContext(ctx); ok && cfg.Mode == modeCodex {
+				return fmt.Errorf("restack is not supported in --mode=codex (run with --mode=classic)")
+			}
+			if opts.output != "table" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format: %s", opts.output)
+			}
+			if cfg, ok := configFromContext(ctx); ok && !cmd.Flags().Changed("grid") {
+				opts.grid = cfg.Status.Grid
+			}
+			modeCtx, err := resolveModeContext(cmd, true)
+			if err != nil {
+				return err
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			stack, err := loadTaskStack(ctx, runner, repoRoot)
+			if err != nil {
+				return err
+			}
+
+			branches := stack.order()
+			if len(args) == 1 {
+				ref, err := resolveTaskRef(ctx, runner, repoRoot, worktree.SlugifyTask(args[0]))
+				if err != nil {
+					return err
+				}
+				branches = stack.descendants(ref.branch)
+				if _, stacked := stack.parents[ref.branch]; stacked {
+					branches = append([]string{ref.branch}, branches...)
+				}
+			}
+			if len(branches) == 0 {
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), "no stacked tasks")
+				return err
+			}
+
+			target := opts.target
+			if target == "" {
+				target, err = git.CurrentBranch(ctx, runner)
+				if err != nil {
+					return err
+				}
+			}
+			tasks, err := listTaskWorktrees(ctx, runner, modeCtx, repoRoot)
+			if err != nil {
+				return err
+			}
+
+			var results []syncResult
+			blocked := map[string]bool{}
+			for _, branch := range branches {
+				result := restackTask(ctx, cmd, runner, repoRoot, stack, tasks, branch, target, blocked, opts.dryRun)
+				if result.Result == syncSkipped || result.Result == syncConflict || result.Result == syncFailed {
+					blocked[branch] = true
+				}
+				results = append(results, result)
+			}
+			if opts.dryRun {
+				return nil
+			}
+			if err := renderSyncResults(cmd, opts.output, results, opts.grid); err != nil {
+				return err
+			}
+			failed := 0
+			for _, result := range results {
+				if result.Result == syncConflict || result.Result == syncFailed {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d task(s) could not be restacked", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.target, "target", "", "branch to retarget children onto when their parent branch is gone (default: current branch)")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "output format: table or json")
+	cmd.Flags().BoolVar(&opts.grid, "grid", false, "render table with grid borders")
+
+	return cmd
+}
+
+// restackTask rebases the commits branch has on top of its recorded parent
+// base onto the current parent tip, inside the branch's worktree. A child
+// whose parent branch was deleted is retargeted onto target first.
+func restackTask(ctx context.Context, cmd *cobra.Command, runner git.Runner, repoRoot string, stack taskStack, tasks []taskWorktree, branch, target string, blocked map[string]bool, dryRun bool) syncResult {
+	parent := stack.parents[branch]
+	result := syncResult{Task: worktree.SlugifyTask(branch), Branch: branch, Target: parent}
+	path := ""
+	for _, tw := range tasks {
+		if tw.branch == branch {
+			result.Task, path = tw.task, tw.path
+		}
+	}
+	if blocked[parent] {
+		result.Result, result.Detail = syncSkipped, "parent was not restacked"
+		return result
+	}
+	if path == "" {
+		result.Result, result.Detail = syncSkipped, "no worktree"
+		return result
+	}
+
+	exists, err := git.BranchExists(ctx, runner, repoRoot, parent)
+	if err != nil {
+		result.Result, result.Detail = syncFailed, err.Error()
+		return result
+	}
+	if !exists {
+		if base := stack.bases[branch]; base != "" {
+			merged, err := parentMerged(ctx, runner, repoRoot, base, target)
+			if err != nil {
+				result.Result, result.Detail = syncFailed, err.Error()
+				return result
+			}
+			if !merged {
+				result.Result, result.Detail = syncSkipped, fmt.Sprintf("parent %s is gone but its commits are not in %s", parent, target)
+				return result
+			}
+		}
+		if err := runGit(ctx, cmd, dryRun, runner, "-C", repoRoot, "config", "branch."+branch+"."+branchParentKey, target); err != nil {
+			result.Result, result.Detail = syncFailed, err.Error()
+			return result
+		}
+		stack.parents[branch] = target
+		parent = target
+		result.Target = target
+	}
+
+	info, err := worktree.Status(ctx, runner, path, parent)
+	if err != nil {
+		result.Result, result.Detail = syncFailed, err.Error()
+		return result
+	}
+	if info.Dirty {
+		result.Result, result.Detail = syncSkipped, "uncommitted changes"
+		return result
+	}
+	tip, err := resolveParentBase(ctx, runner, repoRoot, branch, parent, false)
+	if err != nil {
+		result.Result, result.Detail = syncFailed, err.Error()
+		return result
+	}
+	base := stack.bases[branch]
+	if base == "" {
+		if base, err = resolveParentBase(ctx, runner, repoRoot, branch, parent, true); err != nil {
+			result.Result, result.Detail = syncFailed, err.Error()
+			return result
+		}
+	}
+	if base == tip {
+		result.Result = syncUpToDate
+		return result
+	}
+
+	if err := runGit(ctx, cmd, dryRun, runner, "-C", path, "rebase", "--onto", parent, base); err != nil {
+		if abortErr := runGit(ctx, cmd, false, runner, "-C", path, "rebase", "--abort"); abortErr != nil {
+			result.Result, result.Detail = syncFailed, err.Error()
+			return result
+		}
+		result.Result, result.Detail = syncConflict, "rebase aborted, worktree unchanged"
+		return result
+	}
+	if err := runGit(ctx, cmd, dryRun, runner, "-C", repoRoot, "config", "branch."+branch+"."+branchParentBaseKey, tip); err != nil {
+		result.Result, result.Detail = syncFailed, err.Error()
+		return result
+	}
+	result.Result, result.Detail = syncUpdated, "rebased onto "+parent
+	return result
+}
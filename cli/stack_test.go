@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStackTree(t *testing.T) {
+	branches := []string{"ui", "main-fix", "api", "api-docs", "api-tests"}
+	parents := map[string]string{
+		"ui":        "api",
+		"api-docs":  "api",
+		"api-tests": "api-docs",
+		"api":       "main",
+	}
+	order, prefixes := stackTree(branches, parents)
+
+	var got []string
+	for pos, idx := range order {
+		got = append(got, prefixes[pos]+branches[idx])
+	}
+	want := []string{
+		"main-fix",
+		"api",
+		"├─ ui",
+		"└─ api-docs",
+		"   └─ api-tests",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("stackTree() = %q, want %q", got, want)
+	}
+}
+
+func TestStackTreeKeepsCycles(t *testing.T) {
+	order, _ := stackTree([]string{"a", "b"}, map[string]string{"a": "b", "b": "a"})
+	if len(order) != 2 {
+		t.Fatalf("stackTree() dropped branches in a cycle: %v", order)
+	}
+}
+
+func TestTaskStackOrder(t *testing.T) {
+	stack := taskStack{parents: map[string]string{
+		"c": "b",
+		"b": "a",
+		"a": "main",
+		"x": "main",
+		"y": "gone",
+	}}
+	if got, want := stack.order(), []string{"y", "a", "b", "c", "x"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("order() = %v, want %v", got, want)
+	}
+	if got, want := stack.descendants("a"), []string{"b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("descendants(a) = %v, want %v", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
@@ -18,26 +19,40 @@ import (
 )
 
 type statusOptions struct {
-	output string
-	target string
-	task   string
-	branch string
-	abs    bool
-	grid   bool
-	strict bool
+	output    string
+	target    string
+	task      string
+	branch    string
+	abs       bool
+	grid      bool
+	strict    bool
+	allRepos  bool
+	size      bool
+	tree      bool
+	conflicts bool
 }
 
 type statusRow struct {
-	Task         string `json:"task"`
-	Branch       string `json:"branch"`
-	Path         string `json:"path"`
-	ModifiedTime string `json:"modified_time"`
-	Base         string `json:"base"`
-	Target       string `json:"target"`
-	LastCommit   string `json:"last_commit"`
-	Dirty        bool   `json:"dirty"`
-	Ahead        int    `json:"ahead"`
-	Behind       int    `json:"behind"`
+	Task         string   `json:"task"`
+	Branch       string   `json:"branch"`
+	Path         string   `json:"path"`
+	ModifiedTime string   `json:"modified_time"`
+	Base         string   `json:"base"`
+	Target       string   `json:"target"`
+	LastCommit   string   `json:"last_commit"`
+	Dirty        bool     `json:"dirty"`
+	Ahead        int      `json:"ahead"`
+	Behind       int      `json:"behind"`
+	Sparse       []string `json:"sparse,omitempty"`
+	Submodules   []string `json:"submodules,omitempty"`
+	Parent       string   `json:"parent,omitempty"`
+	SizeBytes    *int64   `json:"size_bytes,omitempty"`
+	LastActivity string   `json:"last_activity,omitempty"`
+	Conflicts    []string `json:"conflicts,omitempty"`
+	Parked       bool     `json:"parked,omitempty"`
+	absPath      string
+	// conflictsChecked is set by status --conflicts.
+	conflictsChecked bool
 }
 
 func newStatusCommand() *cobra.Command {
@@ -48,7 +63,7 @@ func newStatusCommand() *cobra.Command {
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			runner := defaultRunner()
+			runner := defaultRunner(ctx)
 			modeCtx, err := resolveModeContext(cmd, true)
 			if err != nil {
 				return err
@@ -70,6 +85,9 @@ func newStatusCommand() *cobra.Command {
 					opts.strict = cfg.Status.Strict
 				}
 			}
+			if opts.allRepos {
+				return statusAllRepos(cmd, args, opts.output, opts.grid)
+			}
 			repoRoot, err := repoRoot(ctx, runner)
 			if err != nil {
 				return err
@@ -138,6 +156,10 @@ func newStatusCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			recorded, err := git.BranchConfigValues(ctx, runner, repoRoot, branchTaskKey)
+			if err != nil {
+				return err
+			}
 
 			rows := make([]statusRow, 0, len(worktrees))
 			for _, wt := range worktrees {
@@ -172,7 +194,7 @@ func newStatusCommand() *cobra.Command {
 							continue
 						}
 					}
-					task, err = deriveClassicTask(repoRoot, mainWorktree, repo, wt)
+					task, err = deriveClassicTask(repoRoot, mainWorktree, repo, recorded, wt)
 					if err != nil {
 						return err
 					}
@@ -187,7 +209,7 @@ func newStatusCommand() *cobra.Command {
 					continue
 				}
 
-				statusInfo, err := worktree.Status(ctx, runner, wt.Path, target)
+				statusInfo, err := worktree.StatusWith(ctx, runner, wt.Path, target, statusProbes)
 				if err != nil {
 					return err
 				}
@@ -219,6 +241,9 @@ func newStatusCommand() *cobra.Command {
 					Dirty:        statusInfo.Dirty,
 					Ahead:        statusInfo.Ahead,
 					Behind:       statusInfo.Behind,
+					Sparse:       statusInfo.Sparse.Dirs,
+					Submodules:   submoduleSummaries(statusInfo.Submodules),
+					absPath:      wtAbs,
 				})
 				if query != "" && !opts.strict {
 					break
@@ -235,7 +260,7 @@ func newStatusCommand() *cobra.Command {
 					return err
 				}
 				if ok {
-					statusInfo, err := worktree.Status(ctx, runner, path, target)
+					statusInfo, err := worktree.StatusWith(ctx, runner, path, target, statusProbes)
 					if err != nil {
 						return err
 					}
@@ -263,10 +288,65 @@ func newStatusCommand() *cobra.Command {
 						Dirty:        statusInfo.Dirty,
 						Ahead:        statusInfo.Ahead,
 						Behind:       statusInfo.Behind,
+						Sparse:       statusInfo.Sparse.Dirs,
+						Submodules:   submoduleSummaries(statusInfo.Submodules),
+						absPath:      path,
 					})
 				}
 			}
 
+			if mode != modeCodex && (query == "" || opts.strict || len(rows) == 0) {
+				parked, err := listParkedTasks(ctx, runner, repoRoot)
+				if err != nil {
+					return err
+				}
+				for _, pt := range parked {
+					if query != "" && !matchesTask(pt.task, query, opts.strict) {
+						continue
+					}
+					if opts.branch != "" && pt.branch != opts.branch {
+						continue
+					}
+					statusInfo, err := worktree.BranchStatus(ctx, runner, repoRoot, pt.branch, target)
+					if err != nil {
+						return err
+					}
+					rows = append(rows, statusRow{
+						Task:       pt.task,
+						Branch:     pt.branch,
+						Path:       displayPath(repoRoot, pt.path, opts.abs),
+						Base:       statusInfo.Base,
+						Target:     target,
+						LastCommit: statusInfo.LastCommit,
+						Ahead:      statusInfo.Ahead,
+						Behind:     statusInfo.Behind,
+						Parked:     true,
+					})
+				}
+			}
+
+			if mode != modeCodex {
+				stack, err := loadTaskStack(ctx, runner, repoRoot)
+				if err != nil {
+					return err
+				}
+				for idx := range rows {
+					rows[idx].Parent = stack.parents[rows[idx].Branch]
+				}
+			}
+			if opts.tree {
+				rows = stackStatusRows(rows, opts.output == "table")
+			}
+			if opts.size {
+				if err := addStatusUsage(ctx, runner, rows); err != nil {
+					return err
+				}
+			}
+			if opts.conflicts {
+				if err := addStatusConflicts(ctx, runner, repoRoot, rows); err != nil {
+					return err
+				}
+			}
 			return renderStatus(cmd, opts.output, rows, opts.grid)
 		},
 	}
@@ -279,54 +359,90 @@ func newStatusCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&opts.abs, "abs", false, "alias for --absolute-path")
 	cmd.Flags().BoolVar(&opts.grid, "grid", false, "render table with grid borders")
 	cmd.Flags().BoolVar(&opts.strict, "strict", false, "require exact task match (after trimming and slugifying)")
+	cmd.Flags().BoolVar(&opts.tree, "tree", false, "show stacked tasks as a tree under their parent task")
+	cmd.Flags().BoolVar(&opts.size, "size", false, "add disk usage and last activity (see gwtt du)")
+	cmd.Flags().BoolVar(&opts.conflicts, "conflicts", false, "add a column of files that would conflict when merging into --target (see gwtt conflicts)")
+	cmd.Flags().BoolVar(&opts.allRepos, "all-repos", false, "show tasks of every registered or discovered repository")
 
 	return cmd
 }
 
+// addStatusUsage fills in the size columns shown by status --size.
+func addStatusUsage(ctx context.Context, runner git.Runner, rows []statusRow) error {
+	paths := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if !row.Parked {
+			paths = append(paths, row.absPath)
+		}
+	}
+	usages, err := collectUsage(ctx, runner, paths)
+	if err != nil {
+		return err
+	}
+	pos := 0
+	for idx := range rows {
+		if rows[idx].Parked {
+			var none int64
+			rows[idx].SizeBytes = &none
+			continue
+		}
+		size := usages[pos].Bytes
+		rows[idx].SizeBytes = &size
+		rows[idx].LastActivity = usages[pos].LastActivity.UTC().Format(time.RFC3339)
+		pos++
+	}
+	return nil
+}
+
+// addStatusConflicts test-merges every task branch into its row's target
+// for status --conflicts.
+func addStatusConflicts(ctx context.Context, runner git.Runner, repoRoot string, rows []statusRow) error {
+	var pairs []conflictPair
+	var indexes []int
+	for idx, row := range rows {
+		rows[idx].conflictsChecked = true
+		if row.Branch == "" || row.Branch == "detached" || row.Branch == row.Target {
+			continue
+		}
+		pairs = append(pairs, conflictPair{LeftBranch: row.Branch, RightBranch: row.Target})
+		indexes = append(indexes, idx)
+	}
+	if err := testMerges(ctx, runner, repoRoot, pairs); err != nil {
+		return err
+	}
+	for pos, idx := range indexes {
+		if len(pairs[pos].Files) > 0 {
+			rows[idx].Conflicts = pairs[pos].Files
+		}
+	}
+	return nil
+}
+
 func renderStatus(cmd *cobra.Command, format string, rows []statusRow, grid bool) error {
+	withSize := len(rows) > 0 && rows[0].SizeBytes != nil
+	withConflicts := len(rows) > 0 && rows[0].conflictsChecked
 	switch format {
 	case "table":
-		columns := []tableColumn{
-			{Header: "TASK", MinWidth: 6},
-			{Header: "BRANCH", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.AccentStyle }},
-			{Header: "PATH", MinWidth: 16, Flexible: true, Truncate: true},
-			{Header: "MODIFIED", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
-			{Header: "BASE", MinWidth: 8, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
-			{Header: "TARGET", MinWidth: 8, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
-			{Header: "LAST_COMMIT", MinWidth: 12, MaxWidth: 24, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
-			{Header: "DIRTY", MinWidth: 5, Style: func(value string) lipgloss.Style {
-				if value == "true" {
-					return ui.WarningStyle
-				}
-				return ui.SuccessStyle
-			}},
-			{Header: "AHEAD", MinWidth: 5, Style: func(value string) lipgloss.Style {
-				if value != "0" {
-					return ui.WarningStyle
-				}
-				return ui.MutedStyle
-			}},
-			{Header: "BEHIND", MinWidth: 6, Style: func(value string) lipgloss.Style {
-				if value != "0" {
-					return ui.ErrorStyle
-				}
-				return ui.MutedStyle
-			}},
+		columns := statusColumns()
+		if withSize {
+			columns = append(columns,
+				tableColumn{Header: "SIZE", MinWidth: 6},
+				tableColumn{Header: "LAST_ACTIVITY", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+			)
+		}
+		if withConflicts {
+			columns = append(columns, tableColumn{Header: "CONFLICTS", MinWidth: 9, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.ErrorStyle }})
 		}
 		tableRows := make([][]string, 0, len(rows))
 		for _, row := range rows {
-			tableRows = append(tableRows, []string{
-				row.Task,
-				row.Branch,
-				row.Path,
-				row.ModifiedTime,
-				row.Base,
-				row.Target,
-				row.LastCommit,
-				strconv.FormatBool(row.Dirty),
-				strconv.Itoa(row.Ahead),
-				strconv.Itoa(row.Behind),
-			})
+			values := statusRowValues(row)
+			if withSize {
+				values = append(values, formatSize(*row.SizeBytes), row.LastActivity)
+			}
+			if withConflicts {
+				values = append(values, strings.Join(row.Conflicts, ", "))
+			}
+			tableRows = append(tableRows, values)
 		}
 		renderTable(cmd, columns, tableRows, grid)
 		return nil
@@ -341,24 +457,27 @@ func renderStatus(cmd *cobra.Command, format string, rows []statusRow, grid bool
 		return nil
 	case "csv":
 		writer := csv.NewWriter(cmd.OutOrStdout())
-		if err := writer.Write([]string{
-			"task", "branch", "path", "modified_time", "base", "target", "last_commit", "dirty", "ahead", "behind",
-		}); err != nil {
+		header := []string{
+			"task", "branch", "path", "modified_time", "base", "target", "last_commit", "dirty", "ahead", "behind", "sparse", "submodules", "parent",
+		}
+		if withSize {
+			header = append(header, "size_bytes", "last_activity")
+		}
+		if withConflicts {
+			header = append(header, "conflicts")
+		}
+		if err := writer.Write(header); err != nil {
 			return err
 		}
 		for _, row := range rows {
-			if err := writer.Write([]string{
-				row.Task,
-				row.Branch,
-				row.Path,
-				row.ModifiedTime,
-				row.Base,
-				row.Target,
-				row.LastCommit,
-				strconv.FormatBool(row.Dirty),
-				strconv.Itoa(row.Ahead),
-				strconv.Itoa(row.Behind),
-			}); err != nil {
+			values := append(statusRowValues(row), row.Parent)
+			if withSize {
+				values = append(values, strconv.FormatInt(*row.SizeBytes, 10), row.LastActivity)
+			}
+			if withConflicts {
+				values = append(values, strings.Join(row.Conflicts, ";"))
+			}
+			if err := writer.Write(values); err != nil {
 				return err
 			}
 		}
@@ -368,3 +487,66 @@ func renderStatus(cmd *cobra.Command, format string, rows []statusRow, grid bool
 		return fmt.Errorf("unsupported output format: %s", format)
 	}
 }
+
+// statusProbes are the optional worktree probes behind the SPARSE and
+// SUBMODULES columns.
+var statusProbes = worktree.StatusOptions{Sparse: true, Submodules: true}
+
+func statusColumns() []tableColumn {
+	return []tableColumn{
+		{Header: "TASK", MinWidth: 6},
+		{Header: "BRANCH", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.AccentStyle }},
+		{Header: "PATH", MinWidth: 16, Flexible: true, Truncate: true},
+		{Header: "MODIFIED", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+		{Header: "BASE", MinWidth: 8, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+		{Header: "TARGET", MinWidth: 8, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+		{Header: "LAST_COMMIT", MinWidth: 12, MaxWidth: 24, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+		{Header: "DIRTY", MinWidth: 5, Style: func(value string) lipgloss.Style {
+			switch value {
+			case "true", parkedState:
+				return ui.WarningStyle
+			}
+			return ui.SuccessStyle
+		}},
+		{Header: "AHEAD", MinWidth: 5, Style: func(value string) lipgloss.Style {
+			if value != "0" {
+				return ui.WarningStyle
+			}
+			return ui.MutedStyle
+		}},
+		{Header: "BEHIND", MinWidth: 6, Style: func(value string) lipgloss.Style {
+			if value != "0" {
+				return ui.ErrorStyle
+			}
+			return ui.MutedStyle
+		}},
+		{Header: "SPARSE", MinWidth: 6, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+		{Header: "SUBMODULES", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style {
+			if value != "" {
+				return ui.WarningStyle
+			}
+			return ui.MutedStyle
+		}},
+	}
+}
+
+func statusRowValues(row statusRow) []string {
+	dirty := strconv.FormatBool(row.Dirty)
+	if row.Parked {
+		dirty = parkedState
+	}
+	return []string{
+		row.Task,
+		row.Branch,
+		row.Path,
+		row.ModifiedTime,
+		row.Base,
+		row.Target,
+		row.LastCommit,
+		dirty,
+		strconv.Itoa(row.Ahead),
+		strconv.Itoa(row.Behind),
+		strings.Join(row.Sparse, ","),
+		strings.Join(row.Submodules, "; "),
+	}
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+)
+
+// submoduleSummaries lists the submodules that need attention as
+// "<path> (<states>)" for status output.
+func submoduleSummaries(submodules []worktree.SubmoduleState) []string {
+	var summaries []string
+	for _, sub := range submodules {
+		if summary := sub.Summary(); summary != "" {
+			summaries = append(summaries, fmt.Sprintf("%s (%s)", sub.Path, summary))
+		}
+	}
+	return summaries
+}
+
+// checkSubmoduleRemoval decides whether removing the worktree at path needs
+// `worktree remove --force`, which git requires once submodules are checked
+// out. Unless force is set, it refuses when the worktree or any submodule
+// has uncommitted changes or a submodule has commits no remote contains.
+func checkSubmoduleRemoval(ctx context.Context, runner git.Runner, path string, force bool) (bool, error) {
+	return submoduleRemovalCheck(ctx, runner, path, force, true)
+}
+
+// checkParkRemoval is checkSubmoduleRemoval for park. The stash saves the
+// worktree's own changes, so those are allowed, but nothing saves work
+// inside submodules, so there is no force.
+func checkParkRemoval(ctx context.Context, runner git.Runner, path string) (bool, error) {
+	return submoduleRemovalCheck(ctx, runner, path, false, false)
+}
+
+func submoduleRemovalCheck(ctx context.Context, runner git.Runner, path string, force, requireClean bool) (bool, error) {
+	submodules, err := worktree.Submodules(ctx, runner, path)
+	if err != nil {
+		return false, err
+	}
+	initialized := false
+	for _, sub := range submodules {
+		if sub.Initialized() {
+			initialized = true
+		}
+	}
+	if !initialized {
+		return false, nil
+	}
+	if force {
+		return true, nil
+	}
+
+	var problems []string
+	if requireClean {
+		stdout, stderr, err := runner.Run(ctx, "-C", path, "status", "--porcelain", "--ignore-submodules=all")
+		if err != nil {
+			return false, fmt.Errorf("worktree dirty check: %w: %s", err, stderr)
+		}
+		if strings.TrimSpace(stdout) != "" {
+			problems = append(problems, "the worktree has uncommitted changes")
+		}
+	}
+	for _, sub := range submodules {
+		if sub.Dirty {
+			problems = append(problems, fmt.Sprintf("submodule %s has uncommitted changes", sub.Path))
+		}
+	}
+	unpushed, err := worktree.UnpushedSubmodules(ctx, runner, path, submodules)
+	if err != nil {
+		return false, err
+	}
+	for _, sub := range unpushed {
+		problems = append(problems, fmt.Sprintf("submodule %s has commits not pushed to any remote", sub))
+	}
+	if len(problems) > 0 {
+		return false, fmt.Errorf("refusing to remove %s: %s", path, strings.Join(problems, "; "))
+	}
+	return true, nil
+}
+
+func updateSubmodulesArgs(path string) []string {
+	return []string{"-C", path, "submodule", "update", "--init", "--recursive"}
+}
@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+)
+
+type syncOptions struct {
+	all      bool
+	strategy string
+	target   string
+	fetch    bool
+	dryRun   bool
+	output   string
+	grid     bool
+}
+
+// Sync results reported per task.
+const (
+	syncUpdated  = "updated"
+	syncUpToDate = "up-to-date"
+	syncSkipped  = "skipped"
+	syncConflict = "conflict"
+	syncFailed   = "failed"
+)
+
+type syncResult struct {
+	Task   string `json:"task"`
+	Branch string `json:"branch"`
+	Target string `json:"target"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func newSyncCommand() *cobra.Command {
+	opts := &syncOptions{strategy: "rebase", output: "table"}
+	cmd := &cobra.Command{
+		Use:   "sync [task...] [--all]",
+		Short: "Bring task branches up to date with their target",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			runner := defaultRunner(ctx)
+			if cfg, ok := configFromContext(ctx); ok && cfg.Mode == modeCodex {
+				return fmt.Errorf("sync is not supported in --mode=codex (run with --mode=classic)")
+			}
+			if opts.all == (len(args) > 0) {
+				return fmt.Errorf("name the tasks to sync or pass --all")
+			}
+			if opts.strategy != "rebase" && opts.strategy != "merge" {
+				return fmt.Errorf("unsupported strategy %q (use rebase or merge)", opts.strategy)
+			}
+			if opts.output != "table" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format: %s", opts.output)
+			}
+			if cfg, ok := configFromContext(ctx); ok && !cmd.Flags().Changed("grid") {
+				opts.grid = cfg.Status.Grid
+			}
+			modeCtx, err := resolveModeContext(cmd, true)
+			if err != nil {
+				return err
+			}
+			repoRoot, err := repoRoot(ctx, runner)
+			if err != nil {
+				return err
+			}
+			targets, err := selectSyncTargets(ctx, runner, modeCtx, repoRoot, args)
+			if err != nil {
+				return err
+			}
+			if len(targets) == 0 {
+				return fmt.Errorf("no task worktrees to sync")
+			}
+
+			if opts.fetch {
+				if err := fetchRemotes(ctx, cmd, runner, repoRoot, opts.dryRun); err != nil {
+					return err
+				}
+			}
+			defaultTarget := opts.target
+			if defaultTarget == "" {
+				defaultTarget, err = git.CurrentBranch(ctx, runner)
+				if err != nil {
+					return err
+				}
+			}
+			presets, err := git.BranchConfigValues(ctx, runner, repoRoot, branchPresetKey)
+			if err != nil {
+				return err
+			}
+
+			results := make([]syncResult, 0, len(targets))
+			for _, tw := range targets {
+				target := defaultTarget
+				if opts.target == "" {
+					if preset, ok := taskPreset(ctx, taskRef{branch: tw.branch, preset: presets[tw.branch]}); ok && preset.Base != "" {
+						target = preset.Base
+					}
+				}
+				if opts.fetch {
+					target = upstreamOrBranch(ctx, runner, repoRoot, target)
+				}
+				results = append(results, syncTask(ctx, cmd, runner, tw, target, opts))
+			}
+			if opts.dryRun {
+				return nil
+			}
+			if err := renderSyncResults(cmd, opts.output, results, opts.grid); err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, result := range results {
+				if result.Result == syncConflict || result.Result == syncFailed {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d task(s) could not be synced", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.all, "all", false, "sync every task worktree")
+	cmd.Flags().StringVar(&opts.strategy, "strategy", opts.strategy, "how to update task branches: rebase or merge")
+	cmd.Flags().StringVar(&opts.target, "target", "", "branch to sync onto (default: the task preset base, else the current branch)")
+	cmd.Flags().BoolVar(&opts.fetch, "fetch", false, "fetch remotes first and sync onto the target's upstream when it has one")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "show git commands without executing")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", opts.output, "output format: table or json")
+	cmd.Flags().BoolVar(&opts.grid, "grid", false, "render table with grid borders")
+
+	return cmd
+}
+
+// selectSyncTargets returns every task worktree, or the ones named in args
+// by exact task name.
+func selectSyncTargets(ctx context.Context, runner git.Runner, modeCtx modeContext, repoRoot string, args []string) ([]taskWorktree, error) {
+	tasks, err := listTaskWorktrees(ctx, runner, modeCtx, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return tasks, nil
+	}
+	var selected []taskWorktree
+	for _, raw := range args {
+		query, err := normalizeTaskQuery(raw)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, tw := range tasks {
+			if matchesTask(tw.task, query, true) {
+				selected = append(selected, tw)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no worktree found for task %q", raw)
+		}
+	}
+	return selected, nil
+}
+
+// fetchRemotes runs `git fetch --all --prune` when the repository has a
+// remote; without one, sync works on local branches only.
+func fetchRemotes(ctx context.Context, cmd *cobra.Command, runner git.Runner, repoRoot string, dryRun bool) error {
+	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "remote")
+	if err != nil {
+		return fmt.Errorf("list remotes: %w: %s", err, stderr)
+	}
+	if strings.TrimSpace(stdout) == "" {
+		return nil
+	}
+	return runGit(ctx, cmd, dryRun, runner, "-C", repoRoot, "fetch", "--all", "--prune")
+}
+
+// upstreamOrBranch returns the upstream of branch, e.g. origin/main, or
+// branch itself when it has none.
+func upstreamOrBranch(ctx context.Context, runner git.Runner, repoRoot, branch string) string {
+	stdout, _, err := runner.Run(ctx, "-C", repoRoot, "rev-parse", "--abbrev-ref", "--symbolic-full-name", branch+"@{upstream}")
+	if err != nil || strings.TrimSpace(stdout) == "" {
+		return branch
+	}
+	return strings.TrimSpace(stdout)
+}
+
+// syncTask updates one task branch onto target inside its worktree. A
+// conflicting rebase or merge is aborted so the worktree is left as it was.
+func syncTask(ctx context.Context, cmd *cobra.Command, runner git.Runner, tw taskWorktree, target string, opts *syncOptions) syncResult {
+	result := syncResult{Task: tw.task, Branch: tw.branch, Target: target}
+	if tw.branch == "detached" {
+		result.Result, result.Detail = syncSkipped, "detached HEAD"
+		return result
+	}
+	if tw.branch == target {
+		result.Result, result.Detail = syncSkipped, "branch is the target"
+		return result
+	}
+	info, err := worktree.Status(ctx, runner, tw.path, target)
+	if err != nil {
+		result.Result, result.Detail = syncFailed, err.Error()
+		return result
+	}
+	if info.Dirty {
+		result.Result, result.Detail = syncSkipped, "uncommitted changes"
+		return result
+	}
+	if info.Behind == 0 {
+		result.Result = syncUpToDate
+		return result
+	}
+
+	args := []string{"-C", tw.path, "rebase", target}
+	abort := []string{"-C", tw.path, "rebase", "--abort"}
+	if opts.strategy == "merge" {
+		args = []string{"-C", tw.path, "merge", "--no-edit", target}
+		abort = []string{"-C", tw.path, "merge", "--abort"}
+	}
+	if err := runGit(ctx, cmd, opts.dryRun, runner, args...); err != nil {
+		if abortErr := runGit(ctx, cmd, false, runner, abort...); abortErr != nil {
+			result.Result, result.Detail = syncFailed, err.Error()
+			return result
+		}
+		result.Result, result.Detail = syncConflict, fmt.Sprintf("%s aborted, worktree unchanged", opts.strategy)
+		return result
+	}
+	result.Result, result.Detail = syncUpdated, fmt.Sprintf("%d commit(s) from %s", info.Behind, target)
+	return result
+}
+
+func renderSyncResults(cmd *cobra.Command, format string, results []syncResult, grid bool) error {
+	if format == "json" {
+		payload, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+		return err
+	}
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, []string{result.Task, result.Branch, result.Target, result.Result, result.Detail})
+	}
+	renderTable(cmd, []tableColumn{
+		{Header: "TASK", MinWidth: 6},
+		{Header: "BRANCH", MinWidth: 10, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.AccentStyle }},
+		{Header: "TARGET", MinWidth: 6},
+		{Header: "RESULT", MinWidth: 6, Style: func(value string) lipgloss.Style {
+			switch value {
+			case syncUpdated, syncUpToDate:
+				return ui.SuccessStyle
+			case syncSkipped:
+				return ui.WarningStyle
+			default:
+				return ui.ErrorStyle
+			}
+		}},
+		{Header: "DETAIL", MinWidth: 6, Flexible: true, Truncate: true, Style: func(value string) lipgloss.Style { return ui.MutedStyle }},
+	}, rows, grid)
+	return nil
+}
@@ -0,0 +1,395 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+	"github.com/pi2pie/git-worktree-tasks/internal/worktree"
+	"github.com/pi2pie/git-worktree-tasks/internal/workspace"
+	"github.com/pi2pie/git-worktree-tasks/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+type workspaceOptions struct {
+	file string
+}
+
+type workspaceStatusRow struct {
+	Repo string `json:"repo"`
+	statusRow
+}
+
+func newWorkspaceCommand() *cobra.Command {
+	opts := &workspaceOptions{}
+	cmd := &cobra.Command{
+		Use:     "ws",
+		Aliases: []string{"workspace"},
+		Short:   "Run task commands across the repositories of a workspace",
+		Long:    "Run task commands across every repository listed in " + workspace.FileName + ".",
+	}
+	cmd.PersistentFlags().StringVarP(&opts.file, "workspace", "w", "", "workspace file (default: nearest "+workspace.FileName+")")
+	cmd.AddCommand(
+		newWorkspaceCreateCommand(opts),
+		newWorkspaceStatusCommand(opts),
+		newWorkspaceFinishCommand(opts),
+		newWorkspaceCleanupCommand(opts),
+	)
+	return cmd
+}
+
+func newWorkspaceCreateCommand(wsOpts *workspaceOptions) *cobra.Command {
+	var base string
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "create <task>",
+		Short: "Create the task worktree and branch in every repository",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace(cmd, wsOpts)
+			if err != nil {
+				return err
+			}
+			childArgs := []string{"create", args[0]}
+			if base != "" {
+				childArgs = append(childArgs, "--base", base)
+			}
+			if err := workspacePreflight(cmd, ws, func(repo workspace.Repo) error {
+				_, stderr, err := runInRepo(cmd, repo.Path, append(childArgs, "--dry-run"), nil)
+				return childError(err, stderr)
+			}); err != nil {
+				return err
+			}
+			if dryRun {
+				childArgs = append(childArgs, "--dry-run")
+			}
+			return runAcrossWorkspace(cmd, ws, childArgs)
+		},
+	}
+	cmd.Flags().StringVar(&base, "base", "", "base branch to create from in every repository (default: each current branch)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show git commands without executing")
+	return cmd
+}
+
+func newWorkspaceStatusCommand(wsOpts *workspaceOptions) *cobra.Command {
+	output := "table"
+	var target string
+	var grid bool
+	cmd := &cobra.Command{
+		Use:   "status [task]",
+		Short: "Show task worktree status across every repository",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace(cmd, wsOpts)
+			if err != nil {
+				return err
+			}
+			if output != "table" && output != "json" {
+				return fmt.Errorf("unsupported output format: %s", output)
+			}
+			if cfg, ok := configFromContext(cmd.Context()); ok && !cmd.Flags().Changed("grid") {
+				grid = cfg.Status.Grid
+			}
+			childArgs := append([]string{"status", "-o", "json"}, args...)
+			if target != "" {
+				childArgs = append(childArgs, "--target", target)
+			}
+
+			rows := []workspaceStatusRow{}
+			for _, repo := range ws.Repos {
+				stdout, stderr, err := runInRepo(cmd, repo.Path, childArgs, nil)
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s: %v\n", repo.Name, childError(err, stderr))
+					continue
+				}
+				var repoRows []statusRow
+				if err := json.Unmarshal([]byte(stdout), &repoRows); err != nil {
+					return fmt.Errorf("%s: read status: %w", repo.Name, err)
+				}
+				for _, row := range repoRows {
+					rows = append(rows, workspaceStatusRow{Repo: repo.Name, statusRow: row})
+				}
+			}
+
+			if output == "json" {
+				return writeJSON(cmd, rows)
+			}
+			columns := append([]tableColumn{repoColumn()}, statusColumns()...)
+			tableRows := make([][]string, 0, len(rows))
+			for _, row := range rows {
+				tableRows = append(tableRows, append([]string{row.Repo}, statusRowValues(row.statusRow)...))
+			}
+			renderTable(cmd, columns, tableRows, grid)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", output, "output format: table or json")
+	cmd.Flags().StringVar(&target, "target", "", "target branch for ahead/behind comparison")
+	cmd.Flags().BoolVar(&grid, "grid", false, "render table with grid borders")
+	return cmd
+}
+
+func newWorkspaceFinishCommand(wsOpts *workspaceOptions) *cobra.Command {
+	var target string
+	var cleanup, yes, dryRun bool
+	cmd := &cobra.Command{
+		Use:   "finish <task>",
+		Short: "Merge the task branch in every repository",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace(cmd, wsOpts)
+			if err != nil {
+				return err
+			}
+			task := worktree.SlugifyTask(args[0])
+			childArgs := []string{"finish", task, "--yes"}
+			if target != "" {
+				childArgs = append(childArgs, "--target", target)
+			}
+			if cleanup {
+				childArgs = append(childArgs, "--cleanup")
+			}
+			for _, name := range []string{"no-ff", "squash", "rebase"} {
+				if cmd.Flags().Changed(name) {
+					childArgs = append(childArgs, "--"+name)
+				}
+			}
+
+			if err := workspacePreflight(cmd, ws, func(repo workspace.Repo) error {
+				ctx := cmd.Context()
+				runner := defaultRunner(ctx)
+				if err := requireTaskBranch(ctx, runner, repo.Path, task); err != nil {
+					return err
+				}
+				if target == "" {
+					current, err := git.CurrentBranchAt(ctx, runner, repo.Path)
+					if err != nil {
+						return err
+					}
+					if current == "HEAD" {
+						return fmt.Errorf("detached HEAD: pass --target")
+					}
+				}
+				changes, stderr, err := runner.Run(ctx, "-C", repo.Path, "status", "--porcelain", "--untracked-files=no")
+				if err != nil {
+					return childError(err, stderr)
+				}
+				if strings.TrimSpace(changes) != "" {
+					return fmt.Errorf("main checkout has uncommitted changes")
+				}
+				_, stderr, err = runInRepo(cmd, repo.Path, append(childArgs, "--dry-run"), nil)
+				return childError(err, stderr)
+			}); err != nil {
+				return err
+			}
+			if dryRun {
+				return runAcrossWorkspace(cmd, ws, append(childArgs, "--dry-run"))
+			}
+			if err := confirmWorkspace(cmd, ws, fmt.Sprintf("Finish %q", task), yes); err != nil {
+				return err
+			}
+			return runAcrossWorkspace(cmd, ws, childArgs)
+		},
+	}
+	cmd.Flags().StringVar(&target, "target", "", "target branch in every repository (default: each current branch)")
+	cmd.Flags().BoolVar(&cleanup, "cleanup", false, "remove worktree and branch after merge")
+	cmd.Flags().Bool("no-ff", false, "use --no-ff merge")
+	cmd.Flags().Bool("squash", false, "use --squash merge")
+	cmd.Flags().Bool("rebase", false, "rebase task branch onto target before merging")
+	cmd.Flags().BoolVar(&yes, "yes", false, "skip the confirmation prompt")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show git commands without executing")
+	return cmd
+}
+
+func newWorkspaceCleanupCommand(wsOpts *workspaceOptions) *cobra.Command {
+	var worktreeOnly, forceBranch, yes, dryRun bool
+	cmd := &cobra.Command{
+		Use:     "cleanup <task>",
+		Aliases: []string{"rm"},
+		Short:   "Remove the task worktree and branch in every repository",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := loadWorkspace(cmd, wsOpts)
+			if err != nil {
+				return err
+			}
+			task := worktree.SlugifyTask(args[0])
+			childArgs := []string{"cleanup", task, "--yes"}
+			if worktreeOnly {
+				childArgs = append(childArgs, "--worktree-only")
+			}
+			if forceBranch {
+				childArgs = append(childArgs, "--force-branch")
+			}
+
+			if err := workspacePreflight(cmd, ws, func(repo workspace.Repo) error {
+				ctx := cmd.Context()
+				runner := defaultRunner(ctx)
+				if err := requireTaskBranch(ctx, runner, repo.Path, task); err != nil {
+					return err
+				}
+				_, stderr, err := runInRepo(cmd, repo.Path, append(childArgs, "--dry-run"), nil)
+				return childError(err, stderr)
+			}); err != nil {
+				return err
+			}
+			if dryRun {
+				return runAcrossWorkspace(cmd, ws, append(childArgs, "--dry-run"))
+			}
+			if err := confirmWorkspace(cmd, ws, fmt.Sprintf("Remove %q", task), yes); err != nil {
+				return err
+			}
+			return runAcrossWorkspace(cmd, ws, childArgs)
+		},
+	}
+	cmd.Flags().BoolVar(&worktreeOnly, "worktree-only", false, "remove only the worktrees, keep the branches")
+	cmd.Flags().BoolVar(&forceBranch, "force-branch", false, "force delete branches (-D)")
+	cmd.Flags().BoolVar(&yes, "yes", false, "skip the confirmation prompt")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show git commands without executing")
+	return cmd
+}
+
+// requireTaskBranch checks that task has a branch in the repository at
+// repoPath, honoring the branch prefix of the preset it was created with.
+func requireTaskBranch(ctx context.Context, runner git.Runner, repoPath, task string) error {
+	ref, err := resolveTaskRef(ctx, runner, repoPath, task)
+	if err != nil {
+		return err
+	}
+	exists, err := git.BranchExists(ctx, runner, repoPath, ref.branch)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("no branch %q", ref.branch)
+	}
+	return nil
+}
+
+func loadWorkspace(cmd *cobra.Command, opts *workspaceOptions) (workspace.Workspace, error) {
+	if cfg, ok := configFromContext(cmd.Context()); ok && cfg.Mode == modeCodex {
+		return workspace.Workspace{}, fmt.Errorf("ws is not supported in --mode=codex (use --mode=classic)")
+	}
+	path := opts.file
+	if path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return workspace.Workspace{}, err
+		}
+		path, err = workspace.Find(cwd)
+		if err != nil {
+			return workspace.Workspace{}, err
+		}
+	}
+	return workspace.Load(path)
+}
+
+// workspacePreflight runs check in every repository and reports all
+// failures together, so nothing is changed unless every repository is ready.
+func workspacePreflight(cmd *cobra.Command, ws workspace.Workspace, check func(repo workspace.Repo) error) error {
+	failed := 0
+	for _, repo := range ws.Repos {
+		if _, err := os.Stat(repo.Path); err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s %s: %v\n", ui.ErrorStyle.Render("preflight failed:"), repo.Name, err)
+			failed++
+			continue
+		}
+		if err := check(repo); err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s %s: %v\n", ui.ErrorStyle.Render("preflight failed:"), repo.Name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("workspace preflight failed in %d of %d repositories; nothing was changed", failed, len(ws.Repos))
+	}
+	return nil
+}
+
+func confirmWorkspace(cmd *cobra.Command, ws workspace.Workspace, action string, yes bool) error {
+	if yes {
+		return nil
+	}
+	names := make([]string, 0, len(ws.Repos))
+	for _, repo := range ws.Repos {
+		names = append(names, repo.Name)
+	}
+	ok, err := confirmPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), fmt.Sprintf("%s in %s?", action, strings.Join(names, ", ")))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errCanceled
+	}
+	return nil
+}
+
+// runAcrossWorkspace runs the same gwtt command in each repository in order
+// and stops at the first failure, naming the repositories already done.
+func runAcrossWorkspace(cmd *cobra.Command, ws workspace.Workspace, args []string) error {
+	out := cmd.OutOrStdout()
+	var done []string
+	for idx, repo := range ws.Repos {
+		if _, err := fmt.Fprintf(out, "%s %s\n", ui.TitleStyle.Render("["+repo.Name+"]"), ui.MutedStyle.Render(repo.Path)); err != nil {
+			return err
+		}
+		if _, _, err := runInRepo(cmd, repo.Path, args, out); err != nil {
+			var pending []string
+			for _, rest := range ws.Repos[idx+1:] {
+				pending = append(pending, rest.Name)
+			}
+			summary := fmt.Sprintf("completed: %s; not started: %s", listOrNone(done), listOrNone(pending))
+			_, _ = fmt.Fprintln(out, ui.WarningStyle.Render(summary))
+			return fmt.Errorf("%s: %w", repo.Name, err)
+		}
+		done = append(done, repo.Name)
+	}
+	return nil
+}
+
+// runInRepo executes a gwtt command line in dir, forwarding the global
+// flags of the current invocation. dir is passed down the context, so the
+// process working directory never changes. Output is captured unless out
+// is given.
+func runInRepo(cmd *cobra.Command, dir string, args []string, out io.Writer) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	child, _ := gitWorkTreeCommand()
+	if out != nil {
+		child.SetOut(out)
+		child.SetErr(cmd.ErrOrStderr())
+	} else {
+		child.SetOut(&stdout)
+		child.SetErr(&stderr)
+	}
+	child.SetIn(cmd.InOrStdin())
+	var forwarded []string
+	cmd.Root().PersistentFlags().Visit(func(flag *pflag.Flag) {
+		forwarded = append(forwarded, "--"+flag.Name+"="+flag.Value.String())
+	})
+	child.SetArgs(append(forwarded, args...))
+	err := child.ExecuteContext(withWorkDir(cmd.Context(), dir))
+	return stdout.String(), stderr.String(), err
+}
+
+func childError(err error, stderr string) error {
+	if err == nil {
+		return nil
+	}
+	if stderr = strings.TrimSpace(stderr); stderr != "" && !errors.Is(err, errCanceled) {
+		return fmt.Errorf("%w: %s", err, stderr)
+	}
+	return err
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
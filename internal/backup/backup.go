@@ -0,0 +1,309 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+)
+
+const (
+	// RefPrefix is where backup commits are kept so git gc does not drop them.
+	RefPrefix = "refs/gwtt/backups/"
+
+	storeDir      = "gwtt/backups"
+	metaFile      = "meta.json"
+	untrackedFile = "untracked.tar.gz"
+	idLayout      = "20060102T150405Z"
+)
+
+// Backup describes one snapshot of a checkout taken before a destructive step.
+type Backup struct {
+	ID        string    `json:"id"`
+	Created   time.Time `json:"created"`
+	Reason    string    `json:"reason"`
+	Task      string    `json:"task,omitempty"`
+	Path      string    `json:"path"`
+	Head      string    `json:"head"`
+	Branch    string    `json:"branch"`
+	Stash     string    `json:"stash,omitempty"`
+	Untracked int       `json:"untracked"`
+}
+
+// Ref returns the ref that pins the backup commit.
+func (b Backup) Ref() string {
+	return RefPrefix + b.ID
+}
+
+// CreateOptions describes the checkout to snapshot.
+type CreateOptions struct {
+	CommonDir string
+	Path      string
+	Reason    string
+	Task      string
+	Now       time.Time
+}
+
+// Create snapshots the tracked diff (index and working tree), the untracked
+// files and HEAD of opts.Path. Tracked state is stored as a stash-like commit
+// under refs/gwtt/backups/<id>; untracked files go into a tar.gz archive in
+// the git common dir.
+func Create(ctx context.Context, runner git.Runner, opts CreateOptions) (Backup, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	head, err := git.RevParse(ctx, runner, opts.Path, "HEAD")
+	if err != nil {
+		return Backup{}, err
+	}
+	branch, err := git.CurrentBranchAt(ctx, runner, opts.Path)
+	if err != nil {
+		return Backup{}, err
+	}
+	stash, stderr, err := runner.Run(ctx, "-C", opts.Path, "stash", "create", "gwtt backup before "+opts.Reason)
+	if err != nil {
+		return Backup{}, fmt.Errorf("backup stash create: %w: %s", err, stderr)
+	}
+	untracked, err := listUntracked(ctx, runner, opts.Path)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	id, dir, err := reserveID(opts.CommonDir, now.UTC())
+	if err != nil {
+		return Backup{}, err
+	}
+	b := Backup{
+		ID:        id,
+		Created:   now.UTC(),
+		Reason:    opts.Reason,
+		Task:      opts.Task,
+		Path:      opts.Path,
+		Head:      head,
+		Branch:    branch,
+		Stash:     strings.TrimSpace(stash),
+		Untracked: len(untracked),
+	}
+
+	cleanup := func(cause error) error {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("%w (cleanup failed: %v)", cause, err)
+		}
+		return cause
+	}
+	if len(untracked) > 0 {
+		if err := writeArchive(filepath.Join(dir, untrackedFile), opts.Path, untracked); err != nil {
+			return Backup{}, cleanup(err)
+		}
+	}
+	target := b.Head
+	if b.Stash != "" {
+		target = b.Stash
+	}
+	if _, stderr, err := runner.Run(ctx, "-C", opts.Path, "update-ref", b.Ref(), target); err != nil {
+		return Backup{}, cleanup(fmt.Errorf("backup update-ref: %w: %s", err, stderr))
+	}
+	if err := writeMeta(dir, b); err != nil {
+		return Backup{}, cleanup(err)
+	}
+	return b, nil
+}
+
+// reserveID creates the backup directory, suffixing the timestamp id when
+// several backups are taken within the same second.
+func reserveID(commonDir string, now time.Time) (string, string, error) {
+	root := filepath.Join(commonDir, filepath.FromSlash(storeDir))
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return "", "", err
+	}
+	base := now.Format(idLayout)
+	for i := 0; i < 100; i++ {
+		id := base
+		if i > 0 {
+			id = fmt.Sprintf("%s-%d", base, i)
+		}
+		dir := filepath.Join(root, id)
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			return id, dir, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", "", err
+		}
+	}
+	return "", "", fmt.Errorf("unable to allocate backup id for %s", base)
+}
+
+func writeMeta(dir string, b Backup) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, metaFile), data, 0o644)
+}
+
+// List returns all backups in the store, newest first.
+func List(commonDir string) ([]Backup, error) {
+	root := filepath.Join(commonDir, filepath.FromSlash(storeDir))
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var backups []Backup
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		b, err := readMeta(filepath.Join(root, entry.Name()))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].Created.Equal(backups[j].Created) {
+			return backups[i].ID > backups[j].ID
+		}
+		return backups[i].Created.After(backups[j].Created)
+	})
+	return backups, nil
+}
+
+// Load returns the backup with the given id.
+func Load(commonDir, id string) (Backup, error) {
+	id = strings.TrimSpace(id)
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return Backup{}, fmt.Errorf("invalid backup id %q", id)
+	}
+	b, err := readMeta(filepath.Join(commonDir, filepath.FromSlash(storeDir), id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Backup{}, fmt.Errorf("backup %q not found", id)
+		}
+		return Backup{}, err
+	}
+	return b, nil
+}
+
+// Update calls fn for every backup in the store and saves the metadata of
+// those it changed, reporting how many were rewritten. Commands that move or
+// rename a checkout use it so restore still finds the right path.
+func Update(commonDir string, fn func(b *Backup) bool) (int, error) {
+	backups, err := List(commonDir)
+	if err != nil {
+		return 0, err
+	}
+	updated := 0
+	for _, b := range backups {
+		if !fn(&b) {
+			continue
+		}
+		if err := writeMeta(filepath.Join(commonDir, filepath.FromSlash(storeDir), b.ID), b); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func readMeta(dir string) (Backup, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metaFile))
+	if err != nil {
+		return Backup{}, err
+	}
+	var b Backup
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Backup{}, fmt.Errorf("read backup %s: %w", dir, err)
+	}
+	return b, nil
+}
+
+// Restore puts the checkout at b.Path back to the snapshot: the working tree
+// is reset to the recorded HEAD, the tracked diff is reapplied with its index
+// state, and untracked files are extracted from the archive.
+func Restore(ctx context.Context, runner git.Runner, commonDir string, b Backup) error {
+	steps := [][]string{
+		{"-C", b.Path, "reset", "-q", "--hard", b.Head},
+		{"-C", b.Path, "clean", "-fdq"},
+	}
+	if b.Stash != "" {
+		steps = append(steps, []string{"-C", b.Path, "stash", "apply", "--index", b.Stash})
+	}
+	for _, args := range steps {
+		if _, stderr, err := runner.Run(ctx, args...); err != nil {
+			return fmt.Errorf("restore %s: %w: %s", args[2], err, stderr)
+		}
+	}
+	if b.Untracked == 0 {
+		return nil
+	}
+	return extractArchive(filepath.Join(commonDir, filepath.FromSlash(storeDir), b.ID, untrackedFile), b.Path)
+}
+
+// Delete removes a backup's ref and stored files.
+func Delete(ctx context.Context, runner git.Runner, commonDir string, b Backup) error {
+	if _, stderr, err := runner.Run(ctx, "--git-dir", commonDir, "update-ref", "-d", b.Ref()); err != nil {
+		return fmt.Errorf("delete backup ref %s: %w: %s", b.Ref(), err, stderr)
+	}
+	return os.RemoveAll(filepath.Join(commonDir, filepath.FromSlash(storeDir), b.ID))
+}
+
+// Expired returns the backups that fall outside the retention policy: beyond
+// the newest keep entries, or older than maxAge. Zero values disable a rule.
+// backups must be sorted newest first, as returned by List.
+func Expired(backups []Backup, keep int, maxAge time.Duration, now time.Time) []Backup {
+	var expired []Backup
+	for i, b := range backups {
+		if keep > 0 && i >= keep {
+			expired = append(expired, b)
+			continue
+		}
+		if maxAge > 0 && now.Sub(b.Created) > maxAge {
+			expired = append(expired, b)
+		}
+	}
+	return expired
+}
+
+// Prune deletes the backups Expired selects and returns them.
+func Prune(ctx context.Context, runner git.Runner, commonDir string, keep int, maxAge time.Duration, now time.Time) ([]Backup, error) {
+	backups, err := List(commonDir)
+	if err != nil {
+		return nil, err
+	}
+	expired := Expired(backups, keep, maxAge, now)
+	for _, b := range expired {
+		if err := Delete(ctx, runner, commonDir, b); err != nil {
+			return nil, err
+		}
+	}
+	return expired, nil
+}
+
+func listUntracked(ctx context.Context, runner git.Runner, path string) ([]string, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", path, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("backup ls-files: %w: %s", err, stderr)
+	}
+	var out []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out, nil
+}
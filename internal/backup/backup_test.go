@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	backups := []Backup{
+		{ID: "c", Created: now.Add(-1 * time.Hour)},
+		{ID: "b", Created: now.Add(-48 * time.Hour)},
+		{ID: "a", Created: now.Add(-72 * time.Hour)},
+	}
+
+	tests := []struct {
+		name   string
+		keep   int
+		maxAge time.Duration
+		want   []string
+	}{
+		{name: "no limits", want: nil},
+		{name: "keep", keep: 2, want: []string{"a"}},
+		{name: "max age", maxAge: 24 * time.Hour, want: []string{"b", "a"}},
+		{name: "both", keep: 1, maxAge: 60 * time.Hour, want: []string{"b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Expired(backups, tt.keep, tt.maxAge, now)
+			var ids []string
+			for _, b := range got {
+				ids = append(ids, b.ID)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("Expired() = %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("Expired() = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "dir", "a.txt"), []byte("alpha\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "untracked.tar.gz")
+	if err := writeArchive(archive, src, []string{"dir/a.txt", "run.sh"}); err != nil {
+		t.Fatalf("writeArchive() error = %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dest, "run.sh"), []byte("stale\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractArchive(archive, dest); err != nil {
+		t.Fatalf("extractArchive() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "dir", "a.txt"))
+	if err != nil || string(data) != "alpha\n" {
+		t.Fatalf("dir/a.txt = %q, %v", data, err)
+	}
+	info, err := os.Stat(filepath.Join(dest, "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0o100 == 0 {
+		t.Fatalf("run.sh mode = %v, want executable", info.Mode())
+	}
+}
+
+func TestListOrdersNewestFirst(t *testing.T) {
+	commonDir := t.TempDir()
+	base := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	for i, offset := range []time.Duration{0, 2 * time.Hour, time.Hour} {
+		id, dir, err := reserveID(commonDir, base.Add(offset))
+		if err != nil {
+			t.Fatalf("reserveID(%d) error = %v", i, err)
+		}
+		if err := writeMeta(dir, Backup{ID: id, Created: base.Add(offset)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := List(commonDir)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := []string{"20260110T020000Z", "20260110T010000Z", "20260110T000000Z"}
+	if len(backups) != len(want) {
+		t.Fatalf("List() returned %d backups, want %d", len(backups), len(want))
+	}
+	for i, b := range backups {
+		if b.ID != want[i] {
+			t.Fatalf("List()[%d].ID = %q, want %q", i, b.ID, want[i])
+		}
+	}
+	if _, err := Load(commonDir, "../x"); err == nil {
+		t.Fatalf("Load() accepted an id with a path separator")
+	}
+}
+
+func TestUpdateRewritesMatchingBackups(t *testing.T) {
+	commonDir := t.TempDir()
+	base := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	for i, path := range []string{"/work/repo_old", "/work/repo_other"} {
+		id, dir, err := reserveID(commonDir, base.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeMeta(dir, Backup{ID: id, Created: base, Path: path, Task: "old"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	updated, err := Update(commonDir, func(b *Backup) bool {
+		if b.Path != "/work/repo_old" {
+			return false
+		}
+		b.Path = "/work/repo_new"
+		b.Task = "new"
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated != 1 {
+		t.Fatalf("Update() = %d, want 1", updated)
+	}
+	b, err := Load(commonDir, "20260110T000000Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Path != "/work/repo_new" || b.Task != "new" {
+		t.Fatalf("rewritten backup = %+v", b)
+	}
+	other, err := Load(commonDir, "20260110T010000Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Path != "/work/repo_other" || other.Task != "old" {
+		t.Fatalf("unrelated backup changed: %+v", other)
+	}
+}
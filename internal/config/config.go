@@ -16,16 +16,19 @@ const (
 )
 
 type Config struct {
-	Mode    string
-	Theme   ThemeConfig
-	UI      UIConfig
-	Table   TableConfig
-	DryRun  DryRunConfig
-	Create  CreateConfig
-	List    ListConfig
-	Status  StatusConfig
-	Finish  FinishConfig
-	Cleanup CleanupConfig
+	Mode     string
+	Theme    ThemeConfig
+	UI       UIConfig
+	Table    TableConfig
+	DryRun   DryRunConfig
+	Create   CreateConfig
+	List     ListConfig
+	Status   StatusConfig
+	Finish   FinishConfig
+	Cleanup  CleanupConfig
+	Backup   BackupConfig
+	Registry RegistryConfig
+	Presets  map[string]PresetConfig
 }
 
 type ThemeConfig struct {
@@ -45,9 +48,11 @@ type DryRunConfig struct {
 }
 
 type CreateConfig struct {
-	Output       string
-	SkipExisting bool
-	Path         CreatePathConfig
+	Output            string
+	SkipExisting      bool
+	RecurseSubmodules bool
+	Path              CreatePathConfig
+	Sparse            CreateSparseConfig
 }
 
 type CreatePathConfig struct {
@@ -55,6 +60,12 @@ type CreatePathConfig struct {
 	Format string
 }
 
+// CreateSparseConfig is the default sparse-checkout profile for new
+// worktrees. No directories means a full checkout.
+type CreateSparseConfig struct {
+	Dirs []string
+}
+
 type ListConfig struct {
 	Output       string
 	Field        string
@@ -77,6 +88,28 @@ type FinishConfig struct {
 	ForceBranch    bool
 	MergeMode      string
 	Confirm        bool
+	Checks         []FinishCheckConfig
+	Policy         FinishPolicyConfig
+}
+
+// FinishPolicyConfig restricts what `finish` may merge. Protected and the
+// MergeModes keys are branch globs matched against the target.
+type FinishPolicyConfig struct {
+	Protected       []string
+	MergeModes      map[string][]string
+	RequireUpToDate bool
+	RequireClean    bool
+}
+
+// FinishCheckConfig is a named command `finish` runs in the task worktree
+// before it merges. Dir is relative to the worktree root; Timeout is a Go
+// duration such as "10m", empty for no limit.
+type FinishCheckConfig struct {
+	Name    string
+	Run     string
+	Dir     string
+	Timeout string
+	Env     map[string]string
 }
 
 type CleanupConfig struct {
@@ -87,6 +120,30 @@ type CleanupConfig struct {
 	Confirm        bool
 }
 
+type BackupConfig struct {
+	Enabled    bool
+	Keep       int
+	MaxAgeDays int
+}
+
+type RegistryConfig struct {
+	Enabled  bool
+	Roots    []string
+	MaxDepth int
+}
+
+// PresetConfig is a named set of create defaults selected with
+// `gwtt create --preset`. Empty Path fields fall back to [create.path].
+type PresetConfig struct {
+	Base         string
+	BranchPrefix string
+	Path         CreatePathConfig
+	MergeMode    string
+	ForceBranch  bool
+	PostCreate   []string
+	Copy         []string
+}
+
 func DefaultConfig() Config {
 	return Config{
 		Mode: "classic",
@@ -138,20 +195,32 @@ func DefaultConfig() Config {
 			ForceBranch:    false,
 			Confirm:        true,
 		},
+		Backup: BackupConfig{
+			Enabled:    true,
+			Keep:       20,
+			MaxAgeDays: 30,
+		},
+		Registry: RegistryConfig{
+			Enabled:  true,
+			MaxDepth: 3,
+		},
 	}
 }
 
 type loadedConfigFile struct {
-	Mode    *string           `toml:"mode"`
-	Theme   themeConfigFile   `toml:"theme"`
-	UI      uiConfigFile      `toml:"ui"`
-	Table   tableConfigFile   `toml:"table"`
-	DryRun  dryRunConfigFile  `toml:"dry_run"`
-	Create  createConfigFile  `toml:"create"`
-	List    listConfigFile    `toml:"list"`
-	Status  statusConfigFile  `toml:"status"`
-	Finish  finishConfigFile  `toml:"finish"`
-	Cleanup cleanupConfigFile `toml:"cleanup"`
+	Mode     *string                     `toml:"mode"`
+	Theme    themeConfigFile             `toml:"theme"`
+	UI       uiConfigFile                `toml:"ui"`
+	Table    tableConfigFile             `toml:"table"`
+	DryRun   dryRunConfigFile            `toml:"dry_run"`
+	Create   createConfigFile            `toml:"create"`
+	List     listConfigFile              `toml:"list"`
+	Status   statusConfigFile            `toml:"status"`
+	Finish   finishConfigFile            `toml:"finish"`
+	Cleanup  cleanupConfigFile           `toml:"cleanup"`
+	Backup   backupConfigFile            `toml:"backup"`
+	Registry registryConfigFile          `toml:"registry"`
+	Presets  map[string]presetConfigFile `toml:"presets"`
 }
 
 type themeConfigFile struct {
@@ -171,9 +240,11 @@ type dryRunConfigFile struct {
 }
 
 type createConfigFile struct {
-	Output       *string        `toml:"output"`
-	SkipExisting *bool          `toml:"skip_existing"`
-	Path         createPathFile `toml:"path"`
+	Output            *string          `toml:"output"`
+	SkipExisting      *bool            `toml:"skip_existing"`
+	RecurseSubmodules *bool            `toml:"recurse_submodules"`
+	Path              createPathFile   `toml:"path"`
+	Sparse            createSparseFile `toml:"sparse"`
 }
 
 type createPathFile struct {
@@ -181,6 +252,10 @@ type createPathFile struct {
 	Format *string `toml:"format"`
 }
 
+type createSparseFile struct {
+	Dirs *[]string `toml:"dirs"`
+}
+
 type listConfigFile struct {
 	Output       *string `toml:"output"`
 	Field        *string `toml:"field"`
@@ -197,12 +272,29 @@ type statusConfigFile struct {
 }
 
 type finishConfigFile struct {
-	Cleanup        *bool   `toml:"cleanup"`
-	RemoveWorktree *bool   `toml:"remove_worktree"`
-	RemoveBranch   *bool   `toml:"remove_branch"`
-	ForceBranch    *bool   `toml:"force_branch"`
-	MergeMode      *string `toml:"merge_mode"`
-	Confirm        *bool   `toml:"confirm"`
+	Cleanup        *bool              `toml:"cleanup"`
+	RemoveWorktree *bool              `toml:"remove_worktree"`
+	RemoveBranch   *bool              `toml:"remove_branch"`
+	ForceBranch    *bool              `toml:"force_branch"`
+	MergeMode      *string            `toml:"merge_mode"`
+	Confirm        *bool              `toml:"confirm"`
+	Checks         *[]finishCheckFile `toml:"checks"`
+	Policy         finishPolicyFile   `toml:"policy"`
+}
+
+type finishPolicyFile struct {
+	Protected       *[]string           `toml:"protected"`
+	MergeModes      map[string][]string `toml:"merge_modes"`
+	RequireUpToDate *bool               `toml:"require_up_to_date"`
+	RequireClean    *bool               `toml:"require_clean"`
+}
+
+type finishCheckFile struct {
+	Name    string            `toml:"name"`
+	Run     string            `toml:"run"`
+	Dir     string            `toml:"dir"`
+	Timeout string            `toml:"timeout"`
+	Env     map[string]string `toml:"env"`
 }
 
 type cleanupConfigFile struct {
@@ -213,19 +305,47 @@ type cleanupConfigFile struct {
 	Confirm        *bool `toml:"confirm"`
 }
 
+type backupConfigFile struct {
+	Enabled    *bool `toml:"enabled"`
+	Keep       *int  `toml:"keep"`
+	MaxAgeDays *int  `toml:"max_age_days"`
+}
+
+type registryConfigFile struct {
+	Enabled  *bool     `toml:"enabled"`
+	Roots    *[]string `toml:"roots"`
+	MaxDepth *int      `toml:"max_depth"`
+}
+
+type presetConfigFile struct {
+	Base         *string        `toml:"base"`
+	BranchPrefix *string        `toml:"branch_prefix"`
+	Path         createPathFile `toml:"path"`
+	MergeMode    *string        `toml:"merge_mode"`
+	ForceBranch  *bool          `toml:"force_branch"`
+	PostCreate   *[]string      `toml:"post_create"`
+	Copy         *[]string      `toml:"copy"`
+}
+
 type gridFlags struct {
 	listSet   bool
 	statusSet bool
 }
 
 func Load() (Config, error) {
+	return LoadAt("")
+}
+
+// LoadAt is Load with the project config resolved from dir instead of the
+// current directory. An empty dir means the current directory.
+func LoadAt(dir string) (Config, error) {
 	cfg := DefaultConfig()
 	flags := gridFlags{}
 
 	if err := applyUserConfig(&cfg, &flags); err != nil {
 		return cfg, err
 	}
-	if err := applyProjectConfig(&cfg, &flags); err != nil {
+	if err := applyProjectConfig(&cfg, &flags, dir); err != nil {
 		return cfg, err
 	}
 	if err := applyEnvConfig(&cfg); err != nil {
@@ -258,8 +378,8 @@ func applyUserConfig(cfg *Config, flags *gridFlags) error {
 	return nil
 }
 
-func applyProjectConfig(cfg *Config, flags *gridFlags) error {
-	root, err := projectConfigRoot()
+func applyProjectConfig(cfg *Config, flags *gridFlags, dir string) error {
+	root, err := projectConfigRootAt(dir)
 	if err != nil {
 		return err
 	}
@@ -376,6 +496,12 @@ func applyConfig(cfg *Config, flags *gridFlags, file loadedConfigFile) {
 	if format, ok := trimString(file.Create.Path.Format); ok {
 		cfg.Create.Path.Format = format
 	}
+	if file.Create.RecurseSubmodules != nil {
+		cfg.Create.RecurseSubmodules = *file.Create.RecurseSubmodules
+	}
+	if file.Create.Sparse.Dirs != nil {
+		cfg.Create.Sparse.Dirs = *file.Create.Sparse.Dirs
+	}
 	if output, ok := trimString(file.List.Output); ok {
 		cfg.List.Output = output
 	}
@@ -423,6 +549,33 @@ func applyConfig(cfg *Config, flags *gridFlags, file loadedConfigFile) {
 	if file.Finish.Confirm != nil {
 		cfg.Finish.Confirm = *file.Finish.Confirm
 	}
+	if file.Finish.Checks != nil {
+		cfg.Finish.Checks = make([]FinishCheckConfig, 0, len(*file.Finish.Checks))
+		for _, check := range *file.Finish.Checks {
+			cfg.Finish.Checks = append(cfg.Finish.Checks, FinishCheckConfig{
+				Name:    strings.TrimSpace(check.Name),
+				Run:     check.Run,
+				Dir:     strings.TrimSpace(check.Dir),
+				Timeout: strings.TrimSpace(check.Timeout),
+				Env:     check.Env,
+			})
+		}
+	}
+	if file.Finish.Policy.Protected != nil {
+		cfg.Finish.Policy.Protected = *file.Finish.Policy.Protected
+	}
+	for target, modes := range file.Finish.Policy.MergeModes {
+		if cfg.Finish.Policy.MergeModes == nil {
+			cfg.Finish.Policy.MergeModes = map[string][]string{}
+		}
+		cfg.Finish.Policy.MergeModes[target] = modes
+	}
+	if file.Finish.Policy.RequireUpToDate != nil {
+		cfg.Finish.Policy.RequireUpToDate = *file.Finish.Policy.RequireUpToDate
+	}
+	if file.Finish.Policy.RequireClean != nil {
+		cfg.Finish.Policy.RequireClean = *file.Finish.Policy.RequireClean
+	}
 	if file.Cleanup.RemoveWorktree != nil {
 		cfg.Cleanup.RemoveWorktree = *file.Cleanup.RemoveWorktree
 	}
@@ -438,6 +591,58 @@ func applyConfig(cfg *Config, flags *gridFlags, file loadedConfigFile) {
 	if file.Cleanup.Confirm != nil {
 		cfg.Cleanup.Confirm = *file.Cleanup.Confirm
 	}
+	if file.Backup.Enabled != nil {
+		cfg.Backup.Enabled = *file.Backup.Enabled
+	}
+	if file.Backup.Keep != nil {
+		cfg.Backup.Keep = *file.Backup.Keep
+	}
+	if file.Backup.MaxAgeDays != nil {
+		cfg.Backup.MaxAgeDays = *file.Backup.MaxAgeDays
+	}
+	if file.Registry.Enabled != nil {
+		cfg.Registry.Enabled = *file.Registry.Enabled
+	}
+	if file.Registry.Roots != nil {
+		cfg.Registry.Roots = *file.Registry.Roots
+	}
+	if file.Registry.MaxDepth != nil {
+		cfg.Registry.MaxDepth = *file.Registry.MaxDepth
+	}
+	for name, presetFile := range file.Presets {
+		if cfg.Presets == nil {
+			cfg.Presets = map[string]PresetConfig{}
+		}
+		cfg.Presets[name] = applyPreset(cfg.Presets[name], presetFile)
+	}
+}
+
+func applyPreset(preset PresetConfig, file presetConfigFile) PresetConfig {
+	if base, ok := trimString(file.Base); ok {
+		preset.Base = base
+	}
+	if prefix, ok := trimString(file.BranchPrefix); ok {
+		preset.BranchPrefix = prefix
+	}
+	if root, ok := trimString(file.Path.Root); ok {
+		preset.Path.Root = root
+	}
+	if format, ok := trimString(file.Path.Format); ok {
+		preset.Path.Format = format
+	}
+	if mode, ok := trimString(file.MergeMode); ok {
+		preset.MergeMode = mode
+	}
+	if file.ForceBranch != nil {
+		preset.ForceBranch = *file.ForceBranch
+	}
+	if file.PostCreate != nil {
+		preset.PostCreate = *file.PostCreate
+	}
+	if file.Copy != nil {
+		preset.Copy = *file.Copy
+	}
+	return preset
 }
 
 func trimString(value *string) (string, bool) {
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -216,3 +217,180 @@ func TestLoadEnvDryRunMaskSensitivePathsInvalid(t *testing.T) {
 		t.Fatalf("Load() expected error")
 	}
 }
+
+func TestLoadConfigBackup(t *testing.T) {
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, projectConfigPrimary), `
+[backup]
+enabled = false
+keep = 5
+max_age_days = 7
+`)
+
+	restore := chdir(t, project)
+	defer restore()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Backup.Enabled || cfg.Backup.Keep != 5 || cfg.Backup.MaxAgeDays != 7 {
+		t.Fatalf("Backup = %+v, want {Enabled:false Keep:5 MaxAgeDays:7}", cfg.Backup)
+	}
+}
+
+func TestLoadConfigRegistry(t *testing.T) {
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, projectConfigPrimary), `
+[registry]
+enabled = false
+roots = ["~/src", "/work"]
+max_depth = 2
+`)
+
+	restore := chdir(t, project)
+	defer restore()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Registry.Enabled || cfg.Registry.MaxDepth != 2 || len(cfg.Registry.Roots) != 2 || cfg.Registry.Roots[1] != "/work" {
+		t.Fatalf("Registry = %+v", cfg.Registry)
+	}
+}
+
+func TestLoadConfigCreateSparse(t *testing.T) {
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, projectConfigPrimary), `
+[create.sparse]
+dirs = ["services/api", "libs/shared"]
+`)
+
+	restore := chdir(t, project)
+	defer restore()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Create.Sparse.Dirs) != 2 || cfg.Create.Sparse.Dirs[0] != "services/api" {
+		t.Fatalf("Create.Sparse = %+v", cfg.Create.Sparse)
+	}
+}
+
+func TestLoadConfigFinishChecks(t *testing.T) {
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, projectConfigPrimary), `
+[[finish.checks]]
+name = "build"
+run = "go build ./..."
+
+[[finish.checks]]
+name = " web tests "
+run = "npm test"
+dir = "web"
+timeout = "10m"
+env = { CI = "1" }
+`)
+
+	restore := chdir(t, project)
+	defer restore()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	checks := cfg.Finish.Checks
+	if len(checks) != 2 || checks[0].Name != "build" || checks[0].Run != "go build ./..." {
+		t.Fatalf("Finish.Checks = %+v", checks)
+	}
+	if checks[1].Name != "web tests" || checks[1].Dir != "web" || checks[1].Timeout != "10m" || checks[1].Env["CI"] != "1" {
+		t.Fatalf("Finish.Checks[1] = %+v", checks[1])
+	}
+}
+
+func TestLoadConfigFinishPolicy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	userConfigPath := filepath.Join(home, userConfigRelativePath)
+	if err := os.MkdirAll(filepath.Dir(userConfigPath), 0o755); err != nil {
+		t.Fatalf("MkdirAll error = %v", err)
+	}
+	writeFile(t, userConfigPath, `
+[finish.policy]
+protected = ["release/*"]
+require_clean = true
+
+[finish.policy.merge_modes]
+main = ["no-ff"]
+`)
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, projectConfigPrimary), `
+[finish.policy]
+require_up_to_date = true
+
+[finish.policy.merge_modes]
+main = ["squash", "rebase"]
+"hotfix/*" = ["ff"]
+`)
+
+	restore := chdir(t, project)
+	defer restore()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	policy := cfg.Finish.Policy
+	if len(policy.Protected) != 1 || policy.Protected[0] != "release/*" || !policy.RequireClean || !policy.RequireUpToDate {
+		t.Fatalf("Finish.Policy = %+v", policy)
+	}
+	if strings.Join(policy.MergeModes["main"], ",") != "squash,rebase" || strings.Join(policy.MergeModes["hotfix/*"], ",") != "ff" {
+		t.Fatalf("Finish.Policy.MergeModes = %+v", policy.MergeModes)
+	}
+}
+
+func TestLoadConfigPresets(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	userConfigPath := filepath.Join(home, userConfigRelativePath)
+	if err := os.MkdirAll(filepath.Dir(userConfigPath), 0o755); err != nil {
+		t.Fatalf("MkdirAll error = %v", err)
+	}
+	writeFile(t, userConfigPath, `
+[presets.hotfix]
+base = "release/current"
+merge_mode = "squash"
+`)
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, projectConfigPrimary), `
+[presets.hotfix]
+branch_prefix = "hotfix/"
+merge_mode = "no-ff"
+copy = [".env"]
+
+[presets.spike]
+force_branch = true
+post_create = ["make setup"]
+
+[presets.spike.path]
+root = "/tmp"
+`)
+
+	restore := chdir(t, project)
+	defer restore()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	hotfix := cfg.Presets["hotfix"]
+	if hotfix.Base != "release/current" || hotfix.BranchPrefix != "hotfix/" || hotfix.MergeMode != "no-ff" || len(hotfix.Copy) != 1 {
+		t.Fatalf("Presets[hotfix] = %+v", hotfix)
+	}
+	spike := cfg.Presets["spike"]
+	if !spike.ForceBranch || spike.Path.Root != "/tmp" || spike.Path.Format != "" || len(spike.PostCreate) != 1 {
+		t.Fatalf("Presets[spike] = %+v", spike)
+	}
+}
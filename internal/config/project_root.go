@@ -10,18 +10,27 @@ import (
 // It walks up from cwd looking for a .git marker (directory or file for worktrees/submodules).
 // Falls back to cwd if no .git is found.
 func projectConfigRoot() (string, error) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("get working directory: %w", err)
+	return projectConfigRootAt("")
+}
+
+// projectConfigRootAt is projectConfigRoot starting from dir; an empty dir
+// means cwd.
+func projectConfigRootAt(dir string) (string, error) {
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("get working directory: %w", err)
+		}
+		dir = cwd
 	}
-	root, ok, err := findRepoRoot(cwd)
+	root, ok, err := findRepoRoot(dir)
 	if err != nil {
 		return "", err
 	}
 	if ok {
 		return root, nil
 	}
-	return cwd, nil
+	return dir, nil
 }
 
 // findRepoRoot walks up from start looking for a .git marker.
@@ -16,3 +16,26 @@ func BranchExists(ctx context.Context, runner Runner, repoRoot, branch string) (
 	}
 	return strings.TrimSpace(stdout) != "", nil
 }
+
+// BranchConfigValues returns the value of branch.<name>.<key> for every
+// branch that sets key, by branch name.
+func BranchConfigValues(ctx context.Context, runner Runner, repoRoot, key string) (map[string]string, error) {
+	suffix := "." + strings.ToLower(key)
+	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "config", "--get-regexp", `^branch\..*\`+suffix+`$`)
+	if err != nil {
+		// git config exits 1 without output when nothing matches.
+		if strings.TrimSpace(stdout) == "" && strings.TrimSpace(stderr) == "" {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("branch config: %w: %s", err, stderr)
+	}
+	values := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		name, value, _ := strings.Cut(line, " ")
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "branch."), suffix)
+		if name != "" {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
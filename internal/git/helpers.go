@@ -94,3 +94,27 @@ func classifyGitStderr(stderr string) error {
 	}
 	return nil
 }
+
+// CommonDirAt returns the absolute git common directory for the repository at path.
+func CommonDirAt(ctx context.Context, runner Runner, path string) (string, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", path, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		if classified := classifyGitStderr(stderr); classified != nil {
+			return "", fmt.Errorf("git common dir: %w", classified)
+		}
+		return "", fmt.Errorf("git common dir: %w: %s", err, stderr)
+	}
+	return filepath.Clean(strings.TrimSpace(stdout)), nil
+}
+
+// RevParse resolves rev to a full object name in the repository at repoRoot.
+func RevParse(ctx context.Context, runner Runner, repoRoot, rev string) (string, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "rev-parse", "--verify", rev)
+	if err != nil {
+		if classified := classifyGitStderr(stderr); classified != nil {
+			return "", fmt.Errorf("rev-parse %s: %w", rev, classified)
+		}
+		return "", fmt.Errorf("rev-parse %s: %w: %s", rev, err, stderr)
+	}
+	return strings.TrimSpace(stdout), nil
+}
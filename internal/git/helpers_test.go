@@ -40,3 +40,49 @@ func TestRepoBaseName(t *testing.T) {
 		t.Fatalf("RepoBaseName() = %q, want %q", got, "example")
 	}
 }
+
+func TestBranchConfigValues(t *testing.T) {
+	runner := fakeRunner{
+		responses: map[string]fakeResponse{
+			`-C /repo config --get-regexp ^branch\..*\.gwtttask$`:  {stdout: "branch.hotfix/login.gwtttask login\nbranch.spike.gwtttask spike\n"},
+			`-C /empty config --get-regexp ^branch\..*\.gwtttask$`: {err: fmt.Errorf("exit status 1")},
+		},
+	}
+	got, err := BranchConfigValues(context.Background(), runner, "/repo", "gwttTask")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got["hotfix/login"] != "login" || got["spike"] != "spike" {
+		t.Fatalf("BranchConfigValues() = %v", got)
+	}
+	got, err = BranchConfigValues(context.Background(), runner, "/empty", "gwttTask")
+	if err != nil || len(got) != 0 {
+		t.Fatalf("BranchConfigValues() = %v, %v; want empty", got, err)
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	tree := strings.Repeat("a", 40)
+	args := "-C /repo merge-tree --write-tree --name-only --no-messages -z main task"
+	runner := fakeRunner{responses: map[string]fakeResponse{
+		args: {stdout: tree + "\x00README.md\x00docs/a b.md\x00README.md\x00", err: fmt.Errorf("exit status 1")},
+	}}
+	got, err := MergeConflicts(context.Background(), runner, "/repo", "main", "task")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "README.md" || got[1] != "docs/a b.md" {
+		t.Fatalf("MergeConflicts() = %q", got)
+	}
+
+	runner = fakeRunner{responses: map[string]fakeResponse{args: {stdout: tree}}}
+	got, err = MergeConflicts(context.Background(), runner, "/repo", "main", "task")
+	if err != nil || len(got) != 0 {
+		t.Fatalf("MergeConflicts() = %q, %v; want no conflicts", got, err)
+	}
+
+	runner = fakeRunner{responses: map[string]fakeResponse{args: {stderr: "fatal: not a valid object name task", err: fmt.Errorf("exit status 128")}}}
+	if _, err := MergeConflicts(context.Background(), runner, "/repo", "main", "task"); err == nil {
+		t.Fatalf("expected error for an unknown branch")
+	}
+}
@@ -0,0 +1,47 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MergeConflicts test-merges theirs into ours with `git merge-tree
+// --write-tree`, which touches neither the index nor any working tree, and
+// returns the paths that would conflict. It needs git 2.38 or later.
+func MergeConflicts(ctx context.Context, runner Runner, repoRoot, ours, theirs string) ([]string, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", repoRoot, "merge-tree", "--write-tree", "--name-only", "--no-messages", "-z", ours, theirs)
+	fields := strings.Split(stdout, "\x00")
+	if err != nil && !isObjectName(fields[0]) {
+		if strings.Contains(stderr, "usage:") || strings.Contains(stderr, "unknown option") {
+			return nil, fmt.Errorf("merge-tree %s %s: git 2.38 or later is required", ours, theirs)
+		}
+		return nil, fmt.Errorf("merge-tree %s %s: %w: %s", ours, theirs, err, stderr)
+	}
+	if err == nil {
+		return nil, nil
+	}
+	// A conflicted merge exits 1 and lists the conflicting paths after the
+	// tree, once per path.
+	var conflicts []string
+	seen := map[string]bool{}
+	for _, path := range fields[1:] {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			conflicts = append(conflicts, path)
+		}
+	}
+	return conflicts, nil
+}
+
+func isObjectName(value string) bool {
+	if len(value) != 40 && len(value) != 64 {
+		return false
+	}
+	for _, r := range value {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -13,11 +13,15 @@ type Runner interface {
 	Run(ctx context.Context, args ...string) (stdout string, stderr string, err error)
 }
 
-// ExecRunner executes git commands using os/exec.
-type ExecRunner struct{}
+// ExecRunner executes git commands using os/exec. Dir is the working
+// directory of the commands; empty means the current directory.
+type ExecRunner struct {
+	Dir string
+}
 
-func (ExecRunner) Run(ctx context.Context, args ...string) (string, string, error) {
+func (r ExecRunner) Run(ctx context.Context, args ...string) (string, string, error) {
 	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.Dir
 	var outBuf, errBuf bytes.Buffer
 	cmd.Stdout = &outBuf
 	cmd.Stderr = &errBuf
@@ -0,0 +1,177 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	stateDir = "gwtt"
+	fileName = "repos.json"
+
+	// touchInterval limits how often an already known repo is rewritten, so
+	// ordinary commands rarely touch the state file.
+	touchInterval = time.Hour
+)
+
+// Entry is one repository gwtt has been used in, keyed by its main checkout.
+type Entry struct {
+	Path     string    `json:"path"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Registry is the user-level list of known repositories.
+type Registry struct {
+	Repos []Entry `json:"repos"`
+}
+
+// DefaultPath returns $XDG_STATE_HOME/gwtt/repos.json, falling back to
+// ~/.local/state when XDG_STATE_HOME is unset.
+func DefaultPath() (string, error) {
+	if dir := strings.TrimSpace(os.Getenv("XDG_STATE_HOME")); dir != "" {
+		return filepath.Join(dir, stateDir, fileName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", stateDir, fileName), nil
+}
+
+// Load reads the registry at path; a missing file is an empty registry.
+func Load(path string) (Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Registry{}, nil
+		}
+		return Registry{}, err
+	}
+	var r Registry
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Registry{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Save writes the registry atomically.
+func Save(path string, r Registry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	sort.Slice(r.Repos, func(i, j int) bool { return r.Repos[i].Path < r.Repos[j].Path })
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), fileName+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Touch records a use of the repo at path and reports whether the registry
+// changed enough to be worth saving.
+func (r *Registry) Touch(path string, now time.Time) bool {
+	path = filepath.Clean(path)
+	for idx := range r.Repos {
+		if r.Repos[idx].Path != path {
+			continue
+		}
+		if now.Sub(r.Repos[idx].LastUsed) < touchInterval {
+			return false
+		}
+		r.Repos[idx].LastUsed = now
+		return true
+	}
+	r.Repos = append(r.Repos, Entry{Path: path, LastUsed: now})
+	return true
+}
+
+// Prune drops repos that are no longer git checkouts and returns them.
+func (r *Registry) Prune() []Entry {
+	var kept, removed []Entry
+	for _, entry := range r.Repos {
+		if IsRepo(entry.Path) {
+			kept = append(kept, entry)
+		} else {
+			removed = append(removed, entry)
+		}
+	}
+	r.Repos = kept
+	return removed
+}
+
+// IsRepo reports whether path is the main checkout of a git repository.
+func IsRepo(path string) bool {
+	info, err := os.Stat(filepath.Join(path, ".git"))
+	return err == nil && info.IsDir()
+}
+
+// Discover walks roots up to maxDepth levels deep and returns the main
+// checkouts found. It does not descend into repositories, and skips linked
+// worktrees since their main checkout is what gets listed.
+func Discover(roots []string, maxDepth int) ([]string, error) {
+	home, _ := os.UserHomeDir()
+	var found []string
+	for _, root := range roots {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			continue
+		}
+		if home != "" && (root == "~" || strings.HasPrefix(root, "~/")) {
+			root = filepath.Join(home, strings.TrimPrefix(root, "~"))
+		}
+		repos, err := discoverIn(filepath.Clean(root), maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, repos...)
+	}
+	return found, nil
+}
+
+func discoverIn(dir string, depth int) ([]string, error) {
+	if IsRepo(dir) {
+		return []string{dir}, nil
+	}
+	if depth <= 0 {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var found []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		repos, err := discoverIn(filepath.Join(dir, entry.Name()), depth-1)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, repos...)
+	}
+	return found, nil
+}
@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func makeRepo(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(path, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTouchSaveLoadAndPrune(t *testing.T) {
+	root := t.TempDir()
+	alive := filepath.Join(root, "alive")
+	gone := filepath.Join(root, "gone")
+	makeRepo(t, alive)
+
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	var r Registry
+	if !r.Touch(alive, now) || !r.Touch(gone, now) {
+		t.Fatalf("Touch() of new repos should report a change")
+	}
+	if r.Touch(alive, now.Add(time.Minute)) {
+		t.Fatalf("Touch() within the interval should not report a change")
+	}
+	if !r.Touch(alive, now.Add(2*time.Hour)) {
+		t.Fatalf("Touch() after the interval should report a change")
+	}
+
+	path := filepath.Join(root, "state", "repos.json")
+	if err := Save(path, r); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	removed := loaded.Prune()
+	if len(removed) != 1 || removed[0].Path != gone {
+		t.Fatalf("Prune() removed %+v, want %s", removed, gone)
+	}
+	if len(loaded.Repos) != 1 || loaded.Repos[0].Path != alive {
+		t.Fatalf("Prune() kept %+v", loaded.Repos)
+	}
+}
+
+func TestDiscoverStopsAtRepositories(t *testing.T) {
+	root := t.TempDir()
+	makeRepo(t, filepath.Join(root, "a"))
+	makeRepo(t, filepath.Join(root, "a", "vendor", "nested"))
+	makeRepo(t, filepath.Join(root, "group", "b"))
+	makeRepo(t, filepath.Join(root, "x", "y", "z", "too-deep"))
+
+	found, err := Discover([]string{root, filepath.Join(root, "missing")}, 2)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	want := []string{filepath.Join(root, "a"), filepath.Join(root, "group", "b")}
+	if len(found) != len(want) {
+		t.Fatalf("Discover() = %v, want %v", found, want)
+	}
+	for i := range want {
+		if found[i] != want[i] {
+			t.Fatalf("Discover() = %v, want %v", found, want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// FileName is the workspace file looked up from the current directory
+// upwards.
+const FileName = "gwtt.workspace.toml"
+
+// Repo is one repository of a workspace.
+type Repo struct {
+	Name string
+	Path string
+}
+
+// Workspace lists the repositories that share task names.
+type Workspace struct {
+	File  string
+	Repos []Repo
+}
+
+type workspaceFile struct {
+	Repos []string `toml:"repos"`
+}
+
+// Find returns the nearest workspace file at or above start.
+func Find(start string) (string, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no %s found in %s or its parents", FileName, start)
+		}
+		dir = parent
+	}
+}
+
+// Load reads a workspace file. Repo paths are resolved against the file's
+// directory and named after their last path element, which must be unique.
+func Load(path string) (Workspace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Workspace{}, err
+	}
+	var file workspaceFile
+	if err := toml.Unmarshal(data, &file); err != nil {
+		return Workspace{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(file.Repos) == 0 {
+		return Workspace{}, fmt.Errorf("%s: repos is empty", path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Workspace{}, err
+	}
+	ws := Workspace{File: abs}
+	seen := map[string]string{}
+	for _, raw := range file.Repos {
+		repoPath := strings.TrimSpace(raw)
+		if repoPath == "" {
+			return Workspace{}, fmt.Errorf("%s: empty repo path", path)
+		}
+		if !filepath.IsAbs(repoPath) {
+			repoPath = filepath.Join(filepath.Dir(abs), repoPath)
+		}
+		repoPath = filepath.Clean(repoPath)
+		name := filepath.Base(repoPath)
+		if other, ok := seen[name]; ok {
+			return Workspace{}, fmt.Errorf("%s: repos %s and %s share the name %q", path, other, repoPath, name)
+		}
+		seen[name] = repoPath
+		ws.Repos = append(ws.Repos, Repo{Name: name, Path: repoPath})
+	}
+	return ws, nil
+}
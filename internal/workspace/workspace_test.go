@@ -0,0 +1,62 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadResolvesReposAgainstFile(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, FileName)
+	if err := os.WriteFile(path, []byte(`repos = ["backend", "../shared/frontend"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := []Repo{
+		{Name: "backend", Path: filepath.Join(root, "backend")},
+		{Name: "frontend", Path: filepath.Join(filepath.Dir(root), "shared", "frontend")},
+	}
+	if len(ws.Repos) != len(want) {
+		t.Fatalf("Load() repos = %+v", ws.Repos)
+	}
+	for i, repo := range ws.Repos {
+		if repo != want[i] {
+			t.Fatalf("repo %d = %+v, want %+v", i, repo, want[i])
+		}
+	}
+}
+
+func TestLoadRejectsDuplicateNames(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, FileName)
+	if err := os.WriteFile(path, []byte(`repos = ["a/app", "b/app"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatalf("Load() accepted two repos named app")
+	}
+}
+
+func TestFindWalksUp(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, FileName)
+	if err := os.WriteFile(path, []byte(`repos = ["a"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Find(nested)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got != path {
+		t.Fatalf("Find() = %q, want %q", got, path)
+	}
+}
@@ -24,6 +24,23 @@ func WorktreePath(repoRoot, repoName, task string) string {
 	return filepath.Join(parent, repoName+"_"+task)
 }
 
+// TemplatePath renders a [create.path] layout for task. root is resolved
+// against repoRoot unless absolute, and {repo} and {task} are substituted in
+// format. Empty values fall back to the default ../<repo>_<task> layout.
+func TemplatePath(repoRoot, root, format, repoName, task string) string {
+	if strings.TrimSpace(root) == "" {
+		root = "../"
+	}
+	if strings.TrimSpace(format) == "" {
+		format = "{repo}_{task}"
+	}
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(repoRoot, root)
+	}
+	name := strings.NewReplacer("{repo}", repoName, "{task}", task).Replace(format)
+	return filepath.Join(root, name)
+}
+
 // TaskFromPath derives the task name from a worktree path using the fixed naming convention.
 func TaskFromPath(repoName, path string) (string, bool) {
 	base := filepath.Base(path)
@@ -40,6 +40,27 @@ func TestWorktreePath(t *testing.T) {
 	}
 }
 
+func TestTemplatePath(t *testing.T) {
+	repoRoot := filepath.Join("/tmp", "work", "repo")
+	tests := []struct {
+		name   string
+		root   string
+		format string
+		want   string
+	}{
+		{name: "defaults", want: filepath.Join("/tmp", "work", "repo_task")},
+		{name: "relative root", root: "../trees", format: "{task}", want: filepath.Join("/tmp", "work", "trees", "task")},
+		{name: "absolute root", root: "/srv/wt", format: "{repo}-{task}", want: filepath.Join("/srv/wt", "repo-task")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TemplatePath(repoRoot, tt.root, tt.format, "repo", "task"); got != tt.want {
+				t.Fatalf("TemplatePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestTaskFromPath(t *testing.T) {
 	repoName := "repo"
 	path := filepath.Join("/tmp", "repo_task")
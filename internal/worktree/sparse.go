@@ -0,0 +1,75 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+)
+
+// SparseInfo describes the sparse-checkout of a worktree. In cone mode Dirs
+// are directories; otherwise they are raw sparse-checkout patterns.
+type SparseInfo struct {
+	Enabled bool
+	Cone    bool
+	Dirs    []string
+}
+
+// Sparse reads the sparse-checkout settings of the worktree at path.
+func Sparse(ctx context.Context, runner git.Runner, path string) (SparseInfo, error) {
+	var info SparseInfo
+	enabled, err := configBool(ctx, runner, path, "core.sparseCheckout")
+	if err != nil || !enabled {
+		return info, err
+	}
+	info.Enabled = true
+	info.Cone, err = configBool(ctx, runner, path, "core.sparseCheckoutCone")
+	if err != nil {
+		return info, err
+	}
+	stdout, stderr, err := runner.Run(ctx, "-C", path, "sparse-checkout", "list")
+	if err != nil {
+		return info, fmt.Errorf("sparse-checkout list: %w: %s", err, stderr)
+	}
+	for _, line := range strings.Split(stdout, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			info.Dirs = append(info.Dirs, line)
+		}
+	}
+	return info, nil
+}
+
+// Includes reports whether file (slash-separated, relative to the worktree
+// root) is checked out. Cone mode always includes top-level files and the
+// files directly inside each parent of a listed directory. Non-cone patterns
+// are not evaluated and count as included.
+func (s SparseInfo) Includes(file string) bool {
+	if !s.Enabled || !s.Cone {
+		return true
+	}
+	dir := path.Dir(file)
+	if dir == "." {
+		return true
+	}
+	for _, sparseDir := range s.Dirs {
+		sparseDir = strings.Trim(sparseDir, "/")
+		if strings.HasPrefix(file, sparseDir+"/") || strings.HasPrefix(sparseDir, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func configBool(ctx context.Context, runner git.Runner, path, key string) (bool, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", path, "config", "--type=bool", "--get", key)
+	if err != nil {
+		// git config exits 1 without output when the key is unset.
+		if strings.TrimSpace(stdout) == "" && strings.TrimSpace(stderr) == "" {
+			return false, nil
+		}
+		return false, fmt.Errorf("config %s: %w: %s", key, err, stderr)
+	}
+	return strings.TrimSpace(stdout) == "true", nil
+}
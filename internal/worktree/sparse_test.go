@@ -0,0 +1,55 @@
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSparseIncludes(t *testing.T) {
+	cone := SparseInfo{Enabled: true, Cone: true, Dirs: []string{"services/api", "docs"}}
+	tests := []struct {
+		name   string
+		sparse SparseInfo
+		file   string
+		want   bool
+	}{
+		{name: "top-level file", sparse: cone, file: "go.mod", want: true},
+		{name: "inside listed dir", sparse: cone, file: "services/api/handler/main.go", want: true},
+		{name: "parent dir file", sparse: cone, file: "services/README.md", want: true},
+		{name: "sibling dir", sparse: cone, file: "services/web/index.js", want: false},
+		{name: "unlisted dir", sparse: cone, file: "tools/build.sh", want: false},
+		{name: "prefix is not a parent", sparse: cone, file: "docsite/index.md", want: false},
+		{name: "not sparse", sparse: SparseInfo{}, file: "tools/build.sh", want: true},
+		{name: "non-cone patterns", sparse: SparseInfo{Enabled: true, Dirs: []string{"/docs/"}}, file: "tools/build.sh", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sparse.Includes(tt.file); got != tt.want {
+				t.Fatalf("Includes(%q) = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSparseReadsConeDirs(t *testing.T) {
+	runner := fakeRunner{
+		responses: map[string]fakeResponse{
+			"-C /wt config --type=bool --get core.sparseCheckout":     {stdout: "true\n"},
+			"-C /wt config --type=bool --get core.sparseCheckoutCone": {stdout: "true\n"},
+			"-C /wt sparse-checkout list":                             {stdout: "docs\nservices/api\n"},
+			"-C /full config --type=bool --get core.sparseCheckout":   {err: fmt.Errorf("exit status 1")},
+		},
+	}
+	info, err := Sparse(context.Background(), runner, "/wt")
+	if err != nil {
+		t.Fatalf("Sparse() error = %v", err)
+	}
+	if !info.Enabled || !info.Cone || len(info.Dirs) != 2 || info.Dirs[1] != "services/api" {
+		t.Fatalf("Sparse() = %+v", info)
+	}
+	info, err = Sparse(context.Background(), runner, "/full")
+	if err != nil || info.Enabled {
+		t.Fatalf("Sparse() = %+v, %v; want disabled", info, err)
+	}
+}
@@ -17,9 +17,24 @@ type StatusInfo struct {
 	Ahead      int
 	Behind     int
 	Base       string
+	Sparse     SparseInfo
+	Submodules []SubmoduleState
+}
+
+// StatusOptions turns on the optional probes of StatusWith. Each costs extra
+// git processes per worktree, so only callers that show the result ask.
+type StatusOptions struct {
+	Sparse     bool
+	Submodules bool
 }
 
 func Status(ctx context.Context, runner git.Runner, path string, target string) (StatusInfo, error) {
+	return StatusWith(ctx, runner, path, target, StatusOptions{})
+}
+
+// StatusWith is Status plus the sparse-checkout and submodule probes
+// selected in opts.
+func StatusWith(ctx context.Context, runner git.Runner, path string, target string, opts StatusOptions) (StatusInfo, error) {
 	var info StatusInfo
 
 	ok, err := isWorktreePath(path)
@@ -45,42 +60,9 @@ func Status(ctx context.Context, runner git.Runner, path string, target string)
 	}
 
 	if hasHead {
-		shortHashLen, err := ShortHashLength(ctx, runner, path)
-		if err != nil {
+		if err := commitStatus(ctx, runner, path, "HEAD", target, &info); err != nil {
 			return info, err
 		}
-
-		stdout, stderr, err = runner.Run(ctx, "-C", path, "log", "-1", "--pretty=format:%H %s")
-		if err != nil {
-			return info, fmt.Errorf("status last commit: %w: %s", err, stderr)
-		}
-		info.LastCommit = formatCommitLine(stdout, shortHashLen)
-
-		if target != "" {
-			stdout, _, err = runner.Run(ctx, "-C", path, "merge-base", "HEAD", target)
-			if err == nil {
-				info.Base = ShortHash(strings.TrimSpace(stdout), shortHashLen)
-			}
-
-			stdout, stderr, err = runner.Run(ctx, "-C", path, "rev-list", "--left-right", "--count", target+"...HEAD")
-			if err != nil {
-				return info, fmt.Errorf("status ahead/behind: %w: %s", err, stderr)
-			}
-			parts := strings.Fields(stdout)
-			if len(parts) < 2 {
-				return info, fmt.Errorf("status ahead/behind parse: expected 2 fields, got %d (%q)", len(parts), strings.TrimSpace(stdout))
-			}
-			behind, err := strconv.Atoi(parts[0])
-			if err != nil {
-				return info, fmt.Errorf("status ahead/behind parse: behind %q: %w", parts[0], err)
-			}
-			ahead, err := strconv.Atoi(parts[1])
-			if err != nil {
-				return info, fmt.Errorf("status ahead/behind parse: ahead %q: %w", parts[1], err)
-			}
-			info.Behind = behind
-			info.Ahead = ahead
-		}
 	} else {
 		info.LastCommit = "empty history"
 		if target != "" {
@@ -88,9 +70,72 @@ func Status(ctx context.Context, runner git.Runner, path string, target string)
 		}
 	}
 
+	if opts.Sparse {
+		info.Sparse, err = Sparse(ctx, runner, path)
+		if err != nil {
+			return info, err
+		}
+	}
+	if opts.Submodules {
+		info.Submodules, err = Submodules(ctx, runner, path)
+		if err != nil {
+			return info, err
+		}
+	}
 	return info, nil
 }
 
+// BranchStatus reports the last commit, merge base and ahead/behind counts of
+// branch against target without a worktree; Dirty is always false.
+func BranchStatus(ctx context.Context, runner git.Runner, repoRoot, branch, target string) (StatusInfo, error) {
+	var info StatusInfo
+	if err := commitStatus(ctx, runner, repoRoot, "refs/heads/"+branch, target, &info); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// commitStatus fills in the commit fields of info for rev, run from dir.
+func commitStatus(ctx context.Context, runner git.Runner, dir, rev, target string, info *StatusInfo) error {
+	shortHashLen, err := ShortHashLength(ctx, runner, dir)
+	if err != nil {
+		return err
+	}
+
+	stdout, stderr, err := runner.Run(ctx, "-C", dir, "log", "-1", "--pretty=format:%H %s", rev)
+	if err != nil {
+		return fmt.Errorf("status last commit: %w: %s", err, stderr)
+	}
+	info.LastCommit = formatCommitLine(stdout, shortHashLen)
+
+	if target != "" {
+		stdout, _, err = runner.Run(ctx, "-C", dir, "merge-base", rev, target)
+		if err == nil {
+			info.Base = ShortHash(strings.TrimSpace(stdout), shortHashLen)
+		}
+
+		stdout, stderr, err = runner.Run(ctx, "-C", dir, "rev-list", "--left-right", "--count", target+"..."+rev)
+		if err != nil {
+			return fmt.Errorf("status ahead/behind: %w: %s", err, stderr)
+		}
+		parts := strings.Fields(stdout)
+		if len(parts) < 2 {
+			return fmt.Errorf("status ahead/behind parse: expected 2 fields, got %d (%q)", len(parts), strings.TrimSpace(stdout))
+		}
+		behind, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return fmt.Errorf("status ahead/behind parse: behind %q: %w", parts[0], err)
+		}
+		ahead, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return fmt.Errorf("status ahead/behind parse: ahead %q: %w", parts[1], err)
+		}
+		info.Behind = behind
+		info.Ahead = ahead
+	}
+	return nil
+}
+
 func isWorktreePath(path string) (bool, error) {
 	info, err := os.Stat(path)
 	if err != nil {
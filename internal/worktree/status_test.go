@@ -40,7 +40,7 @@ func TestStatusAheadBehindParseError(t *testing.T) {
 			"-C " + worktreePath + " status --porcelain":                        {stdout: ""},
 			"-C " + worktreePath + " rev-parse --verify HEAD":                   {},
 			"-C " + worktreePath + " rev-parse --short HEAD":                    {stdout: "abc1234"},
-			"-C " + worktreePath + " log -1 --pretty=format:%H %s":              {stdout: "abcdef1234567890 message"},
+			"-C " + worktreePath + " log -1 --pretty=format:%H %s HEAD":         {stdout: "abcdef1234567890 message"},
 			"-C " + worktreePath + " merge-base HEAD main":                      {stdout: "abcdef1234567890"},
 			"-C " + worktreePath + " rev-list --left-right --count main...HEAD": {stdout: "x 1"},
 		},
@@ -54,3 +54,43 @@ func TestStatusAheadBehindParseError(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestStatusSkipsOptionalProbes(t *testing.T) {
+	root := t.TempDir()
+	worktreePath := filepath.Join(root, "wt")
+	if err := os.MkdirAll(filepath.Join(worktreePath, ".git"), 0o755); err != nil {
+		t.Fatalf("setup worktree: %v", err)
+	}
+
+	// The runner fails on any command it does not know, so sparse or
+	// submodule probes would surface as an error.
+	runner := fakeRunner{
+		responses: map[string]fakeResponse{
+			"-C " + worktreePath + " status --porcelain":                        {stdout: " M a.txt"},
+			"-C " + worktreePath + " rev-parse --verify HEAD":                   {},
+			"-C " + worktreePath + " rev-parse --short HEAD":                    {stdout: "abc1234"},
+			"-C " + worktreePath + " log -1 --pretty=format:%H %s HEAD":         {stdout: "abcdef1234567890 message"},
+			"-C " + worktreePath + " merge-base HEAD main":                      {stdout: "abcdef1234567890"},
+			"-C " + worktreePath + " rev-list --left-right --count main...HEAD": {stdout: "0 2"},
+		},
+	}
+
+	info, err := Status(context.Background(), runner, worktreePath, "main")
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if !info.Dirty || info.Ahead != 2 || info.Sparse.Enabled || info.Submodules != nil {
+		t.Fatalf("unexpected status: %+v", info)
+	}
+
+	runner.responses["-C "+worktreePath+" config --type=bool --get core.sparseCheckout"] = fakeResponse{stdout: "true"}
+	runner.responses["-C "+worktreePath+" config --type=bool --get core.sparseCheckoutCone"] = fakeResponse{stdout: "true"}
+	runner.responses["-C "+worktreePath+" sparse-checkout list"] = fakeResponse{stdout: "services/api"}
+	info, err = StatusWith(context.Background(), runner, worktreePath, "main", StatusOptions{Sparse: true})
+	if err != nil {
+		t.Fatalf("StatusWith() error = %v", err)
+	}
+	if !info.Sparse.Cone || len(info.Sparse.Dirs) != 1 || info.Sparse.Dirs[0] != "services/api" {
+		t.Fatalf("expected the sparse probe to run, got %+v", info.Sparse)
+	}
+}
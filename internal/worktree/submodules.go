@@ -0,0 +1,135 @@
+package worktree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+)
+
+// SubmoduleState is the state of one submodule of a worktree. Path is
+// relative to the worktree root; nested submodules are listed with their full
+// path.
+type SubmoduleState struct {
+	Path          string
+	Uninitialized bool
+	OutOfDate     bool
+	Dirty         bool
+	Conflict      bool
+}
+
+// Initialized reports whether the submodule has been checked out.
+func (s SubmoduleState) Initialized() bool {
+	return !s.Uninitialized
+}
+
+// Summary lists the states that need attention, e.g. "dirty, out-of-date".
+// It is empty for a clean, up-to-date submodule.
+func (s SubmoduleState) Summary() string {
+	var states []string
+	if s.Conflict {
+		states = append(states, "conflict")
+	}
+	if s.Uninitialized {
+		states = append(states, "uninitialized")
+	}
+	if s.Dirty {
+		states = append(states, "dirty")
+	}
+	if s.OutOfDate {
+		states = append(states, "out-of-date")
+	}
+	return strings.Join(states, ", ")
+}
+
+// Submodules lists the submodules of the worktree at path. Worktrees without
+// a .gitmodules file are not inspected further.
+func Submodules(ctx context.Context, runner git.Runner, path string) ([]SubmoduleState, error) {
+	if _, err := os.Stat(filepath.Join(path, ".gitmodules")); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("stat .gitmodules: %w", err)
+	}
+	stdout, stderr, err := runner.Run(ctx, "-C", path, "submodule", "status", "--recursive")
+	if err != nil {
+		return nil, fmt.Errorf("submodule status: %w: %s", err, stderr)
+	}
+	states := map[string]*SubmoduleState{}
+	var order []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		_, rest, ok := strings.Cut(line[1:], " ")
+		if !ok {
+			continue
+		}
+		// The path is followed by " (<describe>)" for checked-out submodules.
+		if idx := strings.LastIndex(rest, " ("); idx > 0 && strings.HasSuffix(rest, ")") {
+			rest = rest[:idx]
+		}
+		state := &SubmoduleState{Path: rest}
+		switch line[0] {
+		case '-':
+			state.Uninitialized = true
+		case '+':
+			state.OutOfDate = true
+		case 'U':
+			state.Conflict = true
+		}
+		states[rest] = state
+		order = append(order, rest)
+	}
+
+	stdout, stderr, err = runner.Run(ctx, "-C", path, "status", "--porcelain=v2")
+	if err != nil {
+		return nil, fmt.Errorf("submodule dirty check: %w: %s", err, stderr)
+	}
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.SplitN(line, " ", 9)
+		if len(fields) < 9 || fields[0] != "1" || !strings.HasPrefix(fields[2], "S") || len(fields[2]) != 4 {
+			continue
+		}
+		state, ok := states[fields[8]]
+		if !ok {
+			continue
+		}
+		flags := fields[2]
+		if flags[1] == 'C' {
+			state.OutOfDate = true
+		}
+		if flags[2] == 'M' || flags[3] == 'U' {
+			state.Dirty = true
+		}
+	}
+
+	result := make([]SubmoduleState, 0, len(order))
+	for _, name := range order {
+		result = append(result, *states[name])
+	}
+	return result, nil
+}
+
+// UnpushedSubmodules returns the initialized submodules whose checked-out
+// HEAD has commits that no remote-tracking branch contains.
+func UnpushedSubmodules(ctx context.Context, runner git.Runner, path string, submodules []SubmoduleState) ([]string, error) {
+	var unpushed []string
+	for _, sub := range submodules {
+		if !sub.Initialized() {
+			continue
+		}
+		stdout, stderr, err := runner.Run(ctx, "-C", filepath.Join(path, filepath.FromSlash(sub.Path)), "rev-list", "-n", "1", "HEAD", "--not", "--remotes")
+		if err != nil {
+			return nil, fmt.Errorf("submodule %s unpushed check: %w: %s", sub.Path, err, stderr)
+		}
+		if strings.TrimSpace(stdout) != "" {
+			unpushed = append(unpushed, sub.Path)
+		}
+	}
+	return unpushed, nil
+}
@@ -0,0 +1,48 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubmodulesStates(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ".gitmodules"), []byte(""), 0o644); err != nil {
+		t.Fatalf("write .gitmodules: %v", err)
+	}
+	sha := "1adee16bd49489b2f60c00ee8c80f4359d40f509"
+	runner := fakeRunner{
+		responses: map[string]fakeResponse{
+			"-C " + root + " submodule status --recursive": {stdout: " " + sha + " lib/core (heads/main)\n+" + sha + " lib/ui (v1.0-3-gabc)\n-" + sha + " vendor/big\n"},
+			"-C " + root + " status --porcelain=v2": {stdout: "1 .M S.M. 160000 160000 160000 " + sha + " " + sha + " lib/core\n" +
+				"1 M. N... 100644 100644 100644 " + sha + " " + sha + " README.md\n"},
+		},
+	}
+	got, err := Submodules(context.Background(), runner, root)
+	if err != nil {
+		t.Fatalf("Submodules() error = %v", err)
+	}
+	want := []SubmoduleState{
+		{Path: "lib/core", Dirty: true},
+		{Path: "lib/ui", OutOfDate: true},
+		{Path: "vendor/big", Uninitialized: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Submodules() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Submodules()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if summary := got[0].Summary(); summary != "dirty" {
+		t.Fatalf("Summary() = %q, want dirty", summary)
+	}
+
+	none, err := Submodules(context.Background(), fakeRunner{}, t.TempDir())
+	if err != nil || none != nil {
+		t.Fatalf("Submodules() without .gitmodules = %+v, %v", none, err)
+	}
+}
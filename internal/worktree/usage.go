@@ -0,0 +1,111 @@
+package worktree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pi2pie/git-worktree-tasks/internal/git"
+)
+
+// UsageInfo is the disk usage of a worktree and its latest activity: the
+// newest of its HEAD commit time, HEAD reflog update and file mtimes.
+type UsageInfo struct {
+	Bytes        int64
+	LastActivity time.Time
+}
+
+// Usage measures the worktree at path. Entries named .git are skipped, so
+// the object store shared with the main checkout is never counted.
+func Usage(ctx context.Context, runner git.Runner, path string) (UsageInfo, error) {
+	var info UsageInfo
+	err := filepath.WalkDir(path, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Files deleted while walking are not an error.
+			if errors.Is(err, fs.ErrNotExist) && current != path {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.Name() == ".git" && current != path {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if stat.Mode().IsRegular() {
+			info.Bytes += stat.Size()
+		}
+		if stat.ModTime().After(info.LastActivity) {
+			info.LastActivity = stat.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return info, fmt.Errorf("disk usage %s: %w", path, err)
+	}
+
+	if commit, ok, err := headCommitTime(ctx, runner, path); err != nil {
+		return info, err
+	} else if ok && commit.After(info.LastActivity) {
+		info.LastActivity = commit
+	}
+	if reflog, ok, err := headReflogTime(ctx, runner, path); err != nil {
+		return info, err
+	} else if ok && reflog.After(info.LastActivity) {
+		info.LastActivity = reflog
+	}
+	return info, nil
+}
+
+func headCommitTime(ctx context.Context, runner git.Runner, path string) (time.Time, bool, error) {
+	hasHead, err := headExists(ctx, runner, path)
+	if err != nil || !hasHead {
+		return time.Time{}, false, err
+	}
+	stdout, stderr, err := runner.Run(ctx, "-C", path, "log", "-1", "--format=%ct", "HEAD")
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("head commit time: %w: %s", err, stderr)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(stdout), 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("head commit time parse %q: %w", strings.TrimSpace(stdout), err)
+	}
+	return time.Unix(seconds, 0), true, nil
+}
+
+// headReflogTime returns when the worktree's HEAD reflog was last written.
+func headReflogTime(ctx context.Context, runner git.Runner, path string) (time.Time, bool, error) {
+	stdout, stderr, err := runner.Run(ctx, "-C", path, "rev-parse", "--git-path", "logs/HEAD")
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("reflog path: %w: %s", err, stderr)
+	}
+	logPath := strings.TrimSpace(stdout)
+	if !filepath.IsAbs(logPath) {
+		logPath = filepath.Join(path, logPath)
+	}
+	stat, err := os.Stat(logPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, fmt.Errorf("stat reflog: %w", err)
+	}
+	return stat.ModTime(), true, nil
+}